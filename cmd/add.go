@@ -106,6 +106,9 @@ var addCmd = &cobra.Command{
 			MaxResponseSizeBytes: maxResponseSizeBytes,
 		}
 
+		// Populate generation parameters with flag values. Unset flags keep the copied or empty values.
+		applyGenerationFlags(cmd, &newProfile.Generation, "generation-")
+
 		cfg.Profiles[profileName] = newProfile
 
 		if err := cfg.Save(cfgFile); err != nil {
@@ -139,4 +142,7 @@ func init() {
 	addCmd.Flags().String("limits-on-output-exceeded", "stop", "Action on output size limit exceeded (stop or warn)")
 	addCmd.Flags().Int64("limits-max-prompt-size-bytes", 10485760, "Max prompt size in bytes (10MB)")
 	addCmd.Flags().Int64("limits-max-response-size-bytes", 20971520, "Max response size in bytes (20MB)")
+
+	// Flags for generation parameters
+	addGenerationFlags(addCmd, "generation-")
 }
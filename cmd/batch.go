@@ -0,0 +1,290 @@
+/*
+Copyright © 2025 magifd2
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/magifd2/llm-cli/internal/batch"
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/magifd2/llm-cli/internal/templates"
+	"github.com/spf13/cobra"
+)
+
+// batchCmd represents the 'batch' command.
+var batchCmd = &cobra.Command{
+	Use:   "batch <input.jsonl>",
+	Short: "Run the prompts of a JSONL file",
+	Long: `Runs the requests of a JSONL file, one JSON object per line with the fields id, system, user, profile and vars,
+with a pool of workers. The configuration is loaded and each profile's provider is created once for the whole batch.
+
+The results (id, output, error, usage, latency) are appended to the --out file as JSON lines as soon as they are available.
+Requests whose id is already in the output file are skipped, so an interrupted batch is resumed by running the same command again.
+Use '-' to read the requests from stdin.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outPath, _ := cmd.Flags().GetString("out")
+		if outPath == "" {
+			return fmt.Errorf("--out is required")
+		}
+		workers, _ := cmd.Flags().GetInt("workers")
+		if workers < 1 {
+			return fmt.Errorf("invalid number of workers %d: must be at least 1", workers)
+		}
+		cfg, err := config.Load(cfgFile)
+		if err != nil {
+			return fmt.Errorf("error loading config: %w", err)
+		}
+		runner := &batchRunner{cmd: cmd, cfg: cfg, profiles: make(map[string]*batchProfile)}
+		runner.defaultProfile, _ = cmd.Flags().GetString("profile")
+		runner.requestsPerMinute, _ = cmd.Flags().GetInt("requests-per-minute")
+
+		// Load the template the requests are rendered with, if any. Its recommended profile is used unless a profile is selected.
+		if templateName, _ := cmd.Flags().GetString("template"); templateName != "" {
+			store, err := newTemplateStore()
+			if err != nil {
+				return err
+			}
+			if runner.tmpl, err = store.Load(templateName); err != nil {
+				return err
+			}
+			if runner.defaultProfile == "" {
+				runner.defaultProfile = runner.tmpl.Profile
+			}
+		}
+		if runner.defaultProfile == "" {
+			runner.defaultProfile = cfg.CurrentProfile
+		}
+
+		requests, err := readBatchRequests(args[0])
+		if err != nil {
+			return err
+		}
+
+		// Skip the requests that already have a result.
+		results, err := batch.LoadResults(outPath)
+		if err != nil {
+			return err
+		}
+		retryFailed, _ := cmd.Flags().GetBool("retry-failed")
+		var pending []batch.Request
+		for _, req := range requests {
+			if result, ok := results[req.ID]; ok && (result.Error == "" || !retryFailed) {
+				continue
+			}
+			pending = append(pending, req)
+		}
+		skipped := len(requests) - len(pending)
+		if len(pending) == 0 {
+			fmt.Fprintf(os.Stderr, "Nothing to do: all %d requests have results in %s.\n", len(requests), outPath)
+			return nil
+		}
+		if skipped > 0 {
+			fmt.Fprintf(os.Stderr, "Resuming: skipping %d requests that have results in %s.\n", skipped, outPath)
+		}
+
+		out, err := batch.OpenOutput(outPath)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+
+		summary, err := (&batch.Runner{Workers: workers, Handle: runner.handle, Progress: os.Stderr}).Run(cmd.Context(), pending, batch.NewWriter(out))
+		fmt.Fprintf(os.Stderr, "Batch finished: %d succeeded, %d failed, %d skipped.\n", summary.Succeeded, summary.Failed, skipped)
+		if err != nil {
+			return err
+		}
+		if err := cmd.Context().Err(); err != nil {
+			return fmt.Errorf("batch interrupted: run the same command again to resume")
+		}
+		if summary.Failed > 0 {
+			return fmt.Errorf("%d of %d requests failed (use --retry-failed to run them again)", summary.Failed, len(pending))
+		}
+		return nil
+	},
+}
+
+// readBatchRequests reads the requests of a batch from a file, or from stdin if the path is "-".
+func readBatchRequests(path string) ([]batch.Request, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("error opening batch input: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+	return batch.ReadRequests(r)
+}
+
+// batchProfile is a profile prepared for a batch. Its provider, which applies the rate limit, is shared by all workers.
+type batchProfile struct {
+	profile  config.Profile
+	provider llm.Provider
+	err      error // Why the profile cannot be used, reported as the error of each of its requests.
+}
+
+// batchRunner runs the requests of a batch.
+type batchRunner struct {
+	cmd               *cobra.Command
+	cfg               *config.Config
+	tmpl              *templates.Template
+	defaultProfile    string
+	requestsPerMinute int // Overrides the rate limit of every profile if positive.
+
+	mu       sync.Mutex
+	profiles map[string]*batchProfile
+}
+
+// profile returns the named profile, creating its provider on first use.
+func (r *batchRunner) profile(name string) *batchProfile {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.profiles[name]; ok {
+		return p
+	}
+
+	p := &batchProfile{}
+	r.profiles[name] = p
+	profile, err := resolveProfile(r.cfg, name)
+	if err != nil {
+		p.err = err
+		return p
+	}
+	applyGenerationFlags(r.cmd, &profile.Generation, "")
+	provider, err := GetProvider(profile)
+	if err != nil {
+		p.err = fmt.Errorf("error initializing provider for profile '%s': %w", name, err)
+		return p
+	}
+	perMinute := profile.RequestsPerMinute
+	if r.requestsPerMinute > 0 {
+		perMinute = r.requestsPerMinute
+	}
+	p.profile = profile
+	p.provider = withLimitedRequestPolicies(provider, profile, batch.NewLimiter(perMinute))
+	return p
+}
+
+// handle runs a request and reports its outcome.
+func (r *batchRunner) handle(ctx context.Context, req batch.Request) batch.Result {
+	name := req.Profile
+	if name == "" {
+		name = r.defaultProfile
+	}
+	result := batch.Result{ID: req.ID, Profile: name}
+	p := r.profile(name)
+	if p.err != nil {
+		result.Error = p.err.Error()
+		return result
+	}
+	result.Model = p.profile.Model
+
+	resp, err := r.run(ctx, p, req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if resp.Model != "" {
+		result.Model = resp.Model
+	}
+	result.Output = resp.Text
+	result.Usage = resp.Usage
+	result.FinishReason = resp.FinishReason
+	result.LatencyMS = resp.Latency.Milliseconds()
+	return result
+}
+
+// run renders the prompts of a request, sends them within the profile's limits and returns the response.
+func (r *batchRunner) run(ctx context.Context, p *batchProfile, req batch.Request) (*llm.Response, error) {
+	system, user, err := renderBatchRequest(r.tmpl, req)
+	if err != nil {
+		return nil, err
+	}
+	limits := p.profile.Limits
+	source := fmt.Sprintf("request '%s'", req.ID)
+	if system, err = handlePromptData([]byte(system), source, limits, limits.OnInputExceeded); err != nil {
+		return nil, err
+	}
+	if user, err = handlePromptData([]byte(user), source, limits, limits.OnInputExceeded); err != nil {
+		return nil, err
+	}
+	if user == "" {
+		return nil, fmt.Errorf("no user prompt provided")
+	}
+
+	start := time.Now()
+	resp, err := p.provider.Chat(ctx, buildRequest(system, nil, user))
+	if err != nil {
+		return nil, fmt.Errorf("error getting response: %w", err)
+	}
+	resp.Latency = time.Since(start)
+	if resp.Text, err = limitOutput(resp.Text, limits, limits.OnOutputExceeded); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// renderBatchRequest returns the system and user prompts of a request. With a template, the request's user prompt
+// is the template's input variable and its system prompt replaces the template's. Without one, the prompts of
+// a request with variables are rendered as templates themselves.
+func renderBatchRequest(tmpl *templates.Template, req batch.Request) (system, user string, err error) {
+	if tmpl == nil {
+		if len(req.Vars) == 0 {
+			return req.System, req.User, nil
+		}
+		return (&templates.Template{Name: req.ID, System: req.System, User: req.User}).Render(req.Vars)
+	}
+	vars := make(map[string]string, len(req.Vars)+1)
+	for name, value := range req.Vars {
+		vars[name] = value
+	}
+	vars[templates.InputVar] = req.User
+	if system, user, err = tmpl.Render(vars); err != nil {
+		return "", "", err
+	}
+	if req.System != "" {
+		system = req.System
+	}
+	return system, user, nil
+}
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+
+	batchCmd.Flags().StringP("out", "o", "", "Path of the JSONL file the results are appended to (required)")
+	batchCmd.Flags().String("profile", "", "Profile for the requests that do not name one (overrides current active profile)")
+	batchCmd.Flags().String("template", "", "Render every request from a template of the library, with the request's vars and its user prompt as input")
+	batchCmd.Flags().Int("workers", 4, "Number of requests run at the same time")
+	batchCmd.Flags().Int("requests-per-minute", 0, "Rate limit per profile, overriding the profiles' requests_per_minute (default: the profile's setting, or none)")
+	batchCmd.Flags().Bool("retry-failed", false, "Also run the requests whose previous result was an error")
+
+	// Flags for generation parameters
+	addGenerationFlags(batchCmd, "")
+}
@@ -0,0 +1,142 @@
+/*
+Copyright © 2025 magifd2
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/magifd2/llm-cli/internal/batch"
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/magifd2/llm-cli/internal/templates"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchCommand(t *testing.T) {
+	tempDir := setupTestEnvironment(t)
+	addTestProfile(t, "mock_profile", config.Profile{Provider: "mock", Model: "mock-model", RequestsPerMinute: 6000})
+	t.Cleanup(func() {
+		for _, name := range []string{"out", "profile", "template"} {
+			_ = batchCmd.Flags().Set(name, "")
+		}
+		_ = batchCmd.Flags().Set("retry-failed", "false")
+	})
+
+	input := filepath.Join(tempDir, "requests.jsonl")
+	out := filepath.Join(tempDir, "results.jsonl")
+	require.NoError(t, os.WriteFile(input, []byte(strings.Join([]string{
+		`{"id": "plain", "system": "be brief", "user": "hello"}`,
+		`{"id": "vars", "user": "Translate {{.text}}", "vars": {"text": "bonjour"}}`,
+		`{"id": "missing", "user": "hi", "profile": "no_such_profile"}`,
+	}, "\n")), 0600))
+
+	_, _, err := executeCommand(rootCmd, "batch", input, "--out", out, "--profile", "mock_profile", "--workers", "2")
+	assert.ErrorContains(t, err, "1 of 3 requests failed")
+	results, err := batch.LoadResults(out)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Contains(t, results["plain"].Output, "System Prompt: be brief")
+	assert.Contains(t, results["plain"].Output, "User Prompt: hello")
+	assert.Equal(t, "mock_profile", results["plain"].Profile)
+	assert.Equal(t, "mock-model", results["plain"].Model)
+	assert.NotNil(t, results["plain"].Usage)
+	assert.Contains(t, results["vars"].Output, "User Prompt: Translate bonjour")
+	assert.Equal(t, "profile 'no_such_profile' not found", results["missing"].Error)
+
+	// Running again skips the requests that have results, unless failed ones are retried.
+	_, _, err = executeCommand(rootCmd, "batch", input, "--out", out, "--profile", "mock_profile")
+	require.NoError(t, err)
+	addTestProfile(t, "no_such_profile", config.Profile{Provider: "mock", Model: "mock-model"})
+	_, _, err = executeCommand(rootCmd, "batch", input, "--out", out, "--profile", "mock_profile", "--retry-failed")
+	require.NoError(t, err)
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, 4, strings.Count(string(data), "\n"))
+	results, err = batch.LoadResults(out)
+	require.NoError(t, err)
+	assert.Empty(t, results["missing"].Error)
+}
+
+func TestBatchCommand_InvalidWorkers(t *testing.T) {
+	tempDir := setupTestEnvironment(t)
+	t.Cleanup(func() {
+		_ = batchCmd.Flags().Set("out", "")
+		_ = batchCmd.Flags().Set("workers", "4")
+	})
+
+	input := filepath.Join(tempDir, "requests.jsonl")
+	out := filepath.Join(tempDir, "results.jsonl")
+	require.NoError(t, os.WriteFile(input, []byte(`{"id": "a", "user": "hi"}`), 0600))
+
+	_, _, err := executeCommand(rootCmd, "batch", input, "--out", out, "--workers", "0")
+	assert.ErrorContains(t, err, "invalid number of workers 0")
+	assert.NoFileExists(t, out, "the flags are checked before the output file is created")
+}
+
+// unavailableProvider fails every call with a transient error.
+type unavailableProvider struct {
+	calls int
+}
+
+func (p *unavailableProvider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	p.calls++
+	return nil, &llm.StatusError{StatusCode: 503, Message: "service unavailable"}
+}
+
+func (p *unavailableProvider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	return p.Chat(ctx, req)
+}
+
+func (p *unavailableProvider) ValidateConfig() error {
+	return nil
+}
+
+func TestWithLimitedRequestPolicies_LimitsRetries(t *testing.T) {
+	provider := &unavailableProvider{}
+	profile := config.Profile{Retry: config.Retry{MaxAttempts: 3, BaseDelay: config.Duration(time.Millisecond), NoJitter: true}}
+	limited := withLimitedRequestPolicies(provider, profile, batch.NewLimiter(1))
+
+	// The first attempt starts at once; the retry has to wait a minute for the limiter.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := limited.Chat(ctx, llm.NewRequest("", "hi"))
+	assert.Error(t, err)
+	assert.Equal(t, 1, provider.calls)
+}
+
+func TestRenderBatchRequest_Template(t *testing.T) {
+	tmpl := &templates.Template{System: "You review {{.lang}}.", User: "Review: {{.input}}", Vars: map[string]string{"lang": "go"}}
+	system, user, err := renderBatchRequest(tmpl, batch.Request{ID: "a", User: "x := 1", Vars: map[string]string{"lang": "rust"}})
+	require.NoError(t, err)
+	assert.Equal(t, "You review rust.", system)
+	assert.Equal(t, "Review: x := 1", user)
+
+	system, _, err = renderBatchRequest(tmpl, batch.Request{ID: "b", System: "custom", User: "y"})
+	require.NoError(t, err)
+	assert.Equal(t, "custom", system, "the request's system prompt replaces the template's")
+}
@@ -0,0 +1,278 @@
+/*
+Copyright © 2025 magifd2
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/spf13/cobra"
+)
+
+// chatHelp lists the slash-commands available in the chat REPL.
+const chatHelp = `Available commands:
+  /system [text]   Set the system prompt (clears it if no text is given)
+  /reset           Clear the conversation history
+  /save <file>     Save the conversation to a JSON file
+  /profile <name>  Switch to another profile, keeping the conversation
+  /help            Show this help
+  /exit, /quit     Leave the chat`
+
+// chatCmd represents the 'chat' command.
+var chatCmd = &cobra.Command{
+	Use:   "chat",
+	Short: "Start an interactive chat session with the LLM",
+	Long: `Starts an interactive chat session that keeps the conversation history between turns.
+Replies are streamed as they are generated. Lines starting with '/' are treated as commands.
+
+` + chatHelp,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{handlesInterrupt: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load(cfgFile)
+		if err != nil {
+			return fmt.Errorf("error loading config: %w", err)
+		}
+
+		profileName, _ := cmd.Flags().GetString("profile")
+		if profileName == "" {
+			profileName = cfg.CurrentProfile
+		}
+
+		chat := &chatSession{cfg: cfg}
+		if err := chat.useProfile(profileName); err != nil {
+			return err
+		}
+
+		systemPrompt, _ := cmd.Flags().GetString("system-prompt")
+		systemPromptFile, _ := cmd.Flags().GetString("system-prompt-file")
+		chat.systemPrompt, err = loadSystemPrompt(systemPrompt, systemPromptFile, chat.profile.Limits, chat.profile.Limits.OnInputExceeded)
+		if err != nil {
+			return err
+		}
+
+		return chat.run(cmd)
+	},
+}
+
+// chatSession holds the state of an interactive chat: the active profile and provider,
+// the system prompt and the user/assistant turns exchanged so far.
+type chatSession struct {
+	cfg          *config.Config
+	profileName  string
+	profile      config.Profile
+	provider     llm.Provider
+	systemPrompt string
+	history      []llm.Message
+}
+
+// useProfile switches the session to the named profile and creates its provider.
+func (s *chatSession) useProfile(name string) error {
+	profile, err := resolveProfile(s.cfg, name)
+	if err != nil {
+		return err
+	}
+	provider, err := GetProvider(profile)
+	if err != nil {
+		return fmt.Errorf("error initializing provider for profile '%s': %w", name, err)
+	}
+	s.profileName = name
+	s.profile = profile
+	s.provider = withRequestPolicies(provider, profile)
+	return nil
+}
+
+// run reads lines from the command's input until EOF, an exit command or the cancellation of the command
+// context (e.g., by SIGTERM). Ctrl-C cancels the reply in progress, or the line being typed, and returns
+// to the prompt.
+func (s *chatSession) run(cmd *cobra.Command) error {
+	fmt.Fprintf(os.Stderr, "Chatting with profile '%s' (provider: %s, model: %s). Type /help for commands.\n", s.profileName, s.profile.Provider, s.profile.Model)
+
+	// Lines are read in the background, so that a cancellation is noticed while waiting for input.
+	ctx := cmd.Context()
+	scanner := bufio.NewScanner(cmd.InOrStdin())
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Interrupts are caught for the whole session, so that Ctrl-C at the prompt does not end the process.
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	for {
+		fmt.Print("> ")
+		var line string
+		var ok bool
+		select {
+		case line, ok = <-lines:
+		case <-interrupts:
+			fmt.Println()
+			continue
+		case <-ctx.Done():
+			fmt.Println()
+			return nil
+		}
+		if !ok {
+			fmt.Println()
+			break
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "/") {
+			exit, err := s.handleCommand(line)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+			if exit {
+				return nil
+			}
+			continue
+		}
+
+		if err := s.send(cmd, line); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		// The interrupt that cancelled a reply was delivered to the session as well.
+		select {
+		case <-interrupts:
+		default:
+		}
+	}
+	return scanner.Err()
+}
+
+// send sends a user message and records the turn in the history if the reply succeeded.
+// Ctrl-C cancels only this turn, which is then left out of the history.
+func (s *chatSession) send(cmd *cobra.Command, line string) error {
+	userPrompt, err := handlePromptData([]byte(line), "input", s.profile.Limits, s.profile.Limits.OnInputExceeded)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+	response, err := handleStreamResponse(ctx, cmd, s.provider, buildRequest(s.systemPrompt, s.history, userPrompt), s.profile, s.profile.Limits.OnOutputExceeded, outputText)
+	if err != nil {
+		if ctx.Err() != nil && cmd.Context().Err() == nil {
+			fmt.Fprintln(os.Stderr, "\nReply cancelled.")
+			return nil
+		}
+		return err
+	}
+
+	s.history = append(s.history,
+		llm.Message{Role: llm.RoleUser, Content: userPrompt},
+		llm.Message{Role: llm.RoleAssistant, Content: response.Text},
+	)
+	return nil
+}
+
+// handleCommand executes a slash-command. It reports whether the chat should end.
+func (s *chatSession) handleCommand(line string) (bool, error) {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case "/exit", "/quit":
+		return true, nil
+	case "/help":
+		fmt.Println(chatHelp)
+	case "/system":
+		s.systemPrompt = arg
+		if arg == "" {
+			fmt.Fprintln(os.Stderr, "System prompt cleared.")
+		} else {
+			fmt.Fprintln(os.Stderr, "System prompt set.")
+		}
+	case "/reset":
+		s.history = nil
+		fmt.Fprintln(os.Stderr, "Conversation history cleared.")
+	case "/save":
+		if arg == "" {
+			return false, fmt.Errorf("usage: /save <file>")
+		}
+		if err := s.save(arg); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(os.Stderr, "Conversation saved to %s.\n", arg)
+	case "/profile":
+		if arg == "" {
+			return false, fmt.Errorf("usage: /profile <name>")
+		}
+		if err := s.useProfile(arg); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(os.Stderr, "Switched to profile '%s' (provider: %s, model: %s).\n", s.profileName, s.profile.Provider, s.profile.Model)
+	default:
+		return false, fmt.Errorf("unknown command '%s'. Type /help for a list of commands", name)
+	}
+	return false, nil
+}
+
+// save writes the system prompt and the conversation history to a JSON file.
+func (s *chatSession) save(path string) error {
+	var messages []llm.Message
+	if s.systemPrompt != "" {
+		messages = append(messages, llm.Message{Role: llm.RoleSystem, Content: s.systemPrompt})
+	}
+	messages = append(messages, s.history...)
+
+	data, err := json.MarshalIndent(llm.Request{Messages: messages}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding conversation: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("error writing conversation file: %w", err)
+	}
+	return nil
+}
+
+// init function registers the chatCmd with the rootCmd and defines its flags.
+func init() {
+	rootCmd.AddCommand(chatCmd)
+
+	chatCmd.Flags().String("profile", "", "Use a specific profile for this chat (overrides current active profile)")
+	chatCmd.Flags().StringP("system-prompt", "P", "", "System prompt to start the chat with")
+	chatCmd.Flags().StringP("system-prompt-file", "F", "", "Path to a file containing the system prompt.")
+}
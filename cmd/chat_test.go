@@ -0,0 +1,168 @@
+/*
+Copyright © 2025 magifd2
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// addTestProfile adds a profile to the test configuration.
+func addTestProfile(t *testing.T, name string, profile config.Profile) {
+	cfg, err := config.Load(cfgFile)
+	require.NoError(t, err)
+	cfg.Profiles[name] = profile
+	require.NoError(t, cfg.Save(cfgFile))
+}
+
+func TestChatCommand(t *testing.T) {
+	tempDir := setupTestEnvironment(t)
+	addTestProfile(t, "mock_profile", config.Profile{Provider: "mock", Model: "mock"})
+
+	transcript := filepath.Join(tempDir, "chat.json")
+	input := strings.Join([]string{
+		"/system be brief",
+		"first question",
+		"/unknown",
+		"second question",
+		"/save " + transcript,
+		"/exit",
+		"never sent",
+	}, "\n")
+
+	rootCmd.SetIn(strings.NewReader(input))
+	defer rootCmd.SetIn(nil)
+	_, _, err := executeCommand(rootCmd, "chat", "--profile", "mock_profile")
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(transcript)
+	require.NoError(t, err)
+	var saved llm.Request
+	require.NoError(t, json.Unmarshal(data, &saved))
+
+	require.Len(t, saved.Messages, 5)
+	assert.Equal(t, llm.Message{Role: llm.RoleSystem, Content: "be brief"}, saved.Messages[0])
+	assert.Equal(t, llm.Message{Role: llm.RoleUser, Content: "first question"}, saved.Messages[1])
+	assert.Equal(t, llm.RoleAssistant, saved.Messages[2].Role)
+	assert.Equal(t, llm.Message{Role: llm.RoleUser, Content: "second question"}, saved.Messages[3])
+	// The mock provider echoes the number of turns it received, which shows the history was resent.
+	assert.Contains(t, saved.Messages[4].Content, "Turns: 3")
+}
+
+func TestChatSession_HandleCommand(t *testing.T) {
+	_ = setupTestEnvironment(t)
+	cfg, err := config.Load(cfgFile)
+	require.NoError(t, err)
+
+	session := &chatSession{cfg: cfg}
+	require.NoError(t, session.useProfile("default"))
+	session.history = []llm.Message{{Role: llm.RoleUser, Content: "q"}, {Role: llm.RoleAssistant, Content: "a"}}
+
+	exit, err := session.handleCommand("/reset")
+	assert.NoError(t, err)
+	assert.False(t, exit)
+	assert.Empty(t, session.history)
+
+	_, err = session.handleCommand("/profile existing_profile")
+	assert.NoError(t, err)
+	assert.Equal(t, "existing_profile", session.profileName)
+
+	_, err = session.handleCommand("/profile missing")
+	assert.Error(t, err)
+	assert.Equal(t, "existing_profile", session.profileName)
+
+	exit, err = session.handleCommand("/quit")
+	assert.NoError(t, err)
+	assert.True(t, exit)
+}
+
+// hangingProvider blocks its first streamed reply until the request is cancelled and answers the
+// following ones immediately.
+type hangingProvider struct {
+	started chan struct{}
+	calls   int
+}
+
+func (p *hangingProvider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	return nil, nil
+}
+
+func (p *hangingProvider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	p.calls++
+	if p.calls == 1 {
+		close(p.started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	responseChan <- "answer"
+	return &llm.Response{}, nil
+}
+
+func (p *hangingProvider) ValidateConfig() error {
+	return nil
+}
+
+func TestChatSession_InterruptCancelsTurn(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("interrupts cannot be sent to the own process on Windows")
+	}
+
+	input, writer := io.Pipe()
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	cmd.SetIn(input)
+	cmd.SetOut(io.Discard)
+
+	provider := &hangingProvider{started: make(chan struct{})}
+	session := &chatSession{profileName: "test", provider: provider}
+	done := make(chan error, 1)
+	go func() { done <- session.run(cmd) }()
+
+	_, err := io.WriteString(writer, "slow question\n")
+	require.NoError(t, err)
+	<-provider.started
+	self, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, self.Signal(os.Interrupt))
+
+	// The chat is still reading input: the cancelled turn is dropped and the next one is answered.
+	_, err = io.WriteString(writer, "second question\n/exit\n")
+	require.NoError(t, err)
+	require.NoError(t, <-done)
+	writer.Close()
+
+	require.Len(t, session.history, 2)
+	assert.Equal(t, llm.Message{Role: llm.RoleUser, Content: "second question"}, session.history[0])
+	assert.Equal(t, llm.Message{Role: llm.RoleAssistant, Content: "answer"}, session.history[1])
+}
@@ -25,6 +25,7 @@ import (
 	"fmt"
 	os "os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/magifd2/llm-cli/internal/config"
@@ -289,6 +290,9 @@ func showProfile(profile config.Profile, name string) {
 			fmt.Printf("  CredentialsFile: %s (Resolved: %s)\n", profile.CredentialsFile, resolvedPath)
 		}
 	}
+	if profile.APIVersion != "" {
+		fmt.Printf("  APIVersion: %s\n", profile.APIVersion)
+	}
 	// Display Limits if enabled or if any limit is non-zero/non-empty
 	if profile.Limits.Enabled ||
 		profile.Limits.OnInputExceeded != "" ||
@@ -302,6 +306,110 @@ func showProfile(profile config.Profile, name string) {
 		fmt.Printf("    MaxPromptSizeBytes: %d\n", profile.Limits.MaxPromptSizeBytes)
 		fmt.Printf("    MaxResponseSizeBytes: %d\n", profile.Limits.MaxResponseSizeBytes)
 	}
+	if profile.ConnectTimeout != 0 {
+		fmt.Printf("  ConnectTimeout: %s\n", time.Duration(profile.ConnectTimeout))
+	}
+	if profile.RequestTimeout != 0 {
+		fmt.Printf("  RequestTimeout: %s\n", time.Duration(profile.RequestTimeout))
+	}
+	if profile.RequestsPerMinute != 0 {
+		fmt.Printf("  RequestsPerMinute: %d\n", profile.RequestsPerMinute)
+	}
+	// Display the transport settings that are set.
+	transport := profile.Transport
+	if transport.CAFile != "" || transport.ClientCert != "" || transport.ClientKey != "" || transport.InsecureSkipVerify || transport.Proxy != "" || len(transport.Headers) > 0 {
+		fmt.Printf("  Transport:\n")
+		if transport.CAFile != "" {
+			fmt.Printf("    CAFile: %s\n", transport.CAFile)
+		}
+		if transport.ClientCert != "" {
+			fmt.Printf("    ClientCert: %s\n", transport.ClientCert)
+		}
+		if transport.ClientKey != "" {
+			fmt.Printf("    ClientKey: %s\n", transport.ClientKey)
+		}
+		if transport.InsecureSkipVerify {
+			fmt.Printf("    InsecureSkipVerify: %t\n", transport.InsecureSkipVerify)
+		}
+		if transport.Proxy != "" {
+			fmt.Printf("    Proxy: %s\n", transport.Proxy)
+		}
+		if len(transport.Headers) > 0 {
+			fmt.Printf("    Headers:\n")
+			names := make([]string, 0, len(transport.Headers))
+			for name := range transport.Headers {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Printf("      %s: %s\n", name, transport.Headers[name])
+			}
+		}
+	}
+	// Display the retry policy if it differs from the defaults.
+	if profile.Retry != (config.Retry{}) {
+		fmt.Printf("  Retry:\n")
+		if profile.Retry.MaxAttempts != 0 {
+			fmt.Printf("    MaxAttempts: %d\n", profile.Retry.MaxAttempts)
+		}
+		if profile.Retry.BaseDelay != 0 {
+			fmt.Printf("    BaseDelay: %s\n", time.Duration(profile.Retry.BaseDelay))
+		}
+		if profile.Retry.MaxDelay != 0 {
+			fmt.Printf("    MaxDelay: %s\n", time.Duration(profile.Retry.MaxDelay))
+		}
+		if profile.Retry.NoJitter {
+			fmt.Printf("    NoJitter: %t\n", profile.Retry.NoJitter)
+		}
+	}
+	// Display the mock provider settings that are set.
+	if profile.Mock != (config.Mock{}) {
+		fmt.Printf("  Mock:\n")
+		if profile.Mock.ResponsesFile != "" {
+			fmt.Printf("    ResponsesFile: %s\n", profile.Mock.ResponsesFile)
+		}
+		if profile.Mock.Latency != 0 {
+			fmt.Printf("    Latency: %s\n", time.Duration(profile.Mock.Latency))
+		}
+		if profile.Mock.ChunkSize != 0 {
+			fmt.Printf("    ChunkSize: %d\n", profile.Mock.ChunkSize)
+		}
+		if profile.Mock.ChunkDelay != 0 {
+			fmt.Printf("    ChunkDelay: %s\n", time.Duration(profile.Mock.ChunkDelay))
+		}
+	}
+	// Display generation parameters that are explicitly set.
+	gen := profile.Generation
+	if gen.Temperature != nil || gen.TopP != nil || gen.TopK != nil || gen.MaxTokens != nil || len(gen.Stop) > 0 || gen.Seed != nil || gen.CandidateCount != nil {
+		fmt.Printf("  Generation:\n")
+		if gen.Temperature != nil {
+			fmt.Printf("    Temperature: %g\n", *gen.Temperature)
+		}
+		if gen.TopP != nil {
+			fmt.Printf("    TopP: %g\n", *gen.TopP)
+		}
+		if gen.TopK != nil {
+			fmt.Printf("    TopK: %d\n", *gen.TopK)
+		}
+		if gen.MaxTokens != nil {
+			fmt.Printf("    MaxTokens: %d\n", *gen.MaxTokens)
+		}
+		if len(gen.Stop) > 0 {
+			fmt.Printf("    Stop: %q\n", gen.Stop)
+		}
+		if gen.Seed != nil {
+			fmt.Printf("    Seed: %d\n", *gen.Seed)
+		}
+		if gen.CandidateCount != nil {
+			fmt.Printf("    CandidateCount: %d\n", *gen.CandidateCount)
+		}
+	}
+	if len(profile.SafetySettings) > 0 {
+		fmt.Printf("  SafetySettings:\n")
+		for _, s := range profile.SafetySettings {
+			fmt.Printf("    %s: %s\n", s.Category, s.Threshold)
+		}
+	}
 }
 
 // init function registers the profileCmd with the rootCmd and adds the showCmd and checkCmd as subcommands.
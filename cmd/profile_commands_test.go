@@ -25,6 +25,7 @@ import (
 	"bytes"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/magifd2/llm-cli/internal/config"
 	"github.com/spf13/cobra"
@@ -120,6 +121,103 @@ func TestSetCommand(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestSetCommand_Generation(t *testing.T) {
+	_ = setupTestEnvironment(t)
+
+	_, _, err := executeCommand(rootCmd, "profile", "set", "generation-temperature", "0")
+	require.NoError(t, err)
+	_, _, err = executeCommand(rootCmd, "profile", "set", "generation-max-tokens", "4096")
+	require.NoError(t, err)
+	_, _, err = executeCommand(rootCmd, "profile", "set", "generation-stop", "END,###")
+	require.NoError(t, err)
+
+	cfg, err := config.Load(cfgFile)
+	require.NoError(t, err)
+	gen := cfg.Profiles["default"].Generation
+	require.NotNil(t, gen.Temperature)
+	assert.Equal(t, 0.0, *gen.Temperature) // An explicit zero must be kept.
+	require.NotNil(t, gen.MaxTokens)
+	assert.Equal(t, 4096, *gen.MaxTokens)
+	assert.Equal(t, []string{"END", "###"}, gen.Stop)
+	assert.Nil(t, gen.TopP)
+
+	// An empty value unsets the parameter.
+	_, _, err = executeCommand(rootCmd, "profile", "set", "generation-temperature", "")
+	require.NoError(t, err)
+	cfg, err = config.Load(cfgFile)
+	require.NoError(t, err)
+	assert.Nil(t, cfg.Profiles["default"].Generation.Temperature)
+
+	_, _, err = executeCommand(rootCmd, "profile", "set", "generation-top-k", "many")
+	assert.Error(t, err)
+}
+
+func TestSetCommand_SafetySettings(t *testing.T) {
+	_ = setupTestEnvironment(t)
+
+	_, _, err := executeCommand(rootCmd, "profile", "set", "safety-settings", "HARM_CATEGORY_HATE_SPEECH=BLOCK_ONLY_HIGH, HARM_CATEGORY_DANGEROUS_CONTENT=BLOCK_NONE")
+	require.NoError(t, err)
+	_, _, err = executeCommand(rootCmd, "profile", "set", "generation-candidate-count", "2")
+	require.NoError(t, err)
+
+	cfg, err := config.Load(cfgFile)
+	require.NoError(t, err)
+	profile := cfg.Profiles["default"]
+	assert.Equal(t, []config.SafetySetting{
+		{Category: "HARM_CATEGORY_HATE_SPEECH", Threshold: "BLOCK_ONLY_HIGH"},
+		{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Threshold: "BLOCK_NONE"},
+	}, profile.SafetySettings)
+	require.NotNil(t, profile.Generation.CandidateCount)
+	assert.Equal(t, 2, *profile.Generation.CandidateCount)
+
+	_, _, err = executeCommand(rootCmd, "profile", "set", "safety-settings", "BLOCK_NONE")
+	assert.Error(t, err)
+
+	// An empty value unsets the safety settings.
+	_, _, err = executeCommand(rootCmd, "profile", "set", "safety-settings", "")
+	require.NoError(t, err)
+	cfg, err = config.Load(cfgFile)
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Profiles["default"].SafetySettings)
+}
+
+func TestSetCommand_Transport(t *testing.T) {
+	_ = setupTestEnvironment(t)
+
+	for key, value := range map[string]string{
+		"transport-ca-file":              "~/certs/corp-ca.pem",
+		"transport-client-cert":          "~/certs/client.pem",
+		"transport-client-key":           "~/certs/client-key.pem",
+		"transport-insecure-skip-verify": "true",
+		"transport-proxy":                "http://proxy.example.com:8080",
+		"transport-headers":              "OpenAI-Organization=org-1, X-Gateway-Token=secret",
+	} {
+		_, _, err := executeCommand(rootCmd, "profile", "set", key, value)
+		require.NoError(t, err, key)
+	}
+
+	cfg, err := config.Load(cfgFile)
+	require.NoError(t, err)
+	assert.Equal(t, config.Transport{
+		CAFile:             "~/certs/corp-ca.pem",
+		ClientCert:         "~/certs/client.pem",
+		ClientKey:          "~/certs/client-key.pem",
+		InsecureSkipVerify: true,
+		Proxy:              "http://proxy.example.com:8080",
+		Headers:            map[string]string{"OpenAI-Organization": "org-1", "X-Gateway-Token": "secret"},
+	}, cfg.Profiles["default"].Transport)
+
+	_, _, err = executeCommand(rootCmd, "profile", "set", "transport-headers", "=value")
+	assert.Error(t, err)
+
+	// An empty value removes the headers.
+	_, _, err = executeCommand(rootCmd, "profile", "set", "transport-headers", "")
+	require.NoError(t, err)
+	cfg, err = config.Load(cfgFile)
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Profiles["default"].Transport.Headers)
+}
+
 func TestRemoveCommand(t *testing.T) {
 	_ = setupTestEnvironment(t)
 
@@ -167,3 +265,33 @@ func TestMain(m *testing.M) {
 
 	os.Exit(code)
 }
+
+func TestSetCommand_RetryAndTimeouts(t *testing.T) {
+	_ = setupTestEnvironment(t)
+
+	for key, value := range map[string]string{"retry-max-attempts": "5", "retry-base-delay": "500ms", "retry-max-delay": "10s", "retry-no-jitter": "true"} {
+		_, _, err := executeCommand(rootCmd, "profile", "set", key, value)
+		require.NoError(t, err, key)
+	}
+	cfg, err := config.Load(cfgFile)
+	require.NoError(t, err)
+	assert.Equal(t, config.Retry{MaxAttempts: 5, BaseDelay: config.Duration(500 * time.Millisecond), MaxDelay: config.Duration(10 * time.Second), NoJitter: true}, cfg.Profiles["default"].Retry)
+
+	_, _, err = executeCommand(rootCmd, "profile", "set", "retry-max-attempts", "0")
+	assert.Error(t, err)
+	_, _, err = executeCommand(rootCmd, "profile", "set", "retry-base-delay", "1")
+	assert.Error(t, err, "durations need a unit")
+
+	_, _, err = executeCommand(rootCmd, "profile", "set", "request-timeout", "2m")
+	require.NoError(t, err)
+	cfg, err = config.Load(cfgFile)
+	require.NoError(t, err)
+	assert.Equal(t, config.Duration(2*time.Minute), cfg.Profiles["default"].RequestTimeout)
+
+	// Empty values restore the defaults.
+	_, _, err = executeCommand(rootCmd, "profile", "set", "retry-base-delay", "")
+	require.NoError(t, err)
+	cfg, err = config.Load(cfgFile)
+	require.NoError(t, err)
+	assert.Zero(t, cfg.Profiles["default"].Retry.BaseDelay)
+}
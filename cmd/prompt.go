@@ -24,18 +24,30 @@ package cmd
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 	"unicode/utf8"
 
 	"github.com/briandowns/spinner"
+	"github.com/magifd2/llm-cli/internal/attach"
 	"github.com/magifd2/llm-cli/internal/config"
 	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/magifd2/llm-cli/internal/llm/fallback"
 	"github.com/magifd2/llm-cli/internal/llm/mock"
+	"github.com/magifd2/llm-cli/internal/llm/schema"
+	"github.com/magifd2/llm-cli/internal/session"
+	"github.com/magifd2/llm-cli/internal/templates"
+	"github.com/magifd2/llm-cli/internal/tools"
 	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 )
@@ -52,20 +64,27 @@ var promptCmd = &cobra.Command{
 			return fmt.Errorf("error loading config: %w", err)
 		}
 
-		profileName, _ := cmd.Flags().GetString("profile")
-		var activeProfile config.Profile
-		var ok bool
-
-		if profileName != "" {
-			activeProfile, ok = cfg.Profiles[profileName]
-			if !ok {
-				return fmt.Errorf("profile '%s' not found", profileName)
+		// Load the prompt template, if any. Its recommended profile is used unless a profile is selected.
+		var tmpl *templates.Template
+		if templateName, _ := cmd.Flags().GetString("template"); templateName != "" {
+			store, err := newTemplateStore()
+			if err != nil {
+				return err
 			}
-		} else {
-			activeProfile, ok = cfg.Profiles[cfg.CurrentProfile]
-			if !ok {
-				return fmt.Errorf("active profile '%s' not found", cfg.CurrentProfile)
+			if tmpl, err = store.Load(templateName); err != nil {
+				return err
 			}
+		} else if cmd.Flags().Changed("var") || cmd.Flags().Changed("var-file") {
+			return fmt.Errorf("--var and --var-file require --template")
+		}
+
+		profileName, _ := cmd.Flags().GetString("profile")
+		if profileName == "" && tmpl != nil {
+			profileName = tmpl.Profile
+		}
+		activeProfile, err := resolveProfile(cfg, profileName)
+		if err != nil {
+			return err
 		}
 
 		// 2. Determine limit settings from profile and flags.
@@ -79,6 +98,11 @@ var promptCmd = &cobra.Command{
 			onOutputExceeded, _ = cmd.Flags().GetString("on-output-exceeded")
 		}
 
+		format, _ := cmd.Flags().GetString("output")
+		if err := validateOutputFormat(format); err != nil {
+			return err
+		}
+
 		// 3. Load and validate prompts.
 		userPrompt, _ := cmd.Flags().GetString("user-prompt")
 		userPromptFile, _ := cmd.Flags().GetString("user-prompt-file")
@@ -95,30 +119,380 @@ var promptCmd = &cobra.Command{
 			return err
 		}
 
+		if tmpl != nil {
+			systemPromptStr, userPromptStr, err = renderTemplate(cmd, tmpl, systemPromptStr, userPromptStr, limits, onInputExceeded)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Add the attached files to the user prompt.
+		if attachPaths, _ := cmd.Flags().GetStringArray("attach"); len(attachPaths) > 0 {
+			excludes, _ := cmd.Flags().GetStringArray("exclude")
+			if userPromptStr, err = attachFiles(userPromptStr, attachPaths, excludes, limits, onInputExceeded); err != nil {
+				return err
+			}
+		}
+
 		if userPromptStr == "" {
 			return fmt.Errorf("no user prompt provided")
 		}
 
-		// 4. Initialize provider using the registry.
-		provider, err := GetProvider(activeProfile)
+		// Load the images and documents to send with the user prompt.
+		imagePaths, _ := cmd.Flags().GetStringArray("image")
+		documentPaths, _ := cmd.Flags().GetStringArray("document")
+		parts, err := loadParts(imagePaths, documentPaths, int64(len(systemPromptStr)+len(userPromptStr)), limits, onInputExceeded)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: %v. Using mock provider.\n", err)
-			provider, _ = mock.NewProvider(activeProfile)
+			return err
+		}
+
+		// Load the JSON schema the reply must conform to, if any.
+		var replySchema *schema.Schema
+		if schemaFile, _ := cmd.Flags().GetString("json-schema"); schemaFile != "" {
+			data, err := os.ReadFile(schemaFile)
+			if err != nil {
+				return fmt.Errorf("error reading JSON schema file: %w", err)
+			}
+			if replySchema, err = schema.Compile(data); err != nil {
+				return err
+			}
 		}
 
-		// 5. Execute and get response.
+		// Load the tools the model may call, if any.
+		var toolSet *tools.Set
+		if toolsFile, _ := cmd.Flags().GetString("tools"); toolsFile != "" {
+			if toolSet, err = tools.Load(toolsFile); err != nil {
+				return err
+			}
+		}
+
+		// Override generation parameters, timeouts and the retry policy with flag values.
+		applyGenerationFlags(cmd, &activeProfile.Generation, "")
+		applyTimeoutFlags(cmd, &activeProfile)
+		applyRetryFlags(cmd, &activeProfile.Retry)
+
+		if profileName == "" {
+			profileName = cfg.CurrentProfile
+		}
+
+		// 4. Initialize provider using the registry. With fallback profiles, the providers are tried in order.
+		var provider llm.Provider
+		var chain *fallback.Provider
+		chainProfiles := map[string]config.Profile{profileName: activeProfile}
+		if fallbackNames, _ := cmd.Flags().GetStringSlice("fallback"); len(fallbackNames) > 0 {
+			candidates := []fallback.Candidate{newFallbackCandidate(profileName, activeProfile)}
+			for _, name := range fallbackNames {
+				profile, err := resolveProfile(cfg, name)
+				if err != nil {
+					candidates = append(candidates, fallback.Candidate{Name: name, Err: err})
+					continue
+				}
+				// The flags override the settings of every profile in the chain.
+				applyGenerationFlags(cmd, &profile.Generation, "")
+				applyTimeoutFlags(cmd, &profile)
+				applyRetryFlags(cmd, &profile.Retry)
+				chainProfiles[name] = profile
+				candidates = append(candidates, newFallbackCandidate(name, profile))
+			}
+			chain = fallback.NewProvider(candidates, os.Stderr)
+			provider = chain
+		} else {
+			provider, err = GetProvider(activeProfile)
+			if err != nil {
+				// Answering with the mock provider must be requested, so scripts never take its text for a real answer.
+				if !mockAllowed(cmd) {
+					return fmt.Errorf("error initializing provider for profile '%s': %w (use --allow-mock or LLM_CLI_MOCK=1 to answer with the mock provider instead)", profileName, err)
+				}
+				fmt.Fprintf(os.Stderr, "Warning: %v. Using mock provider.\n", err)
+				if provider, err = mock.NewProvider(activeProfile); err != nil {
+					return fmt.Errorf("error initializing mock provider: %w", err)
+				}
+			}
+			provider = withRequestPolicies(provider, activeProfile)
+		}
+		if toolSet != nil {
+			maxRounds, _ := cmd.Flags().GetInt("max-tool-rounds")
+			provider = tools.NewProvider(provider, toolSet, maxRounds, os.Stderr)
+		}
+		if replySchema != nil {
+			retries, _ := cmd.Flags().GetInt("schema-retries")
+			provider = schema.NewProvider(provider, replySchema, retries)
+		}
+
+		// 5. Load the conversation history if a session is used.
+		sessionName, _ := cmd.Flags().GetString("session")
+		var store *session.Store
+		var history []llm.Message
+		if sessionName != "" {
+			configDir, err := config.GetConfigDir(cfgFile)
+			if err != nil {
+				return fmt.Errorf("error determining config directory: %w", err)
+			}
+			store = session.NewStore(configDir)
+			entries, err := store.Load(sessionName)
+			if err != nil {
+				return err
+			}
+			history = session.Messages(entries)
+		}
+
+		req := buildRequest(systemPromptStr, history, userPromptStr)
+		req.Messages[len(req.Messages)-1].Parts = parts
+
+		// 6. Execute and get response.
+		var response *llm.Response
 		stream, _ := cmd.Flags().GetBool("stream")
 		if stream {
-			return handleStreamResponse(cmd, provider, systemPromptStr, userPromptStr, activeProfile, onOutputExceeded)
+			response, err = handleStreamResponse(cmd.Context(), cmd, provider, req, activeProfile, onOutputExceeded, format)
 		} else {
-			return handleSingleResponse(provider, systemPromptStr, userPromptStr, activeProfile, onOutputExceeded)
+			response, err = handleSingleResponse(cmd.Context(), provider, req, activeProfile, onOutputExceeded, format)
+		}
+		if err != nil {
+			return err
 		}
+
+		// Report the profile that answered, if it was a fallback.
+		if chain != nil && chain.Answered() != profileName {
+			profileName = chain.Answered()
+			activeProfile = chainProfiles[profileName]
+			fmt.Fprintf(os.Stderr, "Answered by fallback profile '%s' (provider: %s).\n", profileName, activeProfile.Provider)
+		}
+
+		// In the JSON formats, the response is written as an envelope once it is complete.
+		if format != outputText {
+			if err := printEnvelope(cmd.OutOrStdout(), newUsageReport(profileName, activeProfile, response), response, format); err != nil {
+				return fmt.Errorf("error writing output: %w", err)
+			}
+		}
+
+		// 7. Report token usage if requested.
+		showUsage, _ := cmd.Flags().GetBool("show-usage")
+		usageJSON, _ := cmd.Flags().GetBool("usage-json")
+		if showUsage || usageJSON {
+			if err := printUsage(os.Stderr, newUsageReport(profileName, activeProfile, response), usageJSON); err != nil {
+				return fmt.Errorf("error printing usage: %w", err)
+			}
+		}
+
+		// 8. Record the new turn in the session.
+		if store != nil {
+			now := time.Now()
+			if err := store.Append(sessionName,
+				session.Entry{Role: llm.RoleUser, Content: userPromptStr, Parts: parts, Profile: profileName, Time: now},
+				session.Entry{Role: llm.RoleAssistant, Content: response.Text, Profile: profileName, Time: now},
+			); err != nil {
+				return fmt.Errorf("error saving session: %w", err)
+			}
+		}
+		return nil
 	},
 }
 
-func handleSingleResponse(provider llm.Provider, systemPrompt, userPrompt string, profile config.Profile, onOutputExceeded string) error {
-	var response string
-	var err error
+// Output formats of the prompt command.
+const (
+	outputText  = "text"  // The bare response text.
+	outputJSON  = "json"  // A single JSON envelope with the response text and its metadata.
+	outputJSONL = "jsonl" // JSON lines: one "chunk" event per streamed token, then a "response" event with the envelope.
+)
+
+// validateOutputFormat checks the value of the --output flag.
+func validateOutputFormat(format string) error {
+	switch format {
+	case outputText, outputJSON, outputJSONL:
+		return nil
+	default:
+		return fmt.Errorf("invalid output format '%s': must be 'text', 'json' or 'jsonl'", format)
+	}
+}
+
+// outputEnvelope is the document written to stdout by the JSON output formats.
+type outputEnvelope struct {
+	Type string `json:"type,omitempty"` // The event type; only set in the jsonl format.
+	usageReport
+	Text       string   `json:"text"`
+	Candidates []string `json:"candidates,omitempty"` // The text of every candidate, if several were generated.
+}
+
+// printEnvelope writes the final response envelope to w in the given JSON output format.
+func printEnvelope(w io.Writer, report usageReport, response *llm.Response, format string) error {
+	envelope := outputEnvelope{usageReport: report, Text: response.Text, Candidates: response.Candidates}
+	encoder := json.NewEncoder(w)
+	if format == outputJSONL {
+		envelope.Type = "response"
+	} else {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder.Encode(envelope)
+}
+
+// usageReport describes the cost and outcome of a single call, as printed by --show-usage and --usage-json.
+type usageReport struct {
+	Profile      string     `json:"profile"`
+	Provider     string     `json:"provider"`
+	Model        string     `json:"model"`
+	Usage        *llm.Usage `json:"usage"` // Null if the provider did not report token usage.
+	FinishReason string     `json:"finish_reason,omitempty"`
+	LatencyMS    int64      `json:"latency_ms"`
+}
+
+// newUsageReport builds a usage report for a response. The model reported by the provider
+// takes precedence over the model configured in the profile.
+func newUsageReport(profileName string, profile config.Profile, resp *llm.Response) usageReport {
+	model := resp.Model
+	if model == "" {
+		model = profile.Model
+	}
+	return usageReport{
+		Profile:      profileName,
+		Provider:     profile.Provider,
+		Model:        model,
+		Usage:        resp.Usage,
+		FinishReason: resp.FinishReason,
+		LatencyMS:    resp.Latency.Milliseconds(),
+	}
+}
+
+// printUsage writes the usage report to w, either as a single JSON line or in a human-readable form.
+func printUsage(w io.Writer, report usageReport, asJSON bool) error {
+	if asJSON {
+		return json.NewEncoder(w).Encode(report)
+	}
+	inputTokens, outputTokens := "n/a", "n/a"
+	if report.Usage != nil {
+		inputTokens = strconv.Itoa(report.Usage.InputTokens)
+		outputTokens = strconv.Itoa(report.Usage.OutputTokens)
+	}
+	finishReason := report.FinishReason
+	if finishReason == "" {
+		finishReason = "n/a"
+	}
+	_, err := fmt.Fprintf(w, "Usage: model=%s input_tokens=%s output_tokens=%s finish_reason=%s latency=%dms\n",
+		report.Model, inputTokens, outputTokens, finishReason, report.LatencyMS)
+	return err
+}
+
+// addGenerationFlags defines the flags for generation parameters on a command, using the given name prefix.
+func addGenerationFlags(cmd *cobra.Command, prefix string) {
+	cmd.Flags().Float64(prefix+"temperature", 0, "Sampling temperature")
+	cmd.Flags().Float64(prefix+"top-p", 0, "Nucleus sampling probability mass (top_p)")
+	cmd.Flags().Int(prefix+"top-k", 0, "Number of highest probability tokens to sample from (top_k)")
+	cmd.Flags().Int(prefix+"max-tokens", 0, "Maximum number of tokens to generate")
+	cmd.Flags().StringSlice(prefix+"stop", nil, "Stop sequence (can be repeated or comma-separated)")
+	cmd.Flags().Int64(prefix+"seed", 0, "Seed for deterministic sampling, where supported")
+}
+
+// applyGenerationFlags copies the generation flags that were explicitly set into gen.
+func applyGenerationFlags(cmd *cobra.Command, gen *config.Generation, prefix string) {
+	flags := cmd.Flags()
+	if flags.Changed(prefix + "temperature") {
+		v, _ := flags.GetFloat64(prefix + "temperature")
+		gen.Temperature = &v
+	}
+	if flags.Changed(prefix + "top-p") {
+		v, _ := flags.GetFloat64(prefix + "top-p")
+		gen.TopP = &v
+	}
+	if flags.Changed(prefix + "top-k") {
+		v, _ := flags.GetInt(prefix + "top-k")
+		gen.TopK = &v
+	}
+	if flags.Changed(prefix + "max-tokens") {
+		v, _ := flags.GetInt(prefix + "max-tokens")
+		gen.MaxTokens = &v
+	}
+	if flags.Changed(prefix + "stop") {
+		gen.Stop, _ = flags.GetStringSlice(prefix + "stop")
+	}
+	if flags.Changed(prefix + "seed") {
+		v, _ := flags.GetInt64(prefix + "seed")
+		gen.Seed = &v
+	}
+}
+
+// mockAllowed reports whether the mock provider may answer in place of a provider that cannot be initialized,
+// as requested by the --allow-mock flag or the LLM_CLI_MOCK environment variable.
+func mockAllowed(cmd *cobra.Command) bool {
+	if allow, _ := cmd.Flags().GetBool("allow-mock"); allow {
+		return true
+	}
+	allow, _ := strconv.ParseBool(os.Getenv("LLM_CLI_MOCK"))
+	return allow
+}
+
+// newFallbackCandidate initializes the provider of a profile for a fallback chain. A profile whose provider
+// cannot be created or whose configuration is invalid becomes a candidate that fails, so the chain moves on.
+func newFallbackCandidate(name string, profile config.Profile) fallback.Candidate {
+	provider, err := GetProvider(profile)
+	if err == nil {
+		if validator, ok := provider.(llm.ConfigValidator); ok {
+			err = validator.ValidateConfig()
+		}
+	}
+	if err != nil {
+		return fallback.Candidate{Name: name, Err: err}
+	}
+	return fallback.Candidate{Name: name, Provider: withRequestPolicies(provider, profile)}
+}
+
+// applyTimeoutFlags copies the timeout flags that were explicitly set into the profile.
+func applyTimeoutFlags(cmd *cobra.Command, profile *config.Profile) {
+	flags := cmd.Flags()
+	if flags.Changed("connect-timeout") {
+		v, _ := flags.GetDuration("connect-timeout")
+		profile.ConnectTimeout = config.Duration(v)
+	}
+	if flags.Changed("request-timeout") {
+		v, _ := flags.GetDuration("request-timeout")
+		profile.RequestTimeout = config.Duration(v)
+	}
+}
+
+// applyRetryFlags copies the retry flags that were explicitly set into r.
+func applyRetryFlags(cmd *cobra.Command, r *config.Retry) {
+	flags := cmd.Flags()
+	if flags.Changed("retry-max-attempts") {
+		r.MaxAttempts, _ = flags.GetInt("retry-max-attempts")
+	}
+	if flags.Changed("retry-base-delay") {
+		v, _ := flags.GetDuration("retry-base-delay")
+		r.BaseDelay = config.Duration(v)
+	}
+	if flags.Changed("retry-max-delay") {
+		v, _ := flags.GetDuration("retry-max-delay")
+		r.MaxDelay = config.Duration(v)
+	}
+}
+
+// resolveProfile returns the named profile, or the active profile if name is empty.
+func resolveProfile(cfg *config.Config, name string) (config.Profile, error) {
+	if name != "" {
+		profile, ok := cfg.Profiles[name]
+		if !ok {
+			return config.Profile{}, fmt.Errorf("profile '%s' not found", name)
+		}
+		return profile, nil
+	}
+	profile, ok := cfg.Profiles[cfg.CurrentProfile]
+	if !ok {
+		return config.Profile{}, fmt.Errorf("active profile '%s' not found", cfg.CurrentProfile)
+	}
+	return profile, nil
+}
+
+// buildRequest builds a request from an optional system prompt, the previous turns and a new user prompt.
+func buildRequest(systemPrompt string, history []llm.Message, userPrompt string) llm.Request {
+	var messages []llm.Message
+	if systemPrompt != "" {
+		messages = append(messages, llm.Message{Role: llm.RoleSystem, Content: systemPrompt})
+	}
+	messages = append(messages, history...)
+	messages = append(messages, llm.Message{Role: llm.RoleUser, Content: userPrompt})
+	return llm.Request{Messages: messages}
+}
+
+// handleSingleResponse prints the complete response and returns it, with Text set to the text that was printed.
+// The text is only printed in the text output format.
+func handleSingleResponse(ctx context.Context, provider llm.Provider, req llm.Request, profile config.Profile, onOutputExceeded, format string) (*llm.Response, error) {
 
 	var s *spinner.Spinner
 	if isatty.IsTerminal(os.Stdout.Fd()) {
@@ -127,82 +501,136 @@ func handleSingleResponse(provider llm.Provider, systemPrompt, userPrompt string
 		s.Start()
 	}
 
-	response, err = provider.Chat(systemPrompt, userPrompt)
+	start := time.Now()
+	result, err := provider.Chat(ctx, req)
 
 	if s != nil {
 		s.Stop()
 	}
 
 	if err != nil {
-		return fmt.Errorf("error getting response: %w", err)
-	}
-
-	// Sanitize and check output size limit.
-	if profile.Limits.Enabled {
-		response = sanitizeUTF8(response, "output")
-		if int64(len(response)) > profile.Limits.MaxResponseSizeBytes {
-			if onOutputExceeded == "stop" {
-				return fmt.Errorf("output size (%d bytes) exceeds the limit of %d bytes", len(response), profile.Limits.MaxResponseSizeBytes)
-			} else if onOutputExceeded == "warn" {
-				fmt.Fprintf(os.Stderr, "Warning: Output size (%d bytes) exceeds the limit of %d bytes. Truncating...\n", len(response), profile.Limits.MaxResponseSizeBytes)
-				response = truncateStringByBytes(response, profile.Limits.MaxResponseSizeBytes)
-			}
-		}
+		return nil, fmt.Errorf("error getting response: %w", err)
+	}
+	result.Latency = time.Since(start)
+	response, err := limitOutput(result.Text, profile.Limits, onOutputExceeded)
+	if err != nil {
+		return nil, err
 	}
 
-	fmt.Println(response)
-	return nil
+	if format == outputText {
+		fmt.Println(response)
+	}
+	result.Text = response
+	return result, nil
+}
+
+// limitOutput sanitizes a complete response and checks it against the output size limit.
+func limitOutput(response string, limits config.Limits, onExceeded string) (string, error) {
+	if !limits.Enabled {
+		return response, nil
+	}
+	response = sanitizeUTF8(response, "output")
+	if int64(len(response)) > limits.MaxResponseSizeBytes {
+		if onExceeded == "stop" {
+			return "", fmt.Errorf("output size (%d bytes) exceeds the limit of %d bytes", len(response), limits.MaxResponseSizeBytes)
+		} else if onExceeded == "warn" {
+			fmt.Fprintf(os.Stderr, "Warning: Output size (%d bytes) exceeds the limit of %d bytes. Truncating...\n", len(response), limits.MaxResponseSizeBytes)
+			response = truncateStringByBytes(response, limits.MaxResponseSizeBytes)
+		}
+	}
+	return response, nil
 }
 
-func handleStreamResponse(cmd *cobra.Command, provider llm.Provider, systemPrompt, userPrompt string, profile config.Profile, onOutputExceeded string) error {
+// handleStreamResponse prints the streamed response as it arrives and returns the response metadata,
+// with Text set to the text that was printed. In the jsonl output format each token is written as a
+// "chunk" event, and in the json format nothing is printed until the caller writes the envelope.
+func handleStreamResponse(ctx context.Context, cmd *cobra.Command, provider llm.Provider, req llm.Request, profile config.Profile, onOutputExceeded, format string) (*llm.Response, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	var wg sync.WaitGroup
+	var result *llm.Response
 	errChan := make(chan error, 1)
 	responseChan := make(chan string)
 
+	start := time.Now()
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		var once sync.Once
 		defer once.Do(func() { close(responseChan) }) // Ensure responseChan is closed only once
-		err := provider.ChatStream(cmd.Context(), systemPrompt, userPrompt, responseChan)
+		var err error
+		result, err = provider.ChatStream(ctx, req, responseChan)
 		if err != nil {
 			errChan <- err
 		}
 	}()
 
+	encoder := json.NewEncoder(cmd.OutOrStdout())
+	emit := func(token string) {
+		switch format {
+		case outputText:
+			fmt.Print(token)
+		case outputJSONL:
+			_ = encoder.Encode(outputEnvelope{Type: "chunk", Text: token})
+		}
+	}
+
 	var totalResponseSize int64
 	var truncated bool
+	var limitErr error
+	var output strings.Builder
 	for token := range responseChan {
+		// Once the output limit has been hit, the stream is cancelled and the remaining tokens are drained
+		// so that the provider goroutine does not block on the channel.
+		if truncated || limitErr != nil {
+			continue
+		}
 		sanitizedToken := sanitizeUTF8(token, "output")
 
-		if profile.Limits.Enabled && !truncated {
+		if profile.Limits.Enabled {
 			if totalResponseSize+int64(len(sanitizedToken)) > profile.Limits.MaxResponseSizeBytes {
 				if onOutputExceeded == "stop" {
-					return fmt.Errorf("\nError: Output size exceeded the limit of %d bytes", profile.Limits.MaxResponseSizeBytes)
+					limitErr = fmt.Errorf("\nError: Output size exceeded the limit of %d bytes", profile.Limits.MaxResponseSizeBytes)
+					cancel()
+					continue
 				} else if onOutputExceeded == "warn" {
 					remainingBytes := profile.Limits.MaxResponseSizeBytes - totalResponseSize
-					fmt.Print(truncateStringByBytes(sanitizedToken, remainingBytes))
+					truncatedToken := truncateStringByBytes(sanitizedToken, remainingBytes)
+					output.WriteString(truncatedToken)
+					emit(truncatedToken)
 					fmt.Fprintf(os.Stderr, "\nWarning: Output size exceeded the limit of %d bytes. Truncating...\n", profile.Limits.MaxResponseSizeBytes)
 					truncated = true
-					break
+					cancel()
+					continue
 				}
 			}
 		}
 		totalResponseSize += int64(len(sanitizedToken))
-		fmt.Print(sanitizedToken)
+		output.WriteString(sanitizedToken)
+		emit(sanitizedToken)
 	}
 
 	wg.Wait()
 	close(errChan)
 
-	if err := <-errChan; err != nil {
-		return fmt.Errorf("\nError: %w", err)
+	if limitErr != nil {
+		return nil, limitErr
+	}
+	// Errors caused by cancelling a truncated stream are expected and ignored.
+	if err := <-errChan; err != nil && !truncated {
+		return nil, fmt.Errorf("\nError: %w", err)
 	}
 
-	if !truncated {
+	if !truncated && format == outputText {
 		fmt.Println()
 	}
-	return nil
+	if result == nil {
+		result = &llm.Response{}
+	}
+	result.Text = output.String()
+	result.Latency = time.Since(start)
+	return result, nil
 }
 
 // loadUserPrompt loads the user prompt from a direct value, a file, or stdin.
@@ -228,6 +656,178 @@ func loadUserPrompt(directValue, filePath string, args []string, limits config.L
 	return readAndProcessStream(os.Stdin, "stdin", limits, onExceeded)
 }
 
+// renderTemplate renders a prompt template with the --var and --var-file variables and returns the system
+// and user prompts. The user prompt given as usual is available to the template as the "input" variable, and a
+// system prompt given by flag replaces the template's. Variable files and the rendered prompts are subject to
+// the same size limits as other prompts.
+func renderTemplate(cmd *cobra.Command, tmpl *templates.Template, systemPrompt, input string, limits config.Limits, onExceeded string) (string, string, error) {
+	vars := make(map[string]string)
+	if input != "" {
+		vars[templates.InputVar] = input
+	}
+	assignments, _ := cmd.Flags().GetStringArray("var")
+	for _, assignment := range assignments {
+		name, value, ok := strings.Cut(assignment, "=")
+		if !ok || name == "" {
+			return "", "", fmt.Errorf("invalid --var '%s': must be name=value", assignment)
+		}
+		vars[name] = value
+	}
+	fileAssignments, _ := cmd.Flags().GetStringArray("var-file")
+	for _, assignment := range fileAssignments {
+		name, path, ok := strings.Cut(assignment, "=")
+		if !ok || name == "" || path == "" {
+			return "", "", fmt.Errorf("invalid --var-file '%s': must be name=path", assignment)
+		}
+		if path == "-" {
+			return "", "", fmt.Errorf("invalid --var-file '%s': stdin is passed to the template as the '%s' variable", assignment, templates.InputVar)
+		}
+		value, err := loadPromptFromFile(path, limits, onExceeded)
+		if err != nil {
+			return "", "", err
+		}
+		vars[name] = value
+	}
+
+	system, user, err := tmpl.Render(vars)
+	if err != nil {
+		return "", "", err
+	}
+	source := fmt.Sprintf("template '%s'", tmpl.Name)
+	if systemPrompt == "" {
+		if systemPrompt, err = handlePromptData([]byte(system), source, limits, onExceeded); err != nil {
+			return "", "", err
+		}
+	}
+	if user, err = handlePromptData([]byte(user), source, limits, onExceeded); err != nil {
+		return "", "", err
+	}
+	return systemPrompt, user, nil
+}
+
+// attachFiles adds the files of the --attach paths to the user prompt as blocks labeled with their paths,
+// and reports the size of each file on stderr. The attachments count toward the input size limit together with the prompt.
+// With the "warn" policy, files that do not fit next to the prompt are skipped, so the prompt itself is never truncated.
+func attachFiles(prompt string, paths, excludes []string, limits config.Limits, onExceeded string) (string, error) {
+	opts := attach.Options{Excludes: excludes}
+	if limits.Enabled {
+		// A larger file exceeds the limit anyway, so it is not read completely.
+		opts.MaxFileBytes = limits.MaxPromptSizeBytes + 1
+	}
+	files, err := attach.Collect(paths, opts)
+	if err != nil {
+		return "", err
+	}
+
+	budget := int64(-1)
+	if limits.Enabled && onExceeded == "warn" {
+		budget = max(limits.MaxPromptSizeBytes-int64(len(prompt)), 0)
+	}
+
+	var blocks strings.Builder
+	var attachable, attached int
+	var total int64
+	for i, f := range files {
+		if f.Skipped != "" {
+			fmt.Fprintf(os.Stderr, "Skipped %s: %s\n", f.Path, f.Skipped)
+			continue
+		}
+		attachable++
+		block := sanitizeUTF8(attach.Format(files[i:i+1]), f.Path)
+		if budget >= 0 && int64(blocks.Len()+len(block)) > budget {
+			fmt.Fprintf(os.Stderr, "Skipped %s: exceeds the input limit of %d bytes together with the prompt\n", f.Path, limits.MaxPromptSizeBytes)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "Attached %s (%d bytes)\n", f.Path, f.Size)
+		blocks.WriteString(block)
+		attached++
+		total += f.Size
+	}
+	if attachable == 0 {
+		return "", fmt.Errorf("no files to attach")
+	}
+	fmt.Fprintf(os.Stderr, "Attached %d files (%d bytes).\n", attached, total)
+
+	return handlePromptData([]byte(blocks.String()+prompt), "attachments", limits, onExceeded)
+}
+
+// documentTypes maps the extensions of common document formats to their MIME types,
+// since the system's MIME table does not always know them.
+var documentTypes = map[string]string{
+	".pdf":  "application/pdf",
+	".csv":  "text/csv",
+	".txt":  "text/plain",
+	".md":   "text/markdown",
+	".html": "text/html",
+	".htm":  "text/html",
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xls":  "application/vnd.ms-excel",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// loadParts reads the images and documents to send with the user prompt and detects their MIME types.
+// Their sizes count toward the input size limit together with the prompts of the given size. Binary data cannot be
+// truncated, so with "warn" a file that exceeds the limit is left out and reported on stderr.
+func loadParts(images, documents []string, promptSize int64, limits config.Limits, onExceeded string) ([]llm.Part, error) {
+	var parts []llm.Part
+	total := promptSize
+	load := func(path string, image bool) error {
+		stat, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("error reading '%s': %w", path, err)
+		}
+		if stat.IsDir() {
+			return fmt.Errorf("error reading '%s': is a directory", path)
+		}
+		if limits.Enabled && total+stat.Size() > limits.MaxPromptSizeBytes {
+			if onExceeded == "stop" {
+				return fmt.Errorf("input exceeds the limit of %d bytes with '%s' (%d bytes)", limits.MaxPromptSizeBytes, path, stat.Size())
+			}
+			fmt.Fprintf(os.Stderr, "Warning: Input exceeds the limit of %d bytes with '%s' (%d bytes). Skipping the file...\n", limits.MaxPromptSizeBytes, path, stat.Size())
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading '%s': %w", path, err)
+		}
+		mimeType := detectMIMEType(path, data)
+		if image && !strings.HasPrefix(mimeType, "image/") {
+			return fmt.Errorf("'%s' is not an image (detected %s)", path, mimeType)
+		}
+		total += int64(len(data))
+		parts = append(parts, llm.Part{Name: filepath.Base(path), MIMEType: mimeType, Data: data})
+		return nil
+	}
+	for _, path := range images {
+		if err := load(path, true); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range documents {
+		if err := load(path, false); err != nil {
+			return nil, err
+		}
+	}
+	return parts, nil
+}
+
+// detectMIMEType returns the MIME type of a file, without parameters, from its extension or, failing that, its contents.
+func detectMIMEType(path string, data []byte) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if mimeType, ok := documentTypes[ext]; ok {
+		return mimeType
+	}
+	mimeType := mime.TypeByExtension(ext)
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		return mediaType
+	}
+	return mimeType
+}
+
 // loadSystemPrompt loads the system prompt from a direct value or a file.
 // It explicitly disallows reading from stdin for system prompts.
 func loadSystemPrompt(directValue, filePath string, limits config.Limits, onExceeded string) (string, error) {
@@ -357,6 +957,35 @@ func init() {
 	promptCmd.Flags().StringP("system-prompt-file", "F", "", "Path to a file containing the system prompt.")
 	promptCmd.Flags().Bool("stream", false, "Enable streaming response")
 	promptCmd.Flags().String("profile", "", "Use a specific profile for this command (overrides current active profile)")
+	promptCmd.Flags().StringSlice("fallback", nil, "Profiles to try in order if the profile fails, e.g., when its server is unreachable (comma-separated)")
+	promptCmd.Flags().StringArray("attach", nil, "Attach a file, a directory or a glob such as 'src/**/*.go' as context (can be repeated)")
+	promptCmd.Flags().StringArray("exclude", nil, "Leave files matching a .gitignore-style pattern out of attached directories and globs (can be repeated)")
+	promptCmd.Flags().StringArray("image", nil, "Send an image (PNG, JPEG, GIF or WebP) with the prompt, for vision-capable models (can be repeated)")
+	promptCmd.Flags().StringArray("document", nil, "Send a document, such as a PDF, with the prompt, where the provider supports it (can be repeated)")
+	promptCmd.Flags().String("template", "", "Render the prompt from a template of the library (see 'llm-cli template list')")
+	promptCmd.Flags().StringArray("var", nil, "Set a template variable (name=value, can be repeated)")
+	promptCmd.Flags().StringArray("var-file", nil, "Set a template variable to the contents of a file (name=path, can be repeated)")
+	promptCmd.Flags().Bool("allow-mock", false, "Answer with the mock provider if the profile's provider cannot be initialized (also enabled by LLM_CLI_MOCK=1)")
+	promptCmd.Flags().String("session", "", "Continue a named session: previous turns are resent and the new turn is saved")
+	promptCmd.Flags().String("json-schema", "", "Path to a JSON schema the reply must conform to. The reply is validated before it is printed.")
+	promptCmd.Flags().Int("schema-retries", 0, "Number of times to ask again when the reply does not match --json-schema")
+	promptCmd.Flags().String("tools", "", "Path to a YAML file of tools the model may call. Each tool runs a local command.")
+	promptCmd.Flags().Int("max-tool-rounds", 10, "Maximum number of tool-calling rounds with --tools")
+	promptCmd.Flags().String("output", outputText, "Output format: text, json or jsonl (jsonl streams chunk events with --stream)")
+	promptCmd.Flags().Bool("show-usage", false, "Print token usage, finish reason and latency to stderr")
+	promptCmd.Flags().Bool("usage-json", false, "Print token usage, finish reason and latency to stderr as a JSON line")
+
+	// Flags for generation parameters
+	addGenerationFlags(promptCmd, "")
+
+	// Flags for timeouts
+	promptCmd.Flags().Duration("connect-timeout", 0, "Limit for establishing a connection to the provider, including the TLS handshake (default 30s)")
+	promptCmd.Flags().Duration("request-timeout", 0, "Limit for a single request, or for the first token with --stream (default: none)")
+
+	// Flags for the retry policy
+	promptCmd.Flags().Int("retry-max-attempts", 0, "Total attempts for transient provider errors such as 429 or 503, including the first (1 disables retries; default 3)")
+	promptCmd.Flags().Duration("retry-base-delay", 0, "Delay before the first retry, doubled for every further retry (default 1s)")
+	promptCmd.Flags().Duration("retry-max-delay", 0, "Upper bound of a single retry delay, including one requested by Retry-After (default 30s)")
 
 	// Flags for limits
 	promptCmd.Flags().String("on-input-exceeded", "", "Action on input size limit exceeded (stop or warn)")
@@ -0,0 +1,233 @@
+/*
+Copyright © 2025 magifd2
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/magifd2/llm-cli/internal/llm/mock"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintUsage(t *testing.T) {
+	profile := config.Profile{Provider: "openai", Model: "gpt-4o"}
+	resp := &llm.Response{
+		Model:        "gpt-4o-2024-08-06",
+		FinishReason: "stop",
+		Usage:        &llm.Usage{InputTokens: 12, OutputTokens: 34},
+		Latency:      1500 * time.Millisecond,
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, printUsage(&buf, newUsageReport("ci", profile, resp), true))
+	assert.JSONEq(t, `{"profile":"ci","provider":"openai","model":"gpt-4o-2024-08-06","usage":{"input_tokens":12,"output_tokens":34},"finish_reason":"stop","latency_ms":1500}`, buf.String())
+
+	// Without provider metadata, the configured model is reported and the token counts are unknown.
+	buf.Reset()
+	require.NoError(t, printUsage(&buf, newUsageReport("ci", profile, &llm.Response{}), false))
+	assert.Equal(t, "Usage: model=gpt-4o input_tokens=n/a output_tokens=n/a finish_reason=n/a latency=0ms\n", buf.String())
+}
+
+func TestHandleSingleResponse_ReturnsMetadata(t *testing.T) {
+	provider := &mock.Provider{Model: "mock-model"}
+	resp, err := handleSingleResponse(context.Background(), provider, llm.NewRequest("", "hello world"), config.Profile{}, "", outputText)
+	require.NoError(t, err)
+	assert.Contains(t, resp.Text, "User Prompt: hello world")
+	assert.Equal(t, "mock-model", resp.Model)
+	assert.Equal(t, "stop", resp.FinishReason)
+	require.NotNil(t, resp.Usage)
+	assert.Equal(t, 2, resp.Usage.InputTokens)
+}
+
+func TestPromptCommand_OutputJSON(t *testing.T) {
+	_ = setupTestEnvironment(t)
+	addTestProfile(t, "mock_profile", config.Profile{Provider: "mock", Model: "mock-model"})
+	// Flag values persist on the shared root command, so restore the defaults afterwards.
+	t.Cleanup(func() {
+		_ = promptCmd.Flags().Set("output", outputText)
+		_ = promptCmd.Flags().Set("stream", "false")
+	})
+
+	stdout, _, err := executeCommand(rootCmd, "prompt", "--profile", "mock_profile", "--output", "json", "hello")
+	require.NoError(t, err)
+	var envelope map[string]any
+	require.NoError(t, json.Unmarshal([]byte(stdout), &envelope))
+	assert.Equal(t, "mock_profile", envelope["profile"])
+	assert.Equal(t, "mock", envelope["provider"])
+	assert.Equal(t, "mock-model", envelope["model"])
+	assert.Equal(t, "stop", envelope["finish_reason"])
+	assert.Contains(t, envelope["text"], "User Prompt: hello")
+	assert.NotNil(t, envelope["usage"])
+
+	stdout, _, err = executeCommand(rootCmd, "prompt", "--profile", "mock_profile", "--output", "jsonl", "--stream", "hello")
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"type":"chunk"`)
+	assert.Contains(t, lines[1], `"type":"response"`)
+	assert.Contains(t, lines[1], `"model":"mock-model"`)
+
+	_, _, err = executeCommand(rootCmd, "prompt", "--profile", "mock_profile", "--output", "xml", "hello")
+	assert.Error(t, err)
+}
+
+func TestPromptCommand_Fallback(t *testing.T) {
+	_ = setupTestEnvironment(t)
+	// Nothing listens on port 1, so the first profile fails to connect.
+	addTestProfile(t, "unreachable", config.Profile{Provider: "ollama", Model: "llama3", Endpoint: "http://127.0.0.1:1/api/chat", Retry: config.Retry{MaxAttempts: 1}})
+	addTestProfile(t, "invalid", config.Profile{Provider: "anthropic"})
+	addTestProfile(t, "mock_profile", config.Profile{Provider: "mock", Model: "mock-model"})
+	// Slice flags append to their previous values, so they are reset between runs.
+	resetFallback := func() { _ = promptCmd.Flags().Lookup("fallback").Value.(pflag.SliceValue).Replace(nil) }
+	t.Cleanup(func() {
+		_ = promptCmd.Flags().Set("output", outputText)
+		resetFallback()
+	})
+
+	stdout, _, err := executeCommand(rootCmd, "prompt", "--profile", "unreachable", "--fallback", "invalid,mock_profile", "--output", "json", "hello")
+	require.NoError(t, err)
+	var envelope map[string]any
+	require.NoError(t, json.Unmarshal([]byte(stdout), &envelope))
+	assert.Equal(t, "mock_profile", envelope["profile"], "the profile that answered is reported")
+	assert.Equal(t, "mock", envelope["provider"])
+
+	resetFallback()
+	_, _, err = executeCommand(rootCmd, "prompt", "--profile", "unreachable", "--fallback", "invalid,missing", "hello")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "all providers failed")
+	assert.Contains(t, err.Error(), "missing: profile 'missing' not found")
+}
+
+func TestPromptCommand_AllowMock(t *testing.T) {
+	_ = setupTestEnvironment(t)
+	addTestProfile(t, "unknown", config.Profile{Provider: "no-such-provider", Model: "model"})
+	t.Cleanup(func() {
+		_ = promptCmd.Flags().Set("allow-mock", "false")
+		_ = promptCmd.Flags().Set("output", outputText)
+	})
+
+	_, _, err := executeCommand(rootCmd, "prompt", "--profile", "unknown", "hello")
+	require.Error(t, err, "an unknown provider fails instead of answering with the mock provider")
+	assert.Contains(t, err.Error(), "provider 'no-such-provider' not recognized")
+
+	stdout, _, err := executeCommand(rootCmd, "prompt", "--profile", "unknown", "--allow-mock", "--output", "json", "hello")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Mock Response")
+
+	require.NoError(t, promptCmd.Flags().Set("allow-mock", "false"))
+	t.Setenv("LLM_CLI_MOCK", "1")
+	stdout, _, err = executeCommand(rootCmd, "prompt", "--profile", "unknown", "--output", "json", "hello")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Mock Response")
+}
+
+func TestPromptCommand_Attach(t *testing.T) {
+	tempDir := setupTestEnvironment(t)
+	limits := config.Limits{Enabled: true, OnInputExceeded: "stop", MaxPromptSizeBytes: 200}
+	addTestProfile(t, "mock_profile", config.Profile{Provider: "mock", Model: "mock-model", Limits: limits})
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "main.go"), []byte("package main\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "big.txt"), []byte(strings.Repeat("x", 300)), 0600))
+	// Array flags append to their previous values, so they are reset between runs.
+	resetAttach := func() { _ = promptCmd.Flags().Lookup("attach").Value.(pflag.SliceValue).Replace(nil) }
+	t.Cleanup(func() {
+		_ = promptCmd.Flags().Set("output", outputText)
+		resetAttach()
+	})
+
+	stdout, _, err := executeCommand(rootCmd, "prompt", "--profile", "mock_profile", "--attach", filepath.Join(tempDir, "main.go"), "--output", "json", "Explain")
+	require.NoError(t, err)
+	var envelope map[string]any
+	require.NoError(t, json.Unmarshal([]byte(stdout), &envelope))
+	assert.Contains(t, envelope["text"], "User Prompt: File: "+filepath.ToSlash(filepath.Join(tempDir, "main.go"))+"\n```go\npackage main\n```\n\nExplain")
+
+	resetAttach()
+	_, _, err = executeCommand(rootCmd, "prompt", "--profile", "mock_profile", "--attach", filepath.Join(tempDir, "big.txt"), "Explain")
+	assert.ErrorContains(t, err, "input from attachments exceeds size limit of 200 bytes", "attachments count toward the input limit")
+
+	// With "warn", a file that does not fit is skipped instead of truncating the question.
+	limits.OnInputExceeded = "warn"
+	addTestProfile(t, "mock_profile", config.Profile{Provider: "mock", Model: "mock-model", Limits: limits})
+	resetAttach()
+	stdout, _, err = executeCommand(rootCmd, "prompt", "--profile", "mock_profile",
+		"--attach", filepath.Join(tempDir, "main.go"), "--attach", filepath.Join(tempDir, "big.txt"), "--output", "json", "Explain")
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal([]byte(stdout), &envelope))
+	assert.Contains(t, envelope["text"], "```go\npackage main\n```\n\nExplain")
+	assert.NotContains(t, envelope["text"], "xxx")
+}
+
+func TestPromptCommand_ImageAndDocument(t *testing.T) {
+	tempDir := setupTestEnvironment(t)
+	limits := config.Limits{Enabled: true, OnInputExceeded: "stop", MaxPromptSizeBytes: 100}
+	addTestProfile(t, "mock_profile", config.Profile{Provider: "mock", Model: "mock-model", Limits: limits})
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 8)...)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "photo"), png, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes.md"), []byte("# notes\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "big.pdf"), make([]byte, 200), 0600))
+	resetParts := func() {
+		_ = promptCmd.Flags().Lookup("image").Value.(pflag.SliceValue).Replace(nil)
+		_ = promptCmd.Flags().Lookup("document").Value.(pflag.SliceValue).Replace(nil)
+	}
+	t.Cleanup(func() {
+		_ = promptCmd.Flags().Set("output", outputText)
+		resetParts()
+	})
+
+	stdout, _, err := executeCommand(rootCmd, "prompt", "--profile", "mock_profile", "--image", filepath.Join(tempDir, "photo"),
+		"--document", filepath.Join(tempDir, "notes.md"), "--output", "json", "Describe")
+	require.NoError(t, err)
+	var envelope map[string]any
+	require.NoError(t, json.Unmarshal([]byte(stdout), &envelope))
+	assert.Contains(t, envelope["text"], "Part: photo (image/png, 16 bytes)\nPart: notes.md (text/markdown, 8 bytes)\nUser Prompt: Describe\n", "the type is detected from the contents without an extension")
+
+	resetParts()
+	_, _, err = executeCommand(rootCmd, "prompt", "--profile", "mock_profile", "--image", filepath.Join(tempDir, "notes.md"), "Describe")
+	assert.ErrorContains(t, err, "is not an image (detected text/markdown)")
+
+	resetParts()
+	_, _, err = executeCommand(rootCmd, "prompt", "--profile", "mock_profile", "--document", filepath.Join(tempDir, "big.pdf"), "Describe")
+	assert.ErrorContains(t, err, "input exceeds the limit of 100 bytes with", "documents count toward the input limit")
+}
+
+func TestLoadParts_WarnSkipsFilesOverTheLimit(t *testing.T) {
+	dir := t.TempDir()
+	small, big := filepath.Join(dir, "small.pdf"), filepath.Join(dir, "big.pdf")
+	require.NoError(t, os.WriteFile(small, make([]byte, 40), 0600))
+	require.NoError(t, os.WriteFile(big, make([]byte, 80), 0600))
+
+	limits := config.Limits{Enabled: true, MaxPromptSizeBytes: 100}
+	parts, err := loadParts(nil, []string{small, big}, 10, limits, "warn")
+	require.NoError(t, err)
+	require.Len(t, parts, 1)
+	assert.Equal(t, llm.Part{Name: "small.pdf", MIMEType: "application/pdf", Data: make([]byte, 40)}, parts[0])
+}
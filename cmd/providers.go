@@ -2,16 +2,23 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"time"
 
+	"github.com/magifd2/llm-cli/internal/batch"
 	"github.com/magifd2/llm-cli/internal/config"
 	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/magifd2/llm-cli/internal/llm/anthropic"
+	"github.com/magifd2/llm-cli/internal/llm/azureopenai"
 	"github.com/magifd2/llm-cli/internal/llm/bedrock"
+	"github.com/magifd2/llm-cli/internal/llm/gemini"
 	"github.com/magifd2/llm-cli/internal/llm/mock"
 	"github.com/magifd2/llm-cli/internal/llm/ollama"
 	"github.com/magifd2/llm-cli/internal/llm/openai"
 	"github.com/magifd2/llm-cli/internal/llm/openai2"
+	"github.com/magifd2/llm-cli/internal/llm/retry"
+	"github.com/magifd2/llm-cli/internal/llm/timeout"
 	"github.com/magifd2/llm-cli/internal/llm/vertexai"
-	"github.com/magifd2/llm-cli/internal/llm/vertexai2"
 )
 
 // providerFactory defines the function signature for creating a new llm.Provider.
@@ -21,13 +28,17 @@ type providerFactory func(profile config.Profile) (llm.Provider, error)
 // providerRegistry holds the mapping from a provider name (string) to its factory function.
 // This is the central, explicit, and consistent registry for all supported providers.
 var providerRegistry = map[string]providerFactory{
-	"ollama":    ollama.NewProvider,
-	"openai":    openai.NewProvider,
-	"openai2":   openai2.NewProvider,
-	"bedrock":   bedrock.NewProvider,
-	"vertexai":  vertexai.NewProvider,
-	"vertexai2": vertexai2.NewProvider,
-	"mock":      mock.NewProvider,
+	"ollama":           ollama.NewProvider,
+	"openai":           openai.NewProvider,
+	"openai2":          openai2.NewProvider,
+	"azureopenai":      azureopenai.NewProvider,
+	"anthropic":        anthropic.NewProvider,
+	"bedrock":          bedrock.NewProvider,
+	"bedrock-converse": bedrock.NewConverseProvider,
+	"vertexai":         vertexai.NewProvider,
+	"vertexai2":        vertexai.NewProvider, // Kept for existing profiles; it behaves exactly like vertexai.
+	"gemini":           gemini.NewProvider,
+	"mock":             mock.NewProvider,
 }
 
 // GetProvider retrieves a provider instance based on the provider name in the profile.
@@ -40,3 +51,17 @@ func GetProvider(profile config.Profile) (llm.Provider, error) {
 
 	return factory(profile)
 }
+
+// withRequestPolicies wraps a provider with the request timeout and the retry policy of the profile.
+// The timeout bounds each attempt, so an attempt that hangs is retried like any other transient failure.
+func withRequestPolicies(provider llm.Provider, profile config.Profile) llm.Provider {
+	return withLimitedRequestPolicies(provider, profile, nil)
+}
+
+// withLimitedRequestPolicies is withRequestPolicies with a rate limit. Every attempt, including each retry,
+// waits for the limiter, and its timeout starts once the wait is over.
+func withLimitedRequestPolicies(provider llm.Provider, profile config.Profile, limiter *batch.Limiter) llm.Provider {
+	provider = timeout.NewProvider(provider, time.Duration(profile.RequestTimeout))
+	provider = batch.NewLimitedProvider(provider, limiter)
+	return retry.NewProvider(provider, retry.NewPolicy(profile.Retry), os.Stderr)
+}
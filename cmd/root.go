@@ -22,6 +22,11 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"github.com/spf13/cobra"
 )
 
@@ -30,6 +35,10 @@ var version = "dev"
 
 var cfgFile string
 
+// handlesInterrupt is the annotation key of commands that handle SIGINT themselves instead of having their
+// context cancelled by it.
+const handlesInterrupt = "handles-interrupt"
+
 // rootCmd represents the base command for the llm-cli application.
 // It defines the application's name, version, short description, and long description.
 // rootCmdはllm-cliアプリケーションのベースコマンドです。
@@ -55,8 +64,21 @@ This tool simplifies the process of experimenting with and utilizing different L
 // This is called by main.main() and should only be called once.
 // Executeは全ての子コマンドをルートコマンドに追加し、フラグを適切に設定します。
 // これはmain.main()から呼び出され、一度だけ呼び出されるべきです。
+// The command context is cancelled on SIGINT or SIGTERM, so that requests in progress are aborted cleanly;
+// a second signal terminates the process immediately. Commands annotated with handlesInterrupt (the chat
+// REPL) are only cancelled by SIGTERM and deal with SIGINT themselves.
 func Execute() error {
-	return rootCmd.Execute()
+	signals := []os.Signal{os.Interrupt, syscall.SIGTERM}
+	if cmd, _, err := rootCmd.Find(os.Args[1:]); err == nil && cmd.Annotations[handlesInterrupt] != "" {
+		signals = []os.Signal{syscall.SIGTERM}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), signals...)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return rootCmd.ExecuteContext(ctx)
 }
 
 // init function is called before main.
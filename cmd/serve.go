@@ -0,0 +1,139 @@
+/*
+Copyright © 2025 magifd2
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/magifd2/llm-cli/internal/server"
+	"github.com/spf13/cobra"
+)
+
+// serveTokenEnv is the environment variable the bearer token is read from if --token is not set.
+const serveTokenEnv = "LLM_CLI_SERVE_TOKEN"
+
+// serveCmd represents the 'serve' command.
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the profiles through an OpenAI-compatible API",
+	Long: `Starts a local HTTP server with the OpenAI Chat Completions API (/v1/chat/completions, streaming or not)
+and /v1/models, so that tools and SDKs written for OpenAI can use any configured profile.
+
+A request is routed to the profile named by the X-LLM-CLI-Profile header, else the profile named by its model,
+else the first profile configured with that model. Requests without a model use --profile or the current profile.
+The limits of the profile are applied to each request.
+
+If a token is set with --token or the ` + serveTokenEnv + ` environment variable, requests must send it as a bearer token.
+Without a token, only requests addressed to localhost or a loopback address are accepted, so that web pages cannot use
+the server through DNS rebinding. Request bodies must be application/json.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load(cfgFile)
+		if err != nil {
+			return fmt.Errorf("error loading config: %w", err)
+		}
+		defaultProfile, _ := cmd.Flags().GetString("profile")
+		if defaultProfile == "" {
+			defaultProfile = cfg.CurrentProfile
+		}
+		if _, ok := cfg.Profiles[defaultProfile]; !ok {
+			return fmt.Errorf("profile '%s' not found", defaultProfile)
+		}
+		token, _ := cmd.Flags().GetString("token")
+		if token == "" {
+			token = os.Getenv(serveTokenEnv)
+		}
+		addr, _ := cmd.Flags().GetString("addr")
+
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("error listening on %s: %w", addr, err)
+		}
+		if token == "" && !isLoopback(listener.Addr()) {
+			fmt.Fprintf(os.Stderr, "Warning: listening on %s without a token: only requests addressed to localhost are accepted. Set --token to serve other machines.\n", listener.Addr())
+		}
+
+		srv := &server.Server{
+			Config:         cfg,
+			DefaultProfile: defaultProfile,
+			Token:          token,
+			NewProvider:    newServeProvider,
+			Log:            os.Stderr,
+		}
+		fmt.Fprintf(os.Stderr, "Serving %d profiles on http://%s/v1 (default profile: %s). Press Ctrl+C to stop.\n", len(cfg.Profiles), listener.Addr(), defaultProfile)
+		return serveUntilDone(cmd.Context(), listener, srv.Handler())
+	},
+}
+
+// newServeProvider creates the provider of a profile for the server, which reuses it for the following requests.
+func newServeProvider(profile config.Profile) (llm.Provider, error) {
+	provider, err := GetProvider(profile)
+	if err != nil {
+		return nil, err
+	}
+	return withRequestPolicies(provider, profile), nil
+}
+
+// serveUntilDone serves HTTP on the listener until ctx is done, then lets the requests in progress finish.
+func serveUntilDone(ctx context.Context, listener net.Listener, handler http.Handler) error {
+	httpServer := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- httpServer.Serve(listener)
+	}()
+	select {
+	case err := <-errChan:
+		return fmt.Errorf("error serving: %w", err)
+	case <-ctx.Done():
+	}
+	fmt.Fprintln(os.Stderr, "Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("error shutting down: %w", err)
+	}
+	if err := <-errChan; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("error serving: %w", err)
+	}
+	return nil
+}
+
+// isLoopback reports whether addr only accepts connections from this machine.
+func isLoopback(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	return ok && tcpAddr.IP.IsLoopback()
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().String("addr", "127.0.0.1:8080", "Address to listen on")
+	serveCmd.Flags().String("token", "", "Bearer token required from clients (default: $"+serveTokenEnv+")")
+	serveCmd.Flags().String("profile", "", "Profile for the requests without a model (overrides current active profile)")
+}
@@ -0,0 +1,219 @@
+/*
+Copyright © 2025 magifd2
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/magifd2/llm-cli/internal/session"
+	"github.com/spf13/cobra"
+)
+
+// sessionCmd represents the base command for managing stored conversation sessions.
+var sessionCmd = &cobra.Command{
+	Use:   "session",
+	Short: "Manage stored conversation sessions",
+	Long: `The session command and its subcommands help you manage conversations stored with 'llm-cli prompt --session <name>'.
+Sessions are stored in the 'sessions' directory next to the configuration file.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Show help if no subcommand is given
+		_ = cmd.Help()
+	},
+}
+
+// sessionListCmd represents the 'session list' command.
+var sessionListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List stored sessions",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := newSessionStore()
+		if err != nil {
+			return err
+		}
+		infos, err := store.List()
+		if err != nil {
+			return err
+		}
+		if len(infos) == 0 {
+			fmt.Println("No sessions found.")
+			return nil
+		}
+
+		fmt.Println("Available sessions:")
+		for _, info := range infos {
+			fmt.Printf("  %s (turns: %d, updated: %s)\n", info.Name, info.Turns, info.UpdatedAt.Format("2006-01-02 15:04:05"))
+		}
+		return nil
+	},
+}
+
+// sessionShowCmd represents the 'session show' command.
+var sessionShowCmd = &cobra.Command{
+	Use:   "show [session_name]",
+	Short: "Show the transcript of a session",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := loadExistingSession(args[0])
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			fmt.Printf("[%s] %s (%s)\n%s\n", e.Time.Format("2006-01-02 15:04:05"), e.Role, e.Profile, e.Content)
+			if len(e.Parts) > 0 {
+				fmt.Printf("Attachments: %s\n", partNames(e.Parts))
+			}
+			fmt.Println()
+		}
+		return nil
+	},
+}
+
+// sessionRemoveCmd represents the 'session rm' command.
+var sessionRemoveCmd = &cobra.Command{
+	Use:     "rm [session_name]",
+	Aliases: []string{"remove"},
+	Short:   "Remove a session",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := newSessionStore()
+		if err != nil {
+			return err
+		}
+		if err := store.Remove(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Session '%s' removed.\n", args[0])
+		return nil
+	},
+}
+
+// sessionExportCmd represents the 'session export' command.
+var sessionExportCmd = &cobra.Command{
+	Use:   "export [session_name]",
+	Short: "Export a session as JSON, JSONL or Markdown",
+	Long: `Exports a session transcript. The 'json' format produces a {"messages": [...]} document
+that can be read back by other tools, 'jsonl' writes the raw transcript entries and 'markdown' produces a readable transcript.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+
+		entries, err := loadExistingSession(args[0])
+		if err != nil {
+			return err
+		}
+
+		var w io.Writer = os.Stdout
+		if output != "" {
+			file, err := os.OpenFile(output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+			if err != nil {
+				return fmt.Errorf("error creating output file: %w", err)
+			}
+			defer file.Close()
+			w = file
+		}
+		return exportSession(w, entries, format)
+	},
+}
+
+// newSessionStore returns the session store located next to the configuration file.
+func newSessionStore() (*session.Store, error) {
+	configDir, err := config.GetConfigDir(cfgFile)
+	if err != nil {
+		return nil, fmt.Errorf("error determining config directory: %w", err)
+	}
+	return session.NewStore(configDir), nil
+}
+
+// loadExistingSession loads a session and fails if it has not been stored yet.
+func loadExistingSession(name string) ([]session.Entry, error) {
+	store, err := newSessionStore()
+	if err != nil {
+		return nil, err
+	}
+	exists, err := store.Exists(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("session '%s' not found", name)
+	}
+	return store.Load(name)
+}
+
+// exportSession writes the session entries to w in the requested format.
+func exportSession(w io.Writer, entries []session.Entry, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(llm.Request{Messages: session.Messages(entries)})
+	case "jsonl":
+		encoder := json.NewEncoder(w)
+		for _, e := range entries {
+			if err := encoder.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "markdown", "md":
+		var sb strings.Builder
+		for _, e := range entries {
+			fmt.Fprintf(&sb, "## %s\n\n%s\n\n", e.Role, e.Content)
+			if len(e.Parts) > 0 {
+				fmt.Fprintf(&sb, "*Attachments: %s*\n\n", partNames(e.Parts))
+			}
+		}
+		_, err := io.WriteString(w, sb.String())
+		return err
+	default:
+		return fmt.Errorf("unknown export format '%s': must be 'json', 'jsonl' or 'markdown'", format)
+	}
+}
+
+// partNames lists the file names of the images and documents of a message.
+func partNames(parts []llm.Part) string {
+	names := make([]string, len(parts))
+	for i, part := range parts {
+		names[i] = part.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// init function registers the sessionCmd and its subcommands with the rootCmd.
+func init() {
+	rootCmd.AddCommand(sessionCmd)
+	sessionCmd.AddCommand(sessionListCmd)
+	sessionCmd.AddCommand(sessionShowCmd)
+	sessionCmd.AddCommand(sessionRemoveCmd)
+	sessionCmd.AddCommand(sessionExportCmd)
+
+	sessionExportCmd.Flags().String("format", "json", "Export format (json, jsonl or markdown)")
+	sessionExportCmd.Flags().StringP("output", "o", "", "Write the export to a file instead of stdout")
+}
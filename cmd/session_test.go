@@ -0,0 +1,103 @@
+/*
+Copyright © 2025 magifd2
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/magifd2/llm-cli/internal/session"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptCommand_Session(t *testing.T) {
+	_ = setupTestEnvironment(t)
+	addTestProfile(t, "mock_profile", config.Profile{Provider: "mock", Model: "mock"})
+
+	_, _, err := executeCommand(rootCmd, "prompt", "--profile", "mock_profile", "--session", "refine", "first")
+	require.NoError(t, err)
+	_, _, err = executeCommand(rootCmd, "prompt", "--profile", "mock_profile", "--session", "refine", "second")
+	require.NoError(t, err)
+
+	store, err := newSessionStore()
+	require.NoError(t, err)
+	entries, err := store.Load("refine")
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	assert.Equal(t, "first", entries[0].Content)
+	assert.Equal(t, "second", entries[2].Content)
+	assert.Equal(t, "mock_profile", entries[3].Profile)
+	// The second call resent the first turn, so the provider saw three messages.
+	assert.Contains(t, entries[3].Content, "Turns: 3")
+}
+
+func TestPromptCommand_SessionKeepsParts(t *testing.T) {
+	tempDir := setupTestEnvironment(t)
+	addTestProfile(t, "mock_profile", config.Profile{Provider: "mock", Model: "mock"})
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 8)...)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "photo.png"), png, 0600))
+	t.Cleanup(func() {
+		_ = promptCmd.Flags().Lookup("image").Value.(pflag.SliceValue).Replace(nil)
+	})
+
+	_, _, err := executeCommand(rootCmd, "prompt", "--profile", "mock_profile", "--session", "photos", "--image", filepath.Join(tempDir, "photo.png"), "Describe")
+	require.NoError(t, err)
+
+	store, err := newSessionStore()
+	require.NoError(t, err)
+	entries, err := store.Load("photos")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	// The image is stored with the turn, so that later calls resend it with the history.
+	assert.Equal(t, []llm.Part{{Name: "photo.png", MIMEType: "image/png", Data: png}}, entries[0].Parts)
+	assert.Equal(t, entries[0].Parts, session.Messages(entries)[0].Parts)
+	assert.Empty(t, entries[1].Parts)
+}
+
+func TestExportSession(t *testing.T) {
+	entries := []session.Entry{
+		{Role: llm.RoleUser, Content: "q", Time: time.Unix(0, 0).UTC()},
+		{Role: llm.RoleAssistant, Content: "a", Time: time.Unix(0, 0).UTC()},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, exportSession(&buf, entries, "json"))
+	assert.JSONEq(t, `{"messages":[{"role":"user","content":"q"},{"role":"assistant","content":"a"}]}`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, exportSession(&buf, entries, "markdown"))
+	assert.Equal(t, "## user\n\nq\n\n## assistant\n\na\n\n", buf.String())
+
+	entries[0].Parts = []llm.Part{{Name: "photo.png", MIMEType: "image/png"}, {Name: "spec.pdf", MIMEType: "application/pdf"}}
+	buf.Reset()
+	require.NoError(t, exportSession(&buf, entries, "markdown"))
+	assert.Equal(t, "## user\n\nq\n\n*Attachments: photo.png, spec.pdf*\n\n## assistant\n\na\n\n", buf.String())
+
+	assert.Error(t, exportSession(&buf, entries, "xml"))
+}
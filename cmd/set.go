@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/magifd2/llm-cli/internal/config"
 	"github.com/spf13/cobra"
@@ -89,6 +90,26 @@ func setProfileValue(key, value string) error {
 		profile.Location = value
 	case "credentials_file":
 		profile.CredentialsFile = value
+	case "api_version":
+		profile.APIVersion = value
+	case "transport_ca_file":
+		profile.Transport.CAFile = value
+	case "transport_client_cert":
+		profile.Transport.ClientCert = value
+	case "transport_client_key":
+		profile.Transport.ClientKey = value
+	case "transport_insecure_skip_verify":
+		if value == "" {
+			profile.Transport.InsecureSkipVerify = false
+		} else if profile.Transport.InsecureSkipVerify, err = strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("invalid boolean value for transport.insecure_skip_verify: %s", value)
+		}
+	case "transport_proxy":
+		profile.Transport.Proxy = value
+	case "transport_headers":
+		if profile.Transport.Headers, err = parseHeaders(value); err != nil {
+			return err
+		}
 	case "limits_enabled":
 		enabled, err := strconv.ParseBool(value)
 		if err != nil {
@@ -117,10 +138,100 @@ func setProfileValue(key, value string) error {
 			return fmt.Errorf("invalid integer value for limits.max_response_size_bytes: %s", value)
 		}
 		profile.Limits.MaxResponseSizeBytes = size
+	case "generation_temperature":
+		if profile.Generation.Temperature, err = parseOptionalFloat("generation.temperature", value); err != nil {
+			return err
+		}
+	case "generation_top_p":
+		if profile.Generation.TopP, err = parseOptionalFloat("generation.top_p", value); err != nil {
+			return err
+		}
+	case "generation_top_k":
+		if profile.Generation.TopK, err = parseOptionalInt("generation.top_k", value); err != nil {
+			return err
+		}
+	case "generation_max_tokens":
+		if profile.Generation.MaxTokens, err = parseOptionalInt("generation.max_tokens", value); err != nil {
+			return err
+		}
+	case "generation_stop":
+		profile.Generation.Stop = parseStopSequences(value)
+	case "generation_seed":
+		if value == "" {
+			profile.Generation.Seed = nil
+		} else {
+			seed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid integer value for generation.seed: %s", value)
+			}
+			profile.Generation.Seed = &seed
+		}
+	case "generation_candidate_count":
+		if profile.Generation.CandidateCount, err = parseOptionalInt("generation.candidate_count", value); err != nil {
+			return err
+		}
+	case "connect_timeout":
+		if profile.ConnectTimeout, err = parseOptionalDuration("connect_timeout", value); err != nil {
+			return err
+		}
+	case "request_timeout":
+		if profile.RequestTimeout, err = parseOptionalDuration("request_timeout", value); err != nil {
+			return err
+		}
+	case "requests_per_minute":
+		if value == "" {
+			profile.RequestsPerMinute = 0
+		} else if profile.RequestsPerMinute, err = strconv.Atoi(value); err != nil || profile.RequestsPerMinute < 1 {
+			return fmt.Errorf("invalid value for requests_per_minute: must be a positive integer: %s", value)
+		}
+	case "retry_max_attempts":
+		if value == "" {
+			profile.Retry.MaxAttempts = 0
+		} else if profile.Retry.MaxAttempts, err = strconv.Atoi(value); err != nil || profile.Retry.MaxAttempts < 1 {
+			return fmt.Errorf("invalid value for retry.max_attempts: must be a positive integer: %s", value)
+		}
+	case "retry_base_delay":
+		if profile.Retry.BaseDelay, err = parseOptionalDuration("retry.base_delay", value); err != nil {
+			return err
+		}
+	case "retry_max_delay":
+		if profile.Retry.MaxDelay, err = parseOptionalDuration("retry.max_delay", value); err != nil {
+			return err
+		}
+	case "retry_no_jitter":
+		if value == "" {
+			profile.Retry.NoJitter = false
+		} else if profile.Retry.NoJitter, err = strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("invalid boolean value for retry.no_jitter: %s", value)
+		}
+	case "mock_responses_file":
+		profile.Mock.ResponsesFile = value
+	case "mock_latency":
+		if profile.Mock.Latency, err = parseOptionalDuration("mock.latency", value); err != nil {
+			return err
+		}
+	case "mock_chunk_size":
+		if value == "" {
+			profile.Mock.ChunkSize = 0
+		} else if profile.Mock.ChunkSize, err = strconv.Atoi(value); err != nil || profile.Mock.ChunkSize < 1 {
+			return fmt.Errorf("invalid value for mock.chunk_size: must be a positive integer: %s", value)
+		}
+	case "mock_chunk_delay":
+		if profile.Mock.ChunkDelay, err = parseOptionalDuration("mock.chunk_delay", value); err != nil {
+			return err
+		}
+	case "safety_settings":
+		if profile.SafetySettings, err = parseSafetySettings(value); err != nil {
+			return err
+		}
 	default:
 		availableKeys := []string{
-			"model", "provider", "endpoint", "api-key", "aws-region", "aws-access-key-id", "aws-secret-access-key", "project-id", "location", "credentials-file",
+			"model", "provider", "endpoint", "api-key", "aws-region", "aws-access-key-id", "aws-secret-access-key", "project-id", "location", "credentials-file", "api-version",
 			"limits-enabled", "limits-on-input-exceeded", "limits-on-output-exceeded", "limits-max-prompt-size-bytes", "limits-max-response-size-bytes",
+			"generation-temperature", "generation-top-p", "generation-top-k", "generation-max-tokens", "generation-stop", "generation-seed",
+			"generation-candidate-count", "connect-timeout", "request-timeout", "transport-ca-file", "transport-client-cert", "transport-client-key",
+			"transport-insecure-skip-verify", "transport-proxy", "transport-headers", "retry-max-attempts", "retry-base-delay", "retry-max-delay", "retry-no-jitter", "safety-settings",
+			"mock-responses-file", "mock-latency", "mock-chunk-size", "mock-chunk-delay", "requests-per-minute",
 		}
 		return fmt.Errorf("unknown configuration key '%s'.\nAvailable keys: %s", key, strings.Join(availableKeys, ", "))
 	}
@@ -132,6 +243,90 @@ func setProfileValue(key, value string) error {
 	return nil
 }
 
+// parseOptionalFloat parses a floating-point setting. An empty value unsets it.
+func parseOptionalFloat(name, value string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number value for %s: %s", name, value)
+	}
+	return &f, nil
+}
+
+// parseOptionalInt parses an integer setting. An empty value unsets it.
+func parseOptionalInt(name, value string) (*int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid integer value for %s: %s", name, value)
+	}
+	return &i, nil
+}
+
+// parseOptionalDuration parses a duration such as "500ms" or "2s". An empty value unsets it.
+func parseOptionalDuration(name, value string) (config.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration value for %s: %s", name, value)
+	}
+	return config.Duration(d), nil
+}
+
+// parseStopSequences splits a comma-separated list of stop sequences. An empty value unsets them.
+func parseStopSequences(value string) []string {
+	var stop []string
+	for _, seq := range strings.Split(value, ",") {
+		if seq != "" {
+			stop = append(stop, seq)
+		}
+	}
+	return stop
+}
+
+// parseSafetySettings parses a comma-separated list of CATEGORY=THRESHOLD pairs. An empty value unsets them.
+func parseSafetySettings(value string) ([]config.SafetySetting, error) {
+	var settings []config.SafetySetting
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		category, threshold, ok := strings.Cut(pair, "=")
+		if !ok || category == "" || threshold == "" {
+			return nil, fmt.Errorf("invalid safety setting '%s': must be CATEGORY=THRESHOLD", pair)
+		}
+		settings = append(settings, config.SafetySetting{Category: strings.TrimSpace(category), Threshold: strings.TrimSpace(threshold)})
+	}
+	return settings, nil
+}
+
+// parseHeaders parses a comma-separated list of NAME=VALUE pairs of HTTP headers. An empty value removes them.
+func parseHeaders(value string) (map[string]string, error) {
+	var headers map[string]string
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, headerValue, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid header '%s': must be NAME=VALUE", pair)
+		}
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		headers[strings.TrimSpace(name)] = strings.TrimSpace(headerValue)
+	}
+	return headers, nil
+}
+
 // init function registers the setCmd with the profileCmd.
 func init() {
 	profileCmd.AddCommand(setCmd)
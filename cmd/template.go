@@ -0,0 +1,169 @@
+/*
+Copyright © 2025 magifd2
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/templates"
+	"github.com/spf13/cobra"
+)
+
+// templateCmd represents the base command for managing the prompt template library.
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Manage prompt templates",
+	Long: `The template command and its subcommands help you manage the prompt templates used with 'llm-cli prompt --template <name>'.
+Templates are stored as YAML files in the 'templates' directory next to the configuration file.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Show help if no subcommand is given
+		_ = cmd.Help()
+	},
+}
+
+// templateListCmd represents the 'template list' command.
+var templateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the templates in the library",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := newTemplateStore()
+		if err != nil {
+			return err
+		}
+		list, err := store.List()
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			fmt.Println("No templates found.")
+			return nil
+		}
+
+		fmt.Println("Available templates:")
+		for _, t := range list {
+			line := "  " + t.Name
+			if t.Description != "" {
+				line += " - " + t.Description
+			}
+			if t.Profile != "" {
+				line += fmt.Sprintf(" (profile: %s)", t.Profile)
+			}
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+// templateShowCmd represents the 'template show' command.
+var templateShowCmd = &cobra.Command{
+	Use:   "show [template_name]",
+	Short: "Show the source of a template",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := newTemplateStore()
+		if err != nil {
+			return err
+		}
+		data, err := store.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		_, err = cmd.OutOrStdout().Write(data)
+		return err
+	},
+}
+
+// templateAddCmd represents the 'template add' command.
+var templateAddCmd = &cobra.Command{
+	Use:   "add [template_name] [file]",
+	Short: "Add a template to the library from a YAML file",
+	Long: `Copies a template file into the library. Use '-' to read the template from stdin.
+The file is checked before it is added; an existing template is only replaced with --force.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, source := args[0], args[1]
+		var data []byte
+		var err error
+		if source == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(source)
+		}
+		if err != nil {
+			return fmt.Errorf("error reading template file: %w", err)
+		}
+
+		store, err := newTemplateStore()
+		if err != nil {
+			return err
+		}
+		force, _ := cmd.Flags().GetBool("force")
+		if err := store.Save(name, data, force); err != nil {
+			return err
+		}
+		fmt.Printf("Template '%s' added.\n", name)
+		return nil
+	},
+}
+
+// templateRemoveCmd represents the 'template rm' command.
+var templateRemoveCmd = &cobra.Command{
+	Use:     "rm [template_name]",
+	Aliases: []string{"remove"},
+	Short:   "Remove a template",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := newTemplateStore()
+		if err != nil {
+			return err
+		}
+		if err := store.Remove(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Template '%s' removed.\n", args[0])
+		return nil
+	},
+}
+
+// newTemplateStore returns the template library located next to the configuration file.
+func newTemplateStore() (*templates.Store, error) {
+	configDir, err := config.GetConfigDir(cfgFile)
+	if err != nil {
+		return nil, fmt.Errorf("error determining config directory: %w", err)
+	}
+	return templates.NewStore(configDir), nil
+}
+
+// init function registers the templateCmd and its subcommands with the rootCmd.
+func init() {
+	rootCmd.AddCommand(templateCmd)
+	templateCmd.AddCommand(templateListCmd)
+	templateCmd.AddCommand(templateShowCmd)
+	templateCmd.AddCommand(templateAddCmd)
+	templateCmd.AddCommand(templateRemoveCmd)
+
+	templateAddCmd.Flags().Bool("force", false, "Replace an existing template with the same name")
+}
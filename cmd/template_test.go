@@ -0,0 +1,95 @@
+/*
+Copyright © 2025 magifd2
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateCommands(t *testing.T) {
+	tempDir := setupTestEnvironment(t)
+	source := filepath.Join(tempDir, "review.yaml")
+	require.NoError(t, os.WriteFile(source, []byte("description: Review a diff\nuser: Review {{.diff}}\n"), 0600))
+	t.Cleanup(func() { _ = templateAddCmd.Flags().Set("force", "false") })
+
+	_, _, err := executeCommand(rootCmd, "template", "add", "review", source)
+	require.NoError(t, err)
+	_, _, err = executeCommand(rootCmd, "template", "add", "review", source)
+	assert.ErrorContains(t, err, "already exists")
+	_, _, err = executeCommand(rootCmd, "template", "add", "review", source, "--force")
+	require.NoError(t, err)
+
+	stdout, _, err := executeCommand(rootCmd, "template", "show", "review")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "user: Review {{.diff}}")
+
+	_, _, err = executeCommand(rootCmd, "template", "rm", "review")
+	require.NoError(t, err)
+	_, _, err = executeCommand(rootCmd, "template", "show", "review")
+	assert.ErrorContains(t, err, "template 'review' not found")
+}
+
+func TestPromptCommand_Template(t *testing.T) {
+	tempDir := setupTestEnvironment(t)
+	addTestProfile(t, "reviewer", config.Profile{Provider: "mock", Model: "mock-model"})
+	source := filepath.Join(tempDir, "review.yaml")
+	require.NoError(t, os.WriteFile(source, []byte(`
+profile: reviewer
+system: You review {{.lang}} code.
+user: "{{.input}}: {{.diff}}"
+vars:
+  lang: python
+`), 0600))
+	_, _, err := executeCommand(rootCmd, "template", "add", "review", source)
+	require.NoError(t, err)
+	patch := filepath.Join(tempDir, "patch.diff")
+	require.NoError(t, os.WriteFile(patch, []byte("+x := 1"), 0600))
+
+	// Array flags append to their previous values, so they are reset after the test.
+	t.Cleanup(func() {
+		_ = promptCmd.Flags().Set("template", "")
+		_ = promptCmd.Flags().Set("output", outputText)
+		for _, name := range []string{"var", "var-file"} {
+			flag := promptCmd.Flags().Lookup(name)
+			_ = flag.Value.(pflag.SliceValue).Replace(nil)
+			flag.Changed = false
+		}
+	})
+
+	// Flag values persist between tests, so the profile selected by earlier tests is cleared.
+	require.NoError(t, promptCmd.Flags().Set("profile", ""))
+	stdout, _, err := executeCommand(rootCmd, "prompt", "--template", "review", "--var", "lang=go", "--var-file", "diff="+patch, "--output", "json", "Check this")
+	require.NoError(t, err)
+	var envelope map[string]any
+	require.NoError(t, json.Unmarshal([]byte(stdout), &envelope))
+	assert.Equal(t, "reviewer", envelope["profile"], "the template's profile is used")
+	assert.Contains(t, envelope["text"], "System Prompt: You review go code.")
+	assert.Contains(t, envelope["text"], "User Prompt: Check this: +x := 1")
+}
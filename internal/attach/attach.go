@@ -0,0 +1,228 @@
+package attach
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// binarySniffLen is the number of leading bytes searched for a NUL byte to detect binary files, as git does.
+const binarySniffLen = 8000
+
+// File is a file found for the attached paths.
+type File struct {
+	Path    string // The slash-separated path of the file, as given or found below a given directory.
+	Size    int64  // The size of the file in bytes.
+	Content string // The contents of the file, unless it was skipped.
+	Skipped string // Why the file was not attached (e.g., "binary file"), or "" if it was.
+}
+
+// Options controls which files are attached.
+type Options struct {
+	Excludes     []string // gitignore-style patterns of files to leave out of directories and globs.
+	MaxFileBytes int64    // Files are read up to this many bytes. 0 reads them completely.
+}
+
+// collector gathers the files of several paths, attaching each file only once.
+type collector struct {
+	opts     Options
+	excludes ignoreRules
+	files    []File
+	seen     map[string]bool
+}
+
+// Collect finds the files of the given paths. A file is attached as given, a directory is walked recursively
+// and a glob (e.g., "src/**/*.go") attaches the files it matches. In directories and globs, files excluded by
+// a .gitignore file found on the way or by the exclude patterns are left out, as is the .git directory.
+// Binary files are returned with a reason for skipping them.
+func Collect(paths []string, opts Options) ([]File, error) {
+	c := &collector{opts: opts, seen: make(map[string]bool)}
+	for _, pattern := range opts.Excludes {
+		if err := c.excludes.add("", pattern); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range paths {
+		if hasMeta(p) {
+			if err := c.glob(p); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, fmt.Errorf("error attaching '%s': %w", p, err)
+		}
+		if info.IsDir() {
+			err = c.walk(filepath.Clean(p), nil, -1)
+		} else {
+			err = c.add(p)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c.files, nil
+}
+
+// glob attaches the files that match a glob pattern.
+func (c *collector) glob(pattern string) error {
+	pattern = filepath.ToSlash(filepath.Clean(pattern))
+	re, err := regexp.Compile("^" + globToRegexp(pattern) + "$")
+	if err != nil {
+		return fmt.Errorf("invalid attach pattern '%s': %w", pattern, err)
+	}
+
+	// Walk from the longest directory without wildcards. Unless the pattern contains "**",
+	// there is no need to descend deeper than the pattern.
+	segments := strings.Split(pattern, "/")
+	static := 0
+	for static < len(segments)-1 && !hasMeta(segments[static]) {
+		static++
+	}
+	root := strings.Join(segments[:static], "/")
+	if root == "" {
+		root = "."
+		if strings.HasPrefix(pattern, "/") {
+			root = "/"
+		}
+	}
+	maxDepth := -1
+	if !strings.Contains(pattern, "**") {
+		maxDepth = len(segments) - static - 1
+	}
+
+	count := len(c.files)
+	if _, err := os.Stat(filepath.FromSlash(root)); err == nil {
+		if err := c.walk(filepath.FromSlash(root), re.MatchString, maxDepth); err != nil {
+			return err
+		}
+	}
+	if len(c.files) == count {
+		return fmt.Errorf("no files match '%s'", pattern)
+	}
+	return nil
+}
+
+// walk attaches the files below a directory that are not excluded and, if match is set, that it accepts.
+// A maxDepth other than -1 limits how many directory levels below root are entered.
+func (c *collector) walk(root string, match func(string) bool, maxDepth int) error {
+	rules := append(ignoreRules(nil), c.excludes...)
+	slashRoot := filepath.ToSlash(root)
+	return filepath.WalkDir(root, func(fsPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("error attaching '%s': %w", fsPath, err)
+		}
+		p := filepath.ToSlash(fsPath)
+		if d.IsDir() {
+			if p != slashRoot {
+				if d.Name() == ".git" || rules.ignored(p, true) {
+					return filepath.SkipDir
+				}
+				if maxDepth >= 0 && depth(slashRoot, p) > maxDepth {
+					return filepath.SkipDir
+				}
+			}
+			return rules.load(p)
+		}
+		if !d.Type().IsRegular() && d.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+		if rules.ignored(p, false) || (match != nil && !match(p)) {
+			return nil
+		}
+		return c.add(fsPath)
+	})
+}
+
+// add reads a file and attaches it, unless it has been attached already.
+func (c *collector) add(fsPath string) error {
+	p := filepath.ToSlash(filepath.Clean(fsPath))
+	if c.seen[p] {
+		return nil
+	}
+	c.seen[p] = true
+
+	file, err := os.Open(fsPath)
+	if err != nil {
+		return fmt.Errorf("error attaching '%s': %w", p, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("error attaching '%s': %w", p, err)
+	}
+	if !info.Mode().IsRegular() {
+		c.files = append(c.files, File{Path: p, Skipped: "not a regular file"})
+		return nil
+	}
+
+	var r io.Reader = file
+	if c.opts.MaxFileBytes > 0 {
+		r = io.LimitReader(file, c.opts.MaxFileBytes)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("error reading '%s': %w", p, err)
+	}
+	f := File{Path: p, Size: info.Size()}
+	if bytes.IndexByte(data[:min(len(data), binarySniffLen)], 0) >= 0 {
+		f.Skipped = "binary file"
+	} else {
+		f.Content = string(data)
+	}
+	c.files = append(c.files, f)
+	return nil
+}
+
+// Format renders the attached files as blocks labeled with their paths. Each file is put in a Markdown code fence
+// that is longer than any run of backticks in its contents. Skipped files are left out.
+func Format(files []File) string {
+	var sb strings.Builder
+	for _, f := range files {
+		if f.Skipped != "" {
+			continue
+		}
+		fence := strings.Repeat("`", max(3, longestBacktickRun(f.Content)+1))
+		fmt.Fprintf(&sb, "File: %s\n%s%s\n%s", f.Path, fence, strings.TrimPrefix(path.Ext(f.Path), "."), f.Content)
+		if !strings.HasSuffix(f.Content, "\n") {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(fence + "\n\n")
+	}
+	return sb.String()
+}
+
+// longestBacktickRun returns the length of the longest run of backticks in s.
+func longestBacktickRun(s string) int {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return longest
+}
+
+// hasMeta reports whether the path contains glob wildcards.
+func hasMeta(p string) bool {
+	return strings.ContainsAny(p, "*?[")
+}
+
+// depth returns the number of directory levels of p below root.
+func depth(root, p string) int {
+	rel, ok := relative(root, p)
+	if !ok {
+		return 0
+	}
+	return strings.Count(rel, "/") + 1
+}
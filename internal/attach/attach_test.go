@@ -0,0 +1,120 @@
+package attach
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTree creates files below dir and changes into it, so paths are relative as on the command line.
+func writeTree(t *testing.T, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+	t.Chdir(dir)
+}
+
+func paths(files []File) []string {
+	var list []string
+	for _, f := range files {
+		list = append(list, f.Path)
+	}
+	return list
+}
+
+func TestCollect_Directory(t *testing.T) {
+	writeTree(t, map[string]string{
+		"src/.gitignore":        "*.log\nbuild/\n!keep.log\n",
+		"src/main.go":           "package main\n",
+		"src/debug.log":         "noise",
+		"src/keep.log":          "kept",
+		"src/build/out.go":      "generated",
+		"src/lib/lib.go":        "package lib\n",
+		"src/lib/lib_test.go":   "package lib\n",
+		"src/lib/logo.png":      "\x89PNG\x00\x00",
+		"src/.git/config":       "[core]",
+		"src/vendor/dep/dep.go": "package dep\n",
+		"notes.txt":             "notes",
+	})
+
+	files, err := Collect([]string{"src", "notes.txt", "src/main.go"}, Options{Excludes: []string{"*_test.go", "/src/vendor"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"src/.gitignore", "src/keep.log", "src/lib/lib.go", "src/lib/logo.png", "src/main.go", "notes.txt"}, paths(files))
+	assert.Equal(t, "binary file", files[3].Skipped)
+	assert.Empty(t, files[3].Content)
+	assert.Equal(t, int64(13), files[4].Size)
+}
+
+func TestCollect_Glob(t *testing.T) {
+	writeTree(t, map[string]string{
+		"a.go":         "a",
+		"b.txt":        "b",
+		"pkg/c.go":     "c",
+		"pkg/sub/d.go": "d",
+	})
+
+	files, err := Collect([]string{"*.go"}, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.go"}, paths(files))
+
+	files, err = Collect([]string{"**/*.go"}, Options{Excludes: []string{"sub/"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.go", "pkg/c.go"}, paths(files))
+
+	files, err = Collect([]string{"pkg/**/*.go"}, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pkg/c.go", "pkg/sub/d.go"}, paths(files))
+
+	_, err = Collect([]string{"*.rs"}, Options{})
+	assert.ErrorContains(t, err, "no files match '*.rs'")
+	_, err = Collect([]string{"missing.go"}, Options{})
+	assert.Error(t, err)
+}
+
+func TestCollect_MaxFileBytes(t *testing.T) {
+	writeTree(t, map[string]string{"big.txt": "0123456789"})
+
+	files, err := Collect([]string{"big.txt"}, Options{MaxFileBytes: 4})
+	require.NoError(t, err)
+	assert.Equal(t, "0123", files[0].Content)
+	assert.Equal(t, int64(10), files[0].Size, "the size of the whole file is reported")
+}
+
+func TestFormat(t *testing.T) {
+	out := Format([]File{
+		{Path: "main.go", Content: "package main\n"},
+		{Path: "README.md", Content: "Run:\n```sh\nmake\n```"},
+		{Path: "logo.png", Skipped: "binary file"},
+	})
+	assert.Equal(t, "File: main.go\n```go\npackage main\n```\n\n"+
+		"File: README.md\n````md\nRun:\n```sh\nmake\n```\n````\n\n", out)
+}
+
+func TestIgnoreRules(t *testing.T) {
+	var rules ignoreRules
+	for _, pattern := range []string{"# comment", "*.tmp", "/root.txt", "docs/**/draft-?.md", "out/", "!important.tmp"} {
+		require.NoError(t, rules.add("", pattern))
+	}
+	for p, ignored := range map[string]bool{
+		"a.tmp":               true,
+		"x/y/b.tmp":           true,
+		"important.tmp":       false,
+		"root.txt":            true,
+		"x/root.txt":          false,
+		"docs/draft-1.md":     true,
+		"docs/a/b/draft-2.md": true,
+		"docs/draft-10.md":    false,
+		"out":                 true,
+		"x/out":               true,
+	} {
+		assert.Equal(t, ignored, rules.ignored(p, p == "out" || p == "x/out"), p)
+	}
+	assert.False(t, rules.ignored("out", false), "directory patterns do not match files")
+}
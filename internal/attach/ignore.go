@@ -0,0 +1,146 @@
+package attach
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// rule is a single pattern of a .gitignore file or an --exclude flag.
+type rule struct {
+	base    string         // The directory the pattern is relative to, or "" for the current directory.
+	re      *regexp.Regexp // The pattern compiled to a regular expression over slash-separated paths.
+	negate  bool           // The pattern starts with '!' and re-includes matching paths.
+	dirOnly bool           // The pattern ends with '/' and only matches directories.
+}
+
+// ignoreRules is a list of gitignore-style rules. As in git, the last matching rule decides.
+type ignoreRules []rule
+
+// add parses a gitignore-style pattern relative to base. Empty lines and comments are ignored.
+func (rules *ignoreRules) add(base, pattern string) error {
+	pattern = strings.TrimRight(pattern, " \t\r")
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return nil
+	}
+	r := rule{base: base}
+	if strings.HasPrefix(pattern, "!") {
+		r.negate = true
+		pattern = pattern[1:]
+	} else if strings.HasPrefix(pattern, `\`) {
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		r.dirOnly = true
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	// A pattern with a slash other than a trailing one is anchored to its base directory;
+	// otherwise it matches at any depth.
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if pattern == "" {
+		return nil
+	}
+	expr := globToRegexp(pattern)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return fmt.Errorf("invalid exclude pattern '%s': %w", pattern, err)
+	}
+	r.re = re
+	*rules = append(*rules, r)
+	return nil
+}
+
+// load adds the patterns of the .gitignore file in dir, if there is one.
+func (rules *ignoreRules) load(dir string) error {
+	data, err := os.ReadFile(filepath.Join(filepath.FromSlash(dir), ".gitignore"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("error reading .gitignore: %w", err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if err := rules.add(dir, scanner.Text()); err != nil {
+			return fmt.Errorf("%s: %w", path.Join(dir, ".gitignore"), err)
+		}
+	}
+	return nil
+}
+
+// ignored reports whether the slash-separated path is excluded by the rules.
+func (rules ignoreRules) ignored(p string, isDir bool) bool {
+	ignored := false
+	for _, r := range rules {
+		rel, ok := relative(r.base, p)
+		if !ok || (r.dirOnly && !isDir) {
+			continue
+		}
+		if r.re.MatchString(rel) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// relative returns p relative to base, or false if p is not below base.
+func relative(base, p string) (string, bool) {
+	if base == "" || base == "." {
+		return p, true
+	}
+	if base == "/" {
+		return strings.CutPrefix(p, "/")
+	}
+	if rel, ok := strings.CutPrefix(p, base+"/"); ok {
+		return rel, true
+	}
+	return "", false
+}
+
+// globToRegexp converts a glob pattern to a regular expression. '*' and '?' do not match '/',
+// while '**' matches any number of directories.
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			sb.WriteString("(?:/.*)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
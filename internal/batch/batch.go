@@ -0,0 +1,141 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/magifd2/llm-cli/internal/llm"
+)
+
+// Request is a line of a batch input file.
+type Request struct {
+	ID      string            `json:"id"`                // Identifies the request in the output file. Must be unique.
+	System  string            `json:"system,omitempty"`  // The system prompt.
+	User    string            `json:"user,omitempty"`    // The user prompt.
+	Profile string            `json:"profile,omitempty"` // The profile to use instead of the batch's default.
+	Vars    map[string]string `json:"vars,omitempty"`    // Template variables used to render the prompts.
+}
+
+// Result is a line of a batch output file.
+type Result struct {
+	ID           string     `json:"id"`
+	Profile      string     `json:"profile,omitempty"`
+	Model        string     `json:"model,omitempty"`
+	Output       string     `json:"output"`
+	Error        string     `json:"error,omitempty"` // Why the request failed, or "" if it succeeded.
+	Usage        *llm.Usage `json:"usage,omitempty"`
+	FinishReason string     `json:"finish_reason,omitempty"`
+	LatencyMS    int64      `json:"latency_ms"`
+}
+
+// ReadRequests reads a batch input file of one JSON request per line. Blank lines are skipped.
+func ReadRequests(r io.Reader) ([]Request, error) {
+	var requests []Request
+	seen := make(map[string]int)
+	reader := bufio.NewReader(r)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("error reading batch input: %w", err)
+		}
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			var req Request
+			if err := json.Unmarshal(trimmed, &req); err != nil {
+				return nil, fmt.Errorf("error parsing batch input line %d: %w", lineNumber, err)
+			}
+			if req.ID == "" {
+				return nil, fmt.Errorf("batch input line %d has no id", lineNumber)
+			}
+			if first, ok := seen[req.ID]; ok {
+				return nil, fmt.Errorf("batch input line %d repeats the id '%s' of line %d", lineNumber, req.ID, first)
+			}
+			seen[req.ID] = lineNumber
+			requests = append(requests, req)
+		}
+		if errors.Is(err, io.EOF) {
+			return requests, nil
+		}
+	}
+}
+
+// LoadResults reads the results already written to an output file, so that an interrupted batch can be resumed.
+// If an ID appears more than once, its last result is returned. A missing file has no results, and an incomplete
+// last line, as left by a run that was killed while writing, is ignored.
+func LoadResults(path string) (map[string]Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]Result{}, nil
+		}
+		return nil, fmt.Errorf("error reading batch output: %w", err)
+	}
+	results := make(map[string]Result)
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var result Result
+		if err := json.Unmarshal(line, &result); err != nil || result.ID == "" {
+			if i == len(lines)-1 {
+				break
+			}
+			return nil, fmt.Errorf("error parsing batch output line %d: not a result", i+1)
+		}
+		results[result.ID] = result
+	}
+	return results, nil
+}
+
+// OpenOutput opens an output file for appending results, creating it if needed.
+// An incomplete last line, as left by a run that was killed while writing, is removed.
+func OpenOutput(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening batch output: %w", err)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error reading batch output: %w", err)
+	}
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		if err := file.Truncate(int64(bytes.LastIndexByte(data, '\n') + 1)); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("error repairing batch output: %w", err)
+		}
+	}
+	return file, nil
+}
+
+// Writer writes results as JSON lines. Each result is written with a single call, so that a result is either
+// complete or the incomplete last line of the file. It is safe for concurrent use.
+type Writer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriter returns a Writer that writes to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes a result as a line.
+func (w *Writer) Write(result Result) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("error encoding result '%s': %w", result.ID, err)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing batch output: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package batch
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadRequests(t *testing.T) {
+	input := `{"id": "a", "user": "hello"}
+
+{"id": "b", "system": "be brief", "user": "Translate {{.text}}", "profile": "fast", "vars": {"text": "hi"}}`
+	requests, err := ReadRequests(strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, []Request{
+		{ID: "a", User: "hello"},
+		{ID: "b", System: "be brief", User: "Translate {{.text}}", Profile: "fast", Vars: map[string]string{"text": "hi"}},
+	}, requests)
+
+	_, err = ReadRequests(strings.NewReader(`{"id": "a"}` + "\n" + `{"user": "x"}`))
+	assert.ErrorContains(t, err, "line 2 has no id")
+	_, err = ReadRequests(strings.NewReader(`{"id": "a"}` + "\n" + `{"id": "a"}`))
+	assert.ErrorContains(t, err, "line 2 repeats the id 'a' of line 1")
+	_, err = ReadRequests(strings.NewReader(`{"id": `))
+	assert.ErrorContains(t, err, "error parsing batch input line 1")
+}
+
+func TestLoadResults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.jsonl")
+	results, err := LoadResults(path)
+	require.NoError(t, err)
+	assert.Empty(t, results, "a missing file has no results")
+
+	data := `{"id": "a", "output": "", "error": "boom", "latency_ms": 0}
+{"id": "b", "output": "B", "latency_ms": 12}
+{"id": "a", "output": "A", "latency_ms": 34}
+{"id": "c", "outp`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+	results, err = LoadResults(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Result{
+		"a": {ID: "a", Output: "A", LatencyMS: 34},
+		"b": {ID: "b", Output: "B", LatencyMS: 12},
+	}, results, "the last result of an id counts and an incomplete last line is ignored")
+
+	require.NoError(t, os.WriteFile(path, []byte("garbage\n"+`{"id": "a", "output": "A"}`+"\n"), 0600))
+	_, err = LoadResults(path)
+	assert.ErrorContains(t, err, "line 1: not a result")
+}
+
+func TestOpenOutputAndWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"id": "a", "output": "A", "latency_ms": 1}`+"\n"+`{"id": "b", "outp`), 0600))
+
+	file, err := OpenOutput(path)
+	require.NoError(t, err)
+	w := NewWriter(file)
+	require.NoError(t, w.Write(Result{ID: "b", Output: "B", Usage: &llm.Usage{InputTokens: 1, OutputTokens: 2}}))
+	require.NoError(t, file.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	require.Len(t, lines, 2, "the incomplete line is removed")
+	assert.JSONEq(t, `{"id": "b", "output": "B", "usage": {"input_tokens": 1, "output_tokens": 2}, "latency_ms": 0}`, string(lines[1]))
+
+	results, err := LoadResults(path)
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+}
@@ -0,0 +1,158 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/magifd2/llm-cli/internal/llm"
+)
+
+// Limiter spaces out requests so that no more than a number of requests per minute are started.
+// A nil Limiter does not limit. It is safe for concurrent use.
+type Limiter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     time.Time // The earliest time the next request may start.
+}
+
+// NewLimiter returns a Limiter for the given number of requests per minute, or nil if it is not positive.
+func NewLimiter(perMinute int) *Limiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &Limiter{interval: time.Minute / time.Duration(perMinute)}
+}
+
+// Wait blocks until a request may start or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(start))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// LimitedProvider is a provider whose calls wait for a Limiter. Wrapped by a retrying provider, every attempt
+// counts against the rate limit, not only the first.
+type LimitedProvider struct {
+	llm.Provider          // The provider whose calls are limited.
+	Limiter      *Limiter // The limiter every call waits for.
+}
+
+// NewLimitedProvider returns a provider whose calls wait for l. If l is nil, p is returned unchanged.
+func NewLimitedProvider(p llm.Provider, l *Limiter) llm.Provider {
+	if l == nil {
+		return p
+	}
+	return &LimitedProvider{Provider: p, Limiter: l}
+}
+
+// Chat waits for the limiter and sends the request.
+func (p *LimitedProvider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	if err := p.Limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return p.Provider.Chat(ctx, req)
+}
+
+// ChatStream waits for the limiter and streams the response.
+func (p *LimitedProvider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	if err := p.Limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return p.Provider.ChatStream(ctx, req, responseChan)
+}
+
+// Runner runs the requests of a batch with a pool of workers.
+type Runner struct {
+	Workers  int                                           // The number of requests run at the same time. Default: 1.
+	Handle   func(ctx context.Context, req Request) Result // Runs a request. Failures are reported in the result.
+	Progress io.Writer                                     // If set, receives a line for every completed request.
+}
+
+// Summary counts the outcomes of the requests of a batch run.
+type Summary struct {
+	Succeeded int
+	Failed    int
+}
+
+// Run runs the requests and writes each result as soon as it is available, so an interrupted run can be resumed.
+// When ctx is canceled no further requests are started, and the results of the requests it interrupted are not
+// written, so they run again when the batch is resumed. Run returns the first error writing a result.
+func (r *Runner) Run(ctx context.Context, requests []Request, out *Writer) (Summary, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	queue := make(chan Request)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		summary  Summary
+		writeErr error
+	)
+	for range max(r.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for req := range queue {
+				result := r.Handle(ctx, req)
+				if ctx.Err() != nil {
+					continue
+				}
+				mu.Lock()
+				if err := out.Write(result); err != nil {
+					if writeErr == nil {
+						writeErr = err
+					}
+					cancel()
+					mu.Unlock()
+					continue
+				}
+				if result.Error == "" {
+					summary.Succeeded++
+				} else {
+					summary.Failed++
+				}
+				if r.Progress != nil {
+					done := summary.Succeeded + summary.Failed
+					if result.Error == "" {
+						fmt.Fprintf(r.Progress, "[%d/%d] %s: done in %dms\n", done, len(requests), result.ID, result.LatencyMS)
+					} else {
+						fmt.Fprintf(r.Progress, "[%d/%d] %s: failed: %s\n", done, len(requests), result.ID, result.Error)
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, req := range requests {
+		select {
+		case queue <- req:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+	return summary, writeErr
+}
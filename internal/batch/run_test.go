@@ -0,0 +1,82 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_Run(t *testing.T) {
+	var requests []Request
+	for i := range 10 {
+		requests = append(requests, Request{ID: fmt.Sprintf("r%d", i), User: "hi"})
+	}
+	var running, peak atomic.Int32
+	handle := func(ctx context.Context, req Request) Result {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			if p := peak.Load(); n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if req.ID == "r3" {
+			return Result{ID: req.ID, Error: "boom"}
+		}
+		return Result{ID: req.ID, Output: strings.ToUpper(req.User)}
+	}
+
+	var out, progress bytes.Buffer
+	summary, err := (&Runner{Workers: 3, Handle: handle, Progress: &progress}).Run(context.Background(), requests, NewWriter(&out))
+	require.NoError(t, err)
+	assert.Equal(t, Summary{Succeeded: 9, Failed: 1}, summary)
+	assert.LessOrEqual(t, peak.Load(), int32(3))
+	assert.Equal(t, 10, strings.Count(out.String(), "\n"))
+	assert.Contains(t, progress.String(), "r3: failed: boom")
+	assert.Contains(t, progress.String(), "[10/10]")
+}
+
+func TestRunner_RunCanceled(t *testing.T) {
+	requests := []Request{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	ctx, cancel := context.WithCancel(context.Background())
+	handle := func(ctx context.Context, req Request) Result {
+		if req.ID == "b" {
+			cancel()
+			<-ctx.Done()
+			return Result{ID: req.ID, Error: ctx.Err().Error()}
+		}
+		return Result{ID: req.ID}
+	}
+
+	var out bytes.Buffer
+	summary, err := (&Runner{Workers: 1, Handle: handle}).Run(ctx, requests, NewWriter(&out))
+	require.NoError(t, err)
+	assert.Equal(t, Summary{Succeeded: 1}, summary)
+	assert.Equal(t, `{"id":"a","output":"","latency_ms":0}`+"\n", out.String(), "interrupted requests are not written, so they run again on resume")
+}
+
+func TestLimiter(t *testing.T) {
+	assert.Nil(t, NewLimiter(0))
+	assert.NoError(t, (*Limiter)(nil).Wait(context.Background()), "a nil limiter does not limit")
+
+	l := NewLimiter(60000) // One request per millisecond.
+	start := time.Now()
+	for range 5 {
+		require.NoError(t, l.Wait(context.Background()))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 4*time.Millisecond)
+
+	l = NewLimiter(1)
+	require.NoError(t, l.Wait(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, l.Wait(ctx), context.DeadlineExceeded)
+}
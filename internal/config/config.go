@@ -5,6 +5,8 @@ import (
 	"fmt" // Add this line
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 const (
@@ -32,7 +34,35 @@ type Profile struct {
 	ProjectID          string `json:"project_id,omitempty"`      // GCP Project ID for Vertex AI.
 	Location           string `json:"location,omitempty"`        // GCP Location for Vertex AI.
 	CredentialsFile    string `json:"credentials_file,omitempty"` // Path to a credentials file (e.g., service account key for GCP, or AWS credentials JSON).
+	APIVersion         string `json:"api_version,omitempty"`     // API version for Azure OpenAI (e.g., "2024-10-21").
 	Limits             Limits `json:"limits,omitempty"`
+	Generation         Generation `json:"generation,omitempty"` // Generation parameters sent with every request.
+	SafetySettings     []SafetySetting `json:"safety_settings,omitempty"` // Content safety thresholds for Vertex AI and Gemini.
+	Retry              Retry `json:"retry,omitempty"` // Automatic retries of transient provider errors.
+	ConnectTimeout     Duration `json:"connect_timeout,omitempty"` // Limit for establishing a connection, including the TLS handshake. Default: 30s.
+	RequestTimeout     Duration `json:"request_timeout,omitempty"` // Limit for a single request, or for the first token of a stream. Default: none.
+	Transport          Transport `json:"transport,omitempty"` // TLS, proxy and header settings of the HTTP connections to the provider.
+	Mock               Mock `json:"mock,omitempty"` // Behavior of the mock provider, which answers without calling a model.
+	RequestsPerMinute  int `json:"requests_per_minute,omitempty"` // Rate limit of the requests sent by 'batch'. Default: none.
+}
+
+// SafetySetting sets the blocking threshold for one harm category of the Vertex AI and Gemini APIs.
+// The values are the API enum names, e.g., "HARM_CATEGORY_HATE_SPEECH" and "BLOCK_ONLY_HIGH".
+type SafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+// Generation defines the sampling and length parameters sent to the model.
+// Unset (nil or empty) values are omitted from requests, so the provider's own defaults apply.
+type Generation struct {
+	Temperature *float64 `json:"temperature,omitempty"` // Controls the randomness of the output.
+	TopP        *float64 `json:"top_p,omitempty"`       // Nucleus sampling probability mass.
+	TopK        *int     `json:"top_k,omitempty"`       // Number of highest probability tokens considered at each step.
+	MaxTokens   *int     `json:"max_tokens,omitempty"`  // Maximum number of tokens to generate.
+	Stop        []string `json:"stop,omitempty"`        // Sequences that stop generation.
+	Seed        *int64   `json:"seed,omitempty"`        // Seed for deterministic sampling, where supported.
+	CandidateCount *int  `json:"candidate_count,omitempty"` // Number of alternative responses to generate (Vertex AI and Gemini only).
 }
 
 // Limits defines the usage and size limits for a profile.
@@ -44,6 +74,62 @@ type Limits struct {
 	MaxResponseSizeBytes int64  `json:"max_response_size_bytes,omitempty"`
 }
 
+// Transport configures the HTTP connections to the provider's API, e.g., for corporate proxies, private CAs
+// or gateways that require client certificates or extra headers.
+type Transport struct {
+	CAFile             string            `json:"ca_file,omitempty"`              // PEM bundle of CA certificates trusted in addition to the system's.
+	ClientCert         string            `json:"client_cert,omitempty"`          // PEM client certificate for mutual TLS.
+	ClientKey          string            `json:"client_key,omitempty"`           // PEM private key of the client certificate.
+	InsecureSkipVerify bool              `json:"insecure_skip_verify,omitempty"` // Disables verification of the server's certificate. For testing only.
+	Proxy              string            `json:"proxy,omitempty"`                // Proxy URL (http, https or socks5). Default: the HTTP_PROXY and HTTPS_PROXY environment variables.
+	Headers            map[string]string `json:"headers,omitempty"`              // Extra HTTP headers sent with every request.
+}
+
+// Mock configures the mock provider, which answers without calling a model, for testing scripts and pipelines.
+type Mock struct {
+	ResponsesFile string   `json:"responses_file,omitempty"` // YAML file of scripted responses and errors. Default: echo the request.
+	Latency       Duration `json:"latency,omitempty"`        // Delay before the response, or before the first chunk of a stream.
+	ChunkSize     int      `json:"chunk_size,omitempty"`     // Number of characters per streamed chunk. Default: the whole response in one chunk.
+	ChunkDelay    Duration `json:"chunk_delay,omitempty"`    // Delay between streamed chunks.
+}
+
+// Retry configures automatic retries of transient provider errors, such as rate limits, unavailable servers
+// and reset connections. Zero values select the defaults.
+type Retry struct {
+	MaxAttempts int      `json:"max_attempts,omitempty"` // Total number of attempts, including the first. 1 disables retries. Default: 3.
+	BaseDelay   Duration `json:"base_delay,omitempty"`   // Delay before the first retry; it doubles with every further retry. Default: 1s.
+	MaxDelay    Duration `json:"max_delay,omitempty"`    // Upper bound of a single delay, including one requested by a Retry-After header. Default: 30s.
+	NoJitter    bool     `json:"no_jitter,omitempty"`    // Disables the random spread of delays.
+}
+
+// Duration is a time.Duration written to the configuration file as a string such as "1.5s" or "2m".
+// A bare number is read as seconds.
+type Duration time.Duration
+
+// MarshalJSON encodes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON decodes a duration string or a number of seconds.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		*d = Duration(seconds * float64(time.Second))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid duration %s: must be a string such as \"1.5s\" or a number of seconds", string(data))
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
 // Load reads the configuration file from the user's config directory.
 // If the file does not exist, it returns a default configuration.
 func Load(configPath string) (*Config, error) {
@@ -87,17 +173,27 @@ func Load(configPath string) (*Config, error) {
 		return nil, err
 	}
 
-	// Resolve CredentialsFile paths relative to the config file's directory
+	// Resolve the file paths of the profiles relative to the config file's directory
 	configDir := filepath.Dir(actualConfigPath)
 	for name, profile := range cfg.Profiles {
-		if profile.CredentialsFile != "" && !filepath.IsAbs(profile.CredentialsFile) {
-			resolvedPath, err := ResolvePath(filepath.Join(configDir, profile.CredentialsFile))
+		files := []struct {
+			setting string
+			path    *string
+		}{
+			{"credentials file", &profile.CredentialsFile},
+			{"transport.ca_file", &profile.Transport.CAFile},
+			{"transport.client_cert", &profile.Transport.ClientCert},
+			{"transport.client_key", &profile.Transport.ClientKey},
+			{"mock.responses_file", &profile.Mock.ResponsesFile},
+		}
+		for _, file := range files {
+			resolvedPath, err := resolveConfigPath(configDir, *file.path)
 			if err != nil {
-				return nil, fmt.Errorf("failed to resolve credentials file path for profile %s: %w", name, err)
+				return nil, fmt.Errorf("failed to resolve %s path for profile %s: %w", file.setting, name, err)
 			}
-			profile.CredentialsFile = resolvedPath
-			cfg.Profiles[name] = profile // Update the profile in the map
+			*file.path = resolvedPath
 		}
+		cfg.Profiles[name] = profile // Update the profile in the map
 	}
 
 	// Ensure all profiles have default limits if not explicitly set
@@ -154,6 +250,20 @@ func GetConfigPath() (string, error) {
 	return filepath.Join(home, configDir, configFile), nil
 }
 
+// GetConfigDir returns the directory that holds the configuration file.
+// If configPath is empty, the directory of the default configuration file is returned.
+// Other data managed by llm-cli (e.g., sessions) is stored alongside the configuration file.
+func GetConfigDir(configPath string) (string, error) {
+	if configPath != "" {
+		return filepath.Dir(configPath), nil
+	}
+	defaultPath, err := GetConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Dir(defaultPath), nil
+}
+
 // ResolvePath expands the tilde (~) to the user's home directory if present
 // and returns the absolute path.
 func ResolvePath(p string) (string, error) {
@@ -166,3 +276,61 @@ func ResolvePath(p string) (string, error) {
 	}
 	return filepath.Abs(p)
 }
+
+// resolveConfigPath resolves a relative file path of the configuration against the directory of the
+// configuration file. Empty and absolute paths and paths starting with "~" are returned unchanged;
+// the latter are expanded by ResolvePath when the file is used.
+func resolveConfigPath(configDir, p string) (string, error) {
+	if p == "" || filepath.IsAbs(p) || strings.HasPrefix(p, "~") {
+		return p, nil
+	}
+	return ResolvePath(filepath.Join(configDir, p))
+}
+
+// LoadCredentials reads a JSON credentials file, such as the `credentials_file` of a profile, into v.
+func LoadCredentials(path string, v any) error {
+	resolvedPath, err := ResolvePath(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve credentials file path %s: %w", path, err)
+	}
+
+	data, err := os.ReadFile(resolvedPath)
+	if err != nil {
+		return fmt.Errorf("failed to read credentials file %s: %w", resolvedPath, err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to unmarshal credentials from file %s: %w", resolvedPath, err)
+	}
+	return nil
+}
+
+// LoadCredentialField returns the value of a key of a JSON credentials file, e.g., the "openai_api_key" of an API key file.
+// It fails if the key is missing or empty.
+func LoadCredentialField(path, key string) (string, error) {
+	var fields map[string]any
+	if err := LoadCredentials(path, &fields); err != nil {
+		return "", err
+	}
+	value, _ := fields[key].(string)
+	if value == "" {
+		return "", fmt.Errorf("%s is missing in credentials file %s", key, path)
+	}
+	return value, nil
+}
+
+// CheckCredentialsFile checks that a credentials file exists, so that a misconfigured profile is reported by
+// `profile check` rather than by its first request. An empty path is not checked.
+func CheckCredentialsFile(path string) error {
+	if path == "" {
+		return nil
+	}
+	resolvedPath, err := ResolvePath(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve credentials file path %s: %w", path, err)
+	}
+	if _, err := os.Stat(resolvedPath); os.IsNotExist(err) {
+		return fmt.Errorf("credentials file not found at %s", resolvedPath)
+	}
+	return nil
+}
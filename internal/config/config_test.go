@@ -22,8 +22,11 @@ THE SOFTWARE.
 package config
 
 import (
+	"encoding/json"
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -113,3 +116,68 @@ func TestGetConfigPath(t *testing.T) {
 	// Assert that the path is what we expect
 	assert.Equal(t, expectedPath, path)
 }
+
+func TestDuration_JSON(t *testing.T) {
+	data, err := json.Marshal(Retry{BaseDelay: Duration(1500 * time.Millisecond)})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"base_delay": "1.5s"}`, string(data))
+
+	var r Retry
+	require.NoError(t, json.Unmarshal([]byte(`{"base_delay": "500ms", "max_delay": 20}`), &r))
+	assert.Equal(t, Duration(500*time.Millisecond), r.BaseDelay)
+	assert.Equal(t, Duration(20*time.Second), r.MaxDelay, "numbers are seconds")
+
+	assert.Error(t, json.Unmarshal([]byte(`{"base_delay": "soon"}`), &r))
+}
+
+func TestLoad_ResolvesFilePaths(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"current_profile": "gateway",
+		"profiles": {
+			"gateway": {
+				"provider": "openai",
+				"model": "gpt-4o",
+				"credentials_file": "keys/openai.json",
+				"transport": {"ca_file": "certs/ca.pem", "client_cert": "/etc/llm/client.pem", "client_key": "~/certs/client-key.pem"}
+			},
+			"scripted": {"provider": "mock", "model": "mock", "mock": {"responses_file": "mock/responses.yaml"}}
+		}
+	}`), 0600))
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	profile := cfg.Profiles["gateway"]
+	// Relative paths are taken relative to the config file, not to the working directory.
+	assert.Equal(t, filepath.Join(dir, "keys", "openai.json"), profile.CredentialsFile)
+	assert.Equal(t, filepath.Join(dir, "certs", "ca.pem"), profile.Transport.CAFile)
+	assert.Equal(t, "/etc/llm/client.pem", profile.Transport.ClientCert)
+	assert.Equal(t, "~/certs/client-key.pem", profile.Transport.ClientKey, "home paths are expanded when the file is read")
+	assert.Equal(t, filepath.Join(dir, "mock", "responses.yaml"), cfg.Profiles["scripted"].Mock.ResponsesFile)
+}
+
+func TestLoadCredentialField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"openai_api_key": "sk-test", "other": 1}`), 0600))
+
+	key, err := LoadCredentialField(path, "openai_api_key")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-test", key)
+
+	_, err = LoadCredentialField(path, "gemini_api_key")
+	assert.ErrorContains(t, err, "gemini_api_key is missing")
+	_, err = LoadCredentialField(path, "other")
+	assert.ErrorContains(t, err, "other is missing")
+	_, err = LoadCredentialField(filepath.Join(t.TempDir(), "missing.json"), "openai_api_key")
+	assert.ErrorContains(t, err, "failed to read credentials file")
+}
+
+func TestCheckCredentialsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{}`), 0600))
+
+	assert.NoError(t, CheckCredentialsFile(""))
+	assert.NoError(t, CheckCredentialsFile(path))
+	assert.ErrorContains(t, CheckCredentialsFile(filepath.Join(t.TempDir(), "missing.json")), "credentials file not found")
+}
@@ -0,0 +1,140 @@
+// Package httpclient builds the HTTP clients the providers use to reach their APIs.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/magifd2/llm-cli/internal/config"
+)
+
+// DefaultConnectTimeout is the limit for establishing a connection if the profile does not set one.
+const DefaultConnectTimeout = 30 * time.Second
+
+// Configure applies the connection settings of the profile to a transport: the connect timeout, which covers
+// both dialing and the TLS handshake, the trusted CAs, the client certificate and the proxy.
+// Extra headers cannot be set on a transport; they are added by the round tripper of NewTransport.
+func Configure(transport *http.Transport, profile config.Profile) error {
+	connectTimeout := time.Duration(profile.ConnectTimeout)
+	if connectTimeout == 0 {
+		connectTimeout = DefaultConnectTimeout
+	}
+	dialer := &net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
+
+	settings := profile.Transport
+	tlsConfig, err := newTLSConfig(settings)
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	if settings.Proxy != "" {
+		proxyURL, err := url.Parse(settings.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return fmt.Errorf("invalid proxy URL '%s'", settings.Proxy)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return fmt.Errorf("invalid proxy URL '%s': the scheme must be http, https or socks5", settings.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return nil
+}
+
+// newTLSConfig builds the TLS configuration of the transport settings, or returns nil if they need none.
+func newTLSConfig(settings config.Transport) (*tls.Config, error) {
+	if settings.CAFile == "" && settings.ClientCert == "" && settings.ClientKey == "" && !settings.InsecureSkipVerify {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: settings.InsecureSkipVerify}
+
+	if settings.CAFile != "" {
+		path, err := config.ResolvePath(settings.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving ca_file: %w", err)
+		}
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading ca_file: %w", err)
+		}
+		// The bundle extends the system's trusted CAs, so public endpoints keep working behind an inspecting proxy.
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in ca_file %s", path)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if settings.ClientCert != "" || settings.ClientKey != "" {
+		if settings.ClientCert == "" || settings.ClientKey == "" {
+			return nil, fmt.Errorf("client_cert and client_key must be set together")
+		}
+		certPath, err := config.ResolvePath(settings.ClientCert)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving client_cert: %w", err)
+		}
+		keyPath, err := config.ResolvePath(settings.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving client_key: %w", err)
+		}
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// headerTransport adds extra headers to every request before passing it on.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+// RoundTrip sends the request with the extra headers, which replace headers of the same name.
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// NewTransport returns a copy of the default transport with the connection settings of the profile,
+// which also adds the profile's extra headers to every request.
+func NewTransport(profile config.Profile) (http.RoundTripper, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if err := Configure(transport, profile); err != nil {
+		return nil, err
+	}
+	if len(profile.Transport.Headers) == 0 {
+		return transport, nil
+	}
+	return &headerTransport{base: transport, headers: profile.Transport.Headers}, nil
+}
+
+// New returns an HTTP client with the connection settings of the profile.
+// It sets no overall timeout, since a streamed response may take long; requests are bounded by their context.
+func New(profile config.Profile) (*http.Client, error) {
+	transport, err := NewTransport(profile)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring HTTP transport: %w", err)
+	}
+	return &http.Client{Transport: transport}, nil
+}
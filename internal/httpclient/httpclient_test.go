@@ -0,0 +1,130 @@
+package httpclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_Headers(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+	defer server.Close()
+
+	client, err := New(config.Profile{Transport: config.Transport{Headers: map[string]string{"X-Gateway-Token": "secret", "Authorization": "Bearer gateway"}}})
+	require.NoError(t, err)
+	req, err := http.NewRequest("GET", server.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer sk-test")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, "secret", got.Get("X-Gateway-Token"))
+	assert.Equal(t, "Bearer gateway", got.Get("Authorization"), "extra headers replace headers of the same name")
+	assert.Equal(t, "Bearer sk-test", req.Header.Get("Authorization"), "the caller's request is not modified")
+}
+
+func TestNew_CAFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600))
+
+	// The test server's certificate is not trusted by default.
+	client, err := New(config.Profile{})
+	require.NoError(t, err)
+	_, err = client.Get(server.URL)
+	assert.Error(t, err)
+
+	for name, transport := range map[string]config.Transport{
+		"ca file":              {CAFile: caFile},
+		"insecure skip verify": {InsecureSkipVerify: true},
+	} {
+		client, err := New(config.Profile{Transport: transport})
+		require.NoError(t, err, name)
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err, name)
+		resp.Body.Close()
+	}
+}
+
+// writeClientCert writes a self-signed client certificate and its key to dir and returns their paths.
+func writeClientCert(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
+
+func TestNew_ClientCert(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	certFile, keyFile := writeClientCert(t, t.TempDir())
+
+	client, err := New(config.Profile{Transport: config.Transport{InsecureSkipVerify: true}})
+	require.NoError(t, err)
+	_, err = client.Get(server.URL)
+	assert.Error(t, err, "the server requires a client certificate")
+
+	client, err = New(config.Profile{Transport: config.Transport{InsecureSkipVerify: true, ClientCert: certFile, ClientKey: keyFile}})
+	require.NoError(t, err)
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+}
+
+func TestNew_InvalidSettings(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0600))
+
+	for name, transport := range map[string]config.Transport{
+		"missing ca file":      {CAFile: filepath.Join(dir, "missing.pem")},
+		"ca file without PEM":  {CAFile: notPEM},
+		"client cert only":     {ClientCert: filepath.Join(dir, "client.pem")},
+		"missing client cert":  {ClientCert: filepath.Join(dir, "client.pem"), ClientKey: filepath.Join(dir, "client-key.pem")},
+		"proxy without scheme": {Proxy: "proxy.example.com:8080"},
+		"unsupported proxy":    {Proxy: "ftp://proxy.example.com"},
+	} {
+		_, err := New(config.Profile{Transport: transport})
+		assert.Error(t, err, name)
+	}
+
+	_, err := New(config.Profile{Transport: config.Transport{Proxy: "socks5://localhost:1080"}})
+	assert.NoError(t, err)
+}
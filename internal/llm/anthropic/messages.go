@@ -0,0 +1,276 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+)
+
+// defaultMaxTokens is used if the profile does not set max_tokens, which the Messages API requires.
+const defaultMaxTokens = 4096
+
+// contentBlock represents a block of message content: text, an image, a document, a tool call or a tool result.
+type contentBlock struct {
+	Type      string          `json:"type"`                  // "text", "image", "document", "tool_use" or "tool_result".
+	Text      string          `json:"text,omitempty"`        // The text of a "text" block.
+	ID        string          `json:"id,omitempty"`          // The ID of a "tool_use" block.
+	Name      string          `json:"name,omitempty"`        // The tool name of a "tool_use" block.
+	Input     json.RawMessage `json:"input,omitempty"`       // The arguments of a "tool_use" block.
+	ToolUseID string          `json:"tool_use_id,omitempty"` // The call a "tool_result" block answers.
+	Content   string          `json:"content,omitempty"`     // The output of a "tool_result" block.
+	Source    *source         `json:"source,omitempty"`      // The data of an "image" or "document" block.
+}
+
+// source holds the base64-encoded data of an image or a PDF document.
+type source struct {
+	Type      string `json:"type"` // Always "base64".
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// toContentBlock converts an image or a PDF document to a content block.
+func toContentBlock(p llm.Part) (contentBlock, error) {
+	blockType := "image"
+	switch p.MIMEType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+	case "application/pdf":
+		blockType = "document"
+	default:
+		return contentBlock{}, llm.UnsupportedPartError("the anthropic messages API", p)
+	}
+	return contentBlock{Type: blockType, Source: &source{Type: "base64", MediaType: p.MIMEType, Data: p.Base64()}}, nil
+}
+
+// message represents a message in the Messages API format.
+type message struct {
+	Role    string         `json:"role"` // "user" or "assistant".
+	Content []contentBlock `json:"content"`
+}
+
+// tool represents a function the model may call.
+type tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"` // A JSON schema of the arguments object.
+}
+
+// MessagesRequest represents the request body of the Messages API.
+// Claude models on Bedrock take the same body, with an anthropic_version instead of the model and stream fields.
+type MessagesRequest struct {
+	Model            string    `json:"model,omitempty"`
+	AnthropicVersion string    `json:"anthropic_version,omitempty"` // Only set for Bedrock, which selects the model and streaming by API operation.
+	MaxTokens        int       `json:"max_tokens"`
+	System           string    `json:"system,omitempty"`
+	Messages         []message `json:"messages"`
+	Tools            []tool    `json:"tools,omitempty"`
+	Stream           bool      `json:"stream,omitempty"`
+	Temperature      *float64  `json:"temperature,omitempty"`
+	TopP             *float64  `json:"top_p,omitempty"`
+	TopK             *int      `json:"top_k,omitempty"`
+	StopSequences    []string  `json:"stop_sequences,omitempty"`
+}
+
+// usage represents the token usage reported by the Messages API.
+type usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// messagesResponse represents a non-streaming response of the Messages API.
+type messagesResponse struct {
+	Model      string         `json:"model"`
+	Content    []contentBlock `json:"content"`
+	StopReason string         `json:"stop_reason"` // e.g., "end_turn", "max_tokens", "stop_sequence", "tool_use".
+	Usage      usage          `json:"usage"`
+}
+
+// streamEvent represents the data of a server-sent event of a streaming response.
+// Only the fields of the event types that are used are declared.
+type streamEvent struct {
+	Type    string `json:"type"` // e.g., "message_start", "content_block_start", "content_block_delta", "message_delta", "error".
+	Message struct {
+		Model string `json:"model"`
+		Usage usage  `json:"usage"`
+	} `json:"message"` // Set for "message_start".
+	Index        int          `json:"index"`         // The content block index, for content block events.
+	ContentBlock contentBlock `json:"content_block"` // Set for "content_block_start".
+	Delta        struct {
+		Type        string `json:"type"`         // "text_delta" or "input_json_delta" for content block deltas.
+		Text        string `json:"text"`         // Set for "text_delta".
+		PartialJSON string `json:"partial_json"` // A fragment of the tool input, for "input_json_delta".
+		StopReason  string `json:"stop_reason"`  // Set for "message_delta".
+	} `json:"delta"`
+	Usage usage         `json:"usage"` // The output token count, for "message_delta".
+	Error *errorDetails `json:"error"` // Set for "error".
+}
+
+// errorDetails holds the type and message of an API error.
+type errorDetails struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// NewMessagesRequest converts a request into the Messages API format, without the model and stream fields.
+// Tool results are sent as "tool_result" blocks of a user message; consecutive results share one message.
+// The Messages API has no native structured output, so a requested JSON schema is added to the system prompt.
+// Images and documents are sent ahead of the text of their message. The API rejects empty text blocks,
+// so text is only sent when there is some, and a message without any content is an error.
+func NewMessagesRequest(req llm.Request, gen config.Generation) (MessagesRequest, error) {
+	body := MessagesRequest{
+		MaxTokens:     defaultMaxTokens,
+		System:        req.SystemPrompt(),
+		Temperature:   gen.Temperature,
+		TopP:          gen.TopP,
+		TopK:          gen.TopK,
+		StopSequences: gen.Stop,
+	}
+	if gen.MaxTokens != nil {
+		body.MaxTokens = *gen.MaxTokens
+	}
+	if len(req.Schema) > 0 {
+		if body.System != "" {
+			body.System += "\n\n"
+		}
+		body.System += req.SchemaInstruction()
+	}
+
+	for i, m := range req.Conversation() {
+		if m.Role == llm.RoleTool {
+			block := contentBlock{Type: "tool_result", ToolUseID: m.ToolCallID, Content: m.Content}
+			if n := len(body.Messages); n > 0 && body.Messages[n-1].Content[0].Type == "tool_result" {
+				body.Messages[n-1].Content = append(body.Messages[n-1].Content, block)
+			} else {
+				body.Messages = append(body.Messages, message{Role: llm.RoleUser, Content: []contentBlock{block}})
+			}
+			continue
+		}
+
+		var content []contentBlock
+		for _, part := range m.Parts {
+			block, err := toContentBlock(part)
+			if err != nil {
+				return MessagesRequest{}, err
+			}
+			content = append(content, block)
+		}
+		if m.Content != "" {
+			content = append(content, contentBlock{Type: "text", Text: m.Content})
+		}
+		for _, tc := range m.ToolCalls {
+			content = append(content, contentBlock{Type: "tool_use", ID: tc.ID, Name: tc.Name, Input: tc.Arguments})
+		}
+		if len(content) == 0 {
+			return MessagesRequest{}, fmt.Errorf("message %d (%s) has no content", i+1, m.Role)
+		}
+		body.Messages = append(body.Messages, message{Role: m.Role, Content: content})
+	}
+
+	for _, t := range req.Tools {
+		schema := t.Parameters
+		if len(schema) == 0 {
+			schema = json.RawMessage(`{"type":"object"}`)
+		}
+		body.Tools = append(body.Tools, tool{Name: t.Name, Description: t.Description, InputSchema: schema})
+	}
+	return body, nil
+}
+
+// DecodeResponse reads a non-streaming Messages API response body and extracts its text, tool calls and metadata.
+func DecodeResponse(r io.Reader) (*llm.Response, error) {
+	var msgResp messagesResponse
+	if err := json.NewDecoder(r).Decode(&msgResp); err != nil {
+		return nil, fmt.Errorf("error decoding anthropic response: %w", err)
+	}
+
+	result := &llm.Response{
+		Model:        msgResp.Model,
+		FinishReason: msgResp.StopReason,
+		Usage:        &llm.Usage{InputTokens: msgResp.Usage.InputTokens, OutputTokens: msgResp.Usage.OutputTokens},
+	}
+	var text strings.Builder
+	for _, block := range msgResp.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			result.ToolCalls = append(result.ToolCalls, llm.ToolCall{ID: block.ID, Name: block.Name, Arguments: llm.ToolArguments(block.Input)})
+		}
+	}
+	result.Text = text.String()
+	return result, nil
+}
+
+// StreamAccumulator handles the events of a streaming Messages API response, whatever their transport:
+// it sends the text deltas to a channel and collects the model, token usage, finish reason and tool calls.
+type StreamAccumulator struct {
+	result       llm.Response
+	toolUses     map[int]*contentBlock // Tool calls being assembled, by content block index.
+	toolUseOrder []int
+}
+
+// NewStreamAccumulator returns an accumulator for a new stream.
+func NewStreamAccumulator() *StreamAccumulator {
+	return &StreamAccumulator{result: llm.Response{Usage: &llm.Usage{}}, toolUses: make(map[int]*contentBlock)}
+}
+
+// HandleEvent handles the JSON data of a stream event, sending its text, if any, to responseChan.
+// It returns an error for "error" events, or if ctx is done while sending.
+func (a *StreamAccumulator) HandleEvent(ctx context.Context, data []byte, responseChan chan<- string) error {
+	var event streamEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return fmt.Errorf("error decoding anthropic stream event: %w", err)
+	}
+
+	switch event.Type {
+	case "message_start":
+		if event.Message.Model != "" {
+			a.result.Model = event.Message.Model
+		}
+		a.result.Usage.InputTokens = event.Message.Usage.InputTokens
+	case "content_block_start":
+		if event.ContentBlock.Type == "tool_use" {
+			block := event.ContentBlock
+			block.Input = nil
+			a.toolUses[event.Index] = &block
+			a.toolUseOrder = append(a.toolUseOrder, event.Index)
+		}
+	case "content_block_delta":
+		switch event.Delta.Type {
+		case "text_delta":
+			select {
+			case responseChan <- event.Delta.Text:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		case "input_json_delta":
+			if block, ok := a.toolUses[event.Index]; ok {
+				block.Input = append(block.Input, event.Delta.PartialJSON...)
+			}
+		}
+	case "message_delta":
+		a.result.FinishReason = event.Delta.StopReason
+		a.result.Usage.OutputTokens = event.Usage.OutputTokens
+	case "error":
+		if event.Error != nil {
+			return fmt.Errorf("anthropic streaming error: %s: %s", event.Error.Type, event.Error.Message)
+		}
+		return fmt.Errorf("anthropic streaming error: %s", data)
+	}
+	return nil
+}
+
+// Response returns the metadata and tool calls collected from the events handled so far.
+func (a *StreamAccumulator) Response() *llm.Response {
+	result := a.result
+	result.ToolCalls = nil
+	for _, index := range a.toolUseOrder {
+		block := a.toolUses[index]
+		result.ToolCalls = append(result.ToolCalls, llm.ToolCall{ID: block.ID, Name: block.Name, Arguments: llm.ToolArguments(block.Input)})
+	}
+	return &result
+}
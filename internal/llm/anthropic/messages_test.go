@@ -0,0 +1,29 @@
+package anthropic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeResponse(t *testing.T) {
+	resp, err := DecodeResponse(strings.NewReader(`{
+		"model": "claude-3-haiku-20240307",
+		"content": [
+			{"type": "text", "text": "Checking."},
+			{"type": "tool_use", "id": "toolu_1", "name": "weather", "input": {"city": "Tokyo"}}
+		],
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens": 20, "output_tokens": 9}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, "Checking.", resp.Text)
+	assert.Equal(t, "claude-3-haiku-20240307", resp.Model)
+	assert.Equal(t, "tool_use", resp.FinishReason)
+	assert.Equal(t, &llm.Usage{InputTokens: 20, OutputTokens: 9}, resp.Usage)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.JSONEq(t, `{"city":"Tokyo"}`, string(resp.ToolCalls[0].Arguments))
+}
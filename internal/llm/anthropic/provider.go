@@ -0,0 +1,169 @@
+package anthropic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/httpclient"
+	"github.com/magifd2/llm-cli/internal/llm"
+)
+
+const (
+	defaultEndpoint = "https://api.anthropic.com/v1/messages" // The Messages API endpoint.
+	apiVersion      = "2023-06-01"                            // The value of the anthropic-version header.
+)
+
+// Provider implements the llm.Provider interface for the Anthropic Messages API.
+type Provider struct {
+	Profile config.Profile // The configuration profile for this Anthropic instance.
+}
+
+// errorResponse represents an error returned by the Messages API.
+type errorResponse struct {
+	Error *errorDetails `json:"error"`
+}
+
+// post sends the request body to the Messages API and returns the response if its status is 200 OK.
+func (p *Provider) post(ctx context.Context, body MessagesRequest) (*http.Response, error) {
+	endpoint := p.Profile.Endpoint
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	apiKey, err := p.getAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", apiKey)
+	httpReq.Header.Set("anthropic-version", apiVersion)
+
+	client, err := httpclient.New(p.Profile)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("error making request to anthropic: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		var errResp errorResponse
+		if err := json.Unmarshal(data, &errResp); err == nil && errResp.Error != nil {
+			return nil, llm.NewStatusError(resp, fmt.Sprintf("anthropic API request failed with status %d: %s: %s", resp.StatusCode, errResp.Error.Type, errResp.Error.Message))
+		}
+		return nil, llm.NewStatusError(resp, fmt.Sprintf("anthropic API request failed with status %d: %s", resp.StatusCode, string(data)))
+	}
+	return resp, nil
+}
+
+// Chat sends a chat request to the Messages API and returns a single, complete response.
+func (p *Provider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	body, err := p.buildRequest(req, false)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.post(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return DecodeResponse(resp.Body)
+}
+
+// ChatStream sends a streaming chat request to the Messages API and sends the text deltas to a channel.
+func (p *Provider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	// Note: The caller is responsible for closing the responseChan.
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	body, err := p.buildRequest(req, true)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.post(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Each event is an "event:" line followed by a "data:" line. The data repeats the event type, so only it is read.
+	events := NewStreamAccumulator()
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		if err := events.HandleEvent(ctx, []byte(strings.TrimSpace(data)), responseChan); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading stream: %w", err)
+	}
+	return events.Response(), nil
+}
+
+// buildRequest converts a request into the Messages API request body of the profile's model.
+func (p *Provider) buildRequest(req llm.Request, stream bool) (MessagesRequest, error) {
+	body, err := NewMessagesRequest(req, p.Profile.Generation)
+	if err != nil {
+		return MessagesRequest{}, err
+	}
+	body.Model = p.Profile.Model
+	body.Stream = stream
+	return body, nil
+}
+
+// getAPIKey retrieves the API key from the profile or a credentials file.
+func (p *Provider) getAPIKey() (string, error) {
+	if p.Profile.CredentialsFile != "" {
+		return config.LoadCredentialField(p.Profile.CredentialsFile, "anthropic_api_key")
+	}
+	return p.Profile.APIKey, nil
+}
+
+// NewProvider is a factory function that returns a new Anthropic provider.
+func NewProvider(p config.Profile) (llm.Provider, error) {
+	return &Provider{Profile: p}, nil
+}
+
+// ValidateConfig checks if the Anthropic provider's configuration is valid.
+// It requires a model and either an API key or a credentials file.
+func (p *Provider) ValidateConfig() error {
+	if p.Profile.Model == "" {
+		return fmt.Errorf("Anthropic provider requires a 'model' to be specified in the profile")
+	}
+	if p.Profile.APIKey == "" && p.Profile.CredentialsFile == "" {
+		return fmt.Errorf("Anthropic provider requires either 'api-key' or 'credentials-file' to be set in the profile")
+	}
+
+	return config.CheckCredentialsFile(p.Profile.CredentialsFile)
+}
@@ -0,0 +1,220 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_Chat(t *testing.T) {
+	var got MessagesRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test-key", r.Header.Get("x-api-key"))
+		assert.Equal(t, apiVersion, r.Header.Get("anthropic-version"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		fmt.Fprint(w, `{
+			"model": "claude-test-20250101",
+			"content": [
+				{"type": "text", "text": "Let me check."},
+				{"type": "tool_use", "id": "toolu_1", "name": "weather", "input": {"city": "Tokyo"}}
+			],
+			"stop_reason": "tool_use",
+			"usage": {"input_tokens": 12, "output_tokens": 7}
+		}`)
+	}))
+	defer server.Close()
+
+	maxTokens := 256
+	p := &Provider{Profile: config.Profile{
+		Model:      "claude-test",
+		Endpoint:   server.URL,
+		APIKey:     "test-key",
+		Generation: config.Generation{MaxTokens: &maxTokens, Stop: []string{"END"}},
+	}}
+	req := llm.NewRequest("be brief", "weather?")
+	req.Tools = []llm.Tool{{Name: "weather", Description: "Get the weather", Parameters: json.RawMessage(`{"type":"object"}`)}}
+
+	resp, err := p.Chat(context.Background(), req)
+	require.NoError(t, err)
+
+	assert.Equal(t, "claude-test", got.Model)
+	assert.Equal(t, 256, got.MaxTokens)
+	assert.Equal(t, "be brief", got.System)
+	assert.Equal(t, []string{"END"}, got.StopSequences)
+	require.Len(t, got.Messages, 1)
+	assert.Equal(t, []contentBlock{{Type: "text", Text: "weather?"}}, got.Messages[0].Content)
+	require.Len(t, got.Tools, 1)
+	assert.Equal(t, "weather", got.Tools[0].Name)
+
+	assert.Equal(t, "Let me check.", resp.Text)
+	assert.Equal(t, "claude-test-20250101", resp.Model)
+	assert.Equal(t, "tool_use", resp.FinishReason)
+	assert.Equal(t, &llm.Usage{InputTokens: 12, OutputTokens: 7}, resp.Usage)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "toolu_1", resp.ToolCalls[0].ID)
+	assert.JSONEq(t, `{"city":"Tokyo"}`, string(resp.ToolCalls[0].Arguments))
+}
+
+func TestProvider_ChatStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var got MessagesRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		assert.True(t, got.Stream)
+		assert.Equal(t, defaultMaxTokens, got.MaxTokens)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{
+			`{"type":"message_start","message":{"model":"claude-test-20250101","usage":{"input_tokens":10,"output_tokens":1}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"ping"}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":", world"}}`,
+			`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"weather","input":{}}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Tokyo\"}"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":15}}`,
+			`{"type":"message_stop"}`,
+		} {
+			var typed struct{ Type string }
+			require.NoError(t, json.Unmarshal([]byte(event), &typed))
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typed.Type, event)
+		}
+	}))
+	defer server.Close()
+
+	p := &Provider{Profile: config.Profile{Model: "claude-test", Endpoint: server.URL, APIKey: "test-key"}}
+	responseChan := make(chan string, 10)
+	resp, err := p.ChatStream(context.Background(), llm.NewRequest("", "hi"), responseChan)
+	require.NoError(t, err)
+	close(responseChan)
+
+	var text string
+	for chunk := range responseChan {
+		text += chunk
+	}
+	assert.Equal(t, "Hello, world", text)
+	assert.Equal(t, "claude-test-20250101", resp.Model)
+	assert.Equal(t, "tool_use", resp.FinishReason)
+	assert.Equal(t, &llm.Usage{InputTokens: 10, OutputTokens: 15}, resp.Usage)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "weather", resp.ToolCalls[0].Name)
+	assert.JSONEq(t, `{"city":"Tokyo"}`, string(resp.ToolCalls[0].Arguments))
+}
+
+func TestProvider_ChatStream_ErrorEvent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+	}))
+	defer server.Close()
+
+	p := &Provider{Profile: config.Profile{Model: "claude-test", Endpoint: server.URL, APIKey: "test-key"}}
+	_, err := p.ChatStream(context.Background(), llm.NewRequest("", "hi"), make(chan string, 1))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "overloaded_error: Overloaded")
+}
+
+func TestProvider_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`)
+	}))
+	defer server.Close()
+
+	p := &Provider{Profile: config.Profile{Model: "claude-test", Endpoint: server.URL, APIKey: "bad"}}
+	_, err := p.Chat(context.Background(), llm.NewRequest("", "hi"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 401: authentication_error: invalid x-api-key")
+}
+
+func TestProvider_BuildRequest_ToolRound(t *testing.T) {
+	p := &Provider{Profile: config.Profile{Model: "claude-test"}}
+	req := llm.Request{
+		Messages: []llm.Message{
+			{Role: llm.RoleUser, Content: "weather in Tokyo and Paris?"},
+			{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{
+				{ID: "a", Name: "weather", Arguments: json.RawMessage(`{"city":"Tokyo"}`)},
+				{ID: "b", Name: "weather", Arguments: json.RawMessage(`{"city":"Paris"}`)},
+			}},
+			{Role: llm.RoleTool, ToolCallID: "a", Name: "weather", Content: "sunny"},
+			{Role: llm.RoleTool, ToolCallID: "b", Name: "weather", Content: "rainy"},
+		},
+		Schema: json.RawMessage(`{"type":"object"}`),
+	}
+
+	body, err := p.buildRequest(req, false)
+	require.NoError(t, err)
+	require.Len(t, body.Messages, 3)
+	assert.Len(t, body.Messages[1].Content, 2)
+	assert.Equal(t, "tool_use", body.Messages[1].Content[0].Type)
+	assert.Equal(t, llm.RoleUser, body.Messages[2].Role)
+	assert.Equal(t, []contentBlock{
+		{Type: "tool_result", ToolUseID: "a", Content: "sunny"},
+		{Type: "tool_result", ToolUseID: "b", Content: "rainy"},
+	}, body.Messages[2].Content)
+	assert.Contains(t, body.System, `{"type":"object"}`)
+}
+
+func TestProvider_CredentialsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anthropic.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"anthropic_api_key":"from-file"}`), 0600))
+
+	p := &Provider{Profile: config.Profile{Model: "claude-test", CredentialsFile: path}}
+	require.NoError(t, p.ValidateConfig())
+	key, err := p.getAPIKey()
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", key)
+
+	assert.Error(t, (&Provider{Profile: config.Profile{Model: "claude-test"}}).ValidateConfig())
+	assert.Error(t, (&Provider{Profile: config.Profile{APIKey: "k"}}).ValidateConfig())
+	assert.Error(t, (&Provider{Profile: config.Profile{Model: "claude-test", CredentialsFile: filepath.Join(t.TempDir(), "missing.json")}}).ValidateConfig())
+}
+
+func TestProvider_BuildRequest_Parts(t *testing.T) {
+	p := &Provider{Profile: config.Profile{Model: "claude-test"}}
+	req := llm.NewRequest("", "compare them")
+	req.Messages[0].Parts = []llm.Part{
+		{Name: "chart.png", MIMEType: "image/png", Data: []byte("png")},
+		{Name: "report.pdf", MIMEType: "application/pdf", Data: []byte("pdf")},
+	}
+
+	body, err := p.buildRequest(req, false)
+	require.NoError(t, err)
+	require.Len(t, body.Messages, 1)
+	assert.Equal(t, []contentBlock{
+		{Type: "image", Source: &source{Type: "base64", MediaType: "image/png", Data: "cG5n"}},
+		{Type: "document", Source: &source{Type: "base64", MediaType: "application/pdf", Data: "cGRm"}},
+		{Type: "text", Text: "compare them"},
+	}, body.Messages[0].Content)
+
+	// An image without text is sent without an (invalid) empty text block.
+	req = llm.NewRequest("", "")
+	req.Messages[0].Parts = []llm.Part{{Name: "chart.png", MIMEType: "image/png", Data: []byte("png")}}
+	body, err = p.buildRequest(req, false)
+	require.NoError(t, err)
+	assert.Equal(t, []contentBlock{{Type: "image", Source: &source{Type: "base64", MediaType: "image/png", Data: "cG5n"}}}, body.Messages[0].Content)
+
+	req.Messages[0].Parts = []llm.Part{{Name: "data.csv", MIMEType: "text/csv"}}
+	_, err = p.buildRequest(req, false)
+	assert.Error(t, err)
+}
+
+func TestProvider_BuildRequest_EmptyMessage(t *testing.T) {
+	p := &Provider{Profile: config.Profile{Model: "claude-test"}}
+	req := llm.Request{Messages: []llm.Message{
+		{Role: llm.RoleUser, Content: "hi"},
+		{Role: llm.RoleAssistant, Content: ""},
+		{Role: llm.RoleUser, Content: "again"},
+	}}
+	_, err := p.buildRequest(req, false)
+	assert.ErrorContains(t, err, "message 2 (assistant) has no content")
+}
@@ -0,0 +1,144 @@
+package azureopenai
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/httpclient"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/magifd2/llm-cli/internal/llm/oaicompat"
+)
+
+// defaultAPIVersion is the Azure OpenAI data-plane API version used when the profile does not set one.
+const defaultAPIVersion = "2024-10-21"
+
+// Provider implements the llm.Provider interface for Azure OpenAI.
+// Azure routes requests by deployment instead of by model, so the profile's model is the deployment name.
+// The request and response bodies are those of the OpenAI Chat Completions API, implemented by the oaicompat package.
+type Provider struct {
+	Profile config.Profile // The configuration profile for this Azure OpenAI instance.
+}
+
+// credentials holds what authenticates a request.
+// Azure accepts either a resource API key in the `api-key` header or a Microsoft Entra ID access token as a bearer token.
+type credentials struct {
+	apiKey      string
+	accessToken string
+}
+
+// auth returns the authentication of the credentials.
+func (c credentials) auth() oaicompat.Auth {
+	if c.accessToken != "" {
+		return oaicompat.BearerAuth(c.accessToken)
+	}
+	return oaicompat.HeaderAuth("api-key", c.apiKey)
+}
+
+// chatCompletionsURL builds the URL of the chat completions operation of the profile's deployment,
+// i.e. `{endpoint}/openai/deployments/{deployment}/chat/completions?api-version=...`.
+func (p *Provider) chatCompletionsURL() (string, error) {
+	endpoint := strings.TrimRight(p.Profile.Endpoint, "/")
+	if endpoint == "" {
+		return "", fmt.Errorf("azureopenai provider requires an 'endpoint' such as https://<resource>.openai.azure.com")
+	}
+	apiVersion := p.Profile.APIVersion
+	if apiVersion == "" {
+		apiVersion = defaultAPIVersion
+	}
+	return fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+		endpoint, url.PathEscape(p.Profile.Model), url.QueryEscape(apiVersion)), nil
+}
+
+// newClient returns an OpenAI-compatible client for the chat completions operation of the profile's deployment.
+func (p *Provider) newClient() (*oaicompat.Client, error) {
+	endpoint, err := p.chatCompletionsURL()
+	if err != nil {
+		return nil, err
+	}
+	creds, err := p.getCredentials()
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := httpclient.New(p.Profile)
+	if err != nil {
+		return nil, err
+	}
+	return &oaicompat.Client{Endpoint: endpoint, Auth: creds.auth(), HTTPClient: httpClient}, nil
+}
+
+// Chat sends a chat request to the Azure OpenAI deployment and returns a single, complete response.
+// The request carries no model, since the deployment is part of the URL.
+func (p *Provider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	client, err := p.newClient()
+	if err != nil {
+		return nil, err
+	}
+	return client.Chat(ctx, "", req, p.Profile.Generation)
+}
+
+// ChatStream sends a streaming chat request to the Azure OpenAI deployment and sends response chunks to a channel.
+func (p *Provider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	client, err := p.newClient()
+	if err != nil {
+		return nil, err
+	}
+	return client.ChatStream(ctx, "", req, p.Profile.Generation, responseChan)
+}
+
+// azureCredentialsFile represents the structure of the Azure OpenAI credentials JSON file.
+// It holds either a resource API key or an Entra ID access token; the output of
+// `az account get-access-token --resource https://cognitiveservices.azure.com` can be used as is.
+type azureCredentialsFile struct {
+	AzureOpenAIAPIKey string `json:"azure_openai_api_key"`
+	AccessToken       string `json:"accessToken"`
+}
+
+// getCredentials retrieves the credentials from the profile or a credentials file.
+func (p *Provider) getCredentials() (credentials, error) {
+	if p.Profile.CredentialsFile != "" {
+		return loadAzureCredentialsFromFile(p.Profile.CredentialsFile)
+	}
+	if p.Profile.APIKey == "" {
+		return credentials{}, fmt.Errorf("azureopenai provider requires either 'api-key' or 'credentials-file' to be set in the profile")
+	}
+	return credentials{apiKey: p.Profile.APIKey}, nil
+}
+
+// loadAzureCredentialsFromFile loads an API key or an access token from a specified JSON file.
+// An access token takes precedence if both are present.
+func loadAzureCredentialsFromFile(filePath string) (credentials, error) {
+	var file azureCredentialsFile
+	if err := config.LoadCredentials(filePath, &file); err != nil {
+		return credentials{}, err
+	}
+
+	if file.AccessToken == "" && file.AzureOpenAIAPIKey == "" {
+		return credentials{}, fmt.Errorf("neither accessToken nor azure_openai_api_key is present in credentials file %s", filePath)
+	}
+
+	return credentials{apiKey: file.AzureOpenAIAPIKey, accessToken: file.AccessToken}, nil
+}
+
+// NewProvider is a factory function that returns a new Azure OpenAI provider.
+func NewProvider(p config.Profile) (llm.Provider, error) {
+	return &Provider{Profile: p}, nil
+}
+
+// ValidateConfig checks if the Azure OpenAI provider's configuration is valid.
+// It requires an endpoint, a deployment (the model) and either an API key or a credentials file.
+func (p *Provider) ValidateConfig() error {
+	if p.Profile.Endpoint == "" {
+		return fmt.Errorf("Azure OpenAI provider requires an 'endpoint' to be specified in the profile")
+	}
+	if p.Profile.Model == "" {
+		return fmt.Errorf("Azure OpenAI provider requires a 'model' (the deployment name) to be specified in the profile")
+	}
+	if p.Profile.APIKey == "" && p.Profile.CredentialsFile == "" {
+		return fmt.Errorf("Azure OpenAI provider requires either 'api-key' or 'credentials-file' to be set in the profile")
+	}
+
+	return config.CheckCredentialsFile(p.Profile.CredentialsFile)
+}
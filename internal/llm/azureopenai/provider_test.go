@@ -0,0 +1,127 @@
+package azureopenai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_Chat(t *testing.T) {
+	var gotPath, gotVersion, gotKey, gotAuth string
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotVersion = r.URL.Query().Get("api-version")
+		gotKey = r.Header.Get("api-key")
+		gotAuth = r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{
+			"model": "gpt-4o-2024-08-06",
+			"choices": [{"message": {"role": "assistant", "content": "Hello"}, "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 5, "completion_tokens": 1, "total_tokens": 6}
+		}`)
+	}))
+	defer server.Close()
+
+	p := &Provider{Profile: config.Profile{Model: "my-deployment", Endpoint: server.URL + "/", APIKey: "test-key"}}
+	resp, err := p.Chat(context.Background(), llm.NewRequest("be brief", "hi"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "/openai/deployments/my-deployment/chat/completions", gotPath)
+	assert.Equal(t, defaultAPIVersion, gotVersion)
+	assert.Equal(t, "test-key", gotKey)
+	assert.Empty(t, gotAuth, "the api-key must not be sent as a bearer token")
+	assert.Len(t, got["messages"], 2)
+
+	assert.Equal(t, "Hello", resp.Text)
+	assert.Equal(t, "gpt-4o-2024-08-06", resp.Model)
+	assert.Equal(t, "stop", resp.FinishReason)
+	assert.Equal(t, &llm.Usage{InputTokens: 5, OutputTokens: 1}, resp.Usage)
+}
+
+func TestProvider_ChatStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "2025-01-01-preview", r.URL.Query().Get("api-version"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		// Azure sends a first chunk without choices that carries the prompt filter results.
+		fmt.Fprint(w, "data: {\"choices\":[],\"prompt_filter_results\":[{\"prompt_index\":0}]}\n\n")
+		fmt.Fprint(w, "data: {\"model\":\"gpt-4o\",\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"model\":\"gpt-4o\",\"choices\":[{\"delta\":{\"content\":\"lo\"},\"finish_reason\":\"stop\"}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	p := &Provider{Profile: config.Profile{Model: "my-deployment", Endpoint: server.URL, APIKey: "test-key", APIVersion: "2025-01-01-preview"}}
+	responseChan := make(chan string, 10)
+	resp, err := p.ChatStream(context.Background(), llm.NewRequest("", "hi"), responseChan)
+	require.NoError(t, err)
+	close(responseChan)
+
+	var text string
+	for chunk := range responseChan {
+		text += chunk
+	}
+	assert.Equal(t, "Hello", text)
+	assert.Equal(t, "gpt-4o", resp.Model)
+	assert.Equal(t, "stop", resp.FinishReason)
+}
+
+func TestProvider_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":{"code":"DeploymentNotFound","message":"The API deployment for this resource does not exist."}}`)
+	}))
+	defer server.Close()
+
+	p := &Provider{Profile: config.Profile{Model: "missing", Endpoint: server.URL, APIKey: "test-key"}}
+	_, err := p.Chat(context.Background(), llm.NewRequest("", "hi"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 404")
+	assert.Contains(t, err.Error(), "DeploymentNotFound")
+}
+
+func TestProvider_CredentialsFile(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token.json")
+	require.NoError(t, os.WriteFile(tokenFile, []byte(`{"accessToken": "entra-token", "expiresOn": "2026-01-01 00:00:00.000000"}`), 0600))
+	keyFile := filepath.Join(dir, "key.json")
+	require.NoError(t, os.WriteFile(keyFile, []byte(`{"azure_openai_api_key": "file-key"}`), 0600))
+
+	var gotKey, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.Header.Get("api-key")
+		gotAuth = r.Header.Get("Authorization")
+		fmt.Fprint(w, `{"choices": [{"message": {"content": "ok"}}]}`)
+	}))
+	defer server.Close()
+
+	p := &Provider{Profile: config.Profile{Model: "my-deployment", Endpoint: server.URL, CredentialsFile: tokenFile}}
+	_, err := p.Chat(context.Background(), llm.NewRequest("", "hi"))
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer entra-token", gotAuth)
+	assert.Empty(t, gotKey)
+
+	p.Profile.CredentialsFile = keyFile
+	_, err = p.Chat(context.Background(), llm.NewRequest("", "hi"))
+	require.NoError(t, err)
+	assert.Equal(t, "file-key", gotKey)
+	assert.Empty(t, gotAuth)
+}
+
+func TestProvider_ValidateConfig(t *testing.T) {
+	assert.Error(t, (&Provider{Profile: config.Profile{Model: "d", APIKey: "k"}}).ValidateConfig())
+	assert.Error(t, (&Provider{Profile: config.Profile{Endpoint: "https://example.openai.azure.com", APIKey: "k"}}).ValidateConfig())
+	assert.Error(t, (&Provider{Profile: config.Profile{Endpoint: "https://example.openai.azure.com", Model: "d"}}).ValidateConfig())
+	assert.NoError(t, (&Provider{Profile: config.Profile{Endpoint: "https://example.openai.azure.com", Model: "d", APIKey: "k"}}).ValidateConfig())
+}
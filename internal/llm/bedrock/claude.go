@@ -0,0 +1,86 @@
+package bedrock
+
+import (
+	"bytes"
+	"context"
+
+	appconfig "github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/magifd2/llm-cli/internal/llm/anthropic"
+)
+
+// claudeAnthropicVersion is the anthropic_version that Bedrock requires in Claude request bodies.
+const claudeAnthropicVersion = "bedrock-2023-05-31"
+
+// ClaudeProvider implements the llm.Provider interface for Anthropic Claude models on Bedrock.
+// The request and response bodies follow the Anthropic Messages API and are built and read by the anthropic package.
+type ClaudeProvider struct {
+	Profile appconfig.Profile // The configuration profile for this Bedrock instance.
+}
+
+// newClaudeRequest converts a request into the Claude request body: a Messages API body whose model
+// and stream fields are replaced by the anthropic_version, as Bedrock selects them by API operation.
+func newClaudeRequest(req llm.Request, gen appconfig.Generation) (anthropic.MessagesRequest, error) {
+	body, err := anthropic.NewMessagesRequest(req, gen)
+	if err != nil {
+		return anthropic.MessagesRequest{}, err
+	}
+	body.AnthropicVersion = claudeAnthropicVersion
+	return body, nil
+}
+
+// Chat sends a chat request to a Claude model on Bedrock and returns a single, complete response.
+func (p *ClaudeProvider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	body, err := newClaudeRequest(req, p.Profile.Generation)
+	if err != nil {
+		return nil, err
+	}
+	data, err := invokeModel(ctx, p.Profile, body)
+	if err != nil {
+		return nil, err
+	}
+	result, err := anthropic.DecodeResponse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if result.Model == "" {
+		result.Model = p.Profile.Model
+	}
+	return result, nil
+}
+
+// ChatStream sends a streaming chat request to a Claude model on Bedrock and sends the text deltas to a channel.
+// Each chunk of the Bedrock stream is one Messages API stream event.
+func (p *ClaudeProvider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	// Note: The caller is responsible for closing the responseChan.
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	body, err := newClaudeRequest(req, p.Profile.Generation)
+	if err != nil {
+		return nil, err
+	}
+	events := anthropic.NewStreamAccumulator()
+	err = invokeModelStream(ctx, p.Profile, body, func(data []byte) error {
+		return events.HandleEvent(ctx, data, responseChan)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := events.Response()
+	if result.Model == "" {
+		result.Model = p.Profile.Model
+	}
+	return result, nil
+}
+
+// ValidateConfig checks if the Bedrock provider's configuration is valid.
+func (p *ClaudeProvider) ValidateConfig() error {
+	return validateConfig(p.Profile)
+}
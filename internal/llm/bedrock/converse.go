@@ -0,0 +1,307 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	appconfig "github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+)
+
+// ConverseProvider implements the llm.Provider interface with the Bedrock Converse and ConverseStream operations.
+// Unlike the InvokeModel providers, it does not depend on a model-specific body format, so it works with any
+// Bedrock model that supports the Converse API.
+type ConverseProvider struct {
+	Profile appconfig.Profile // The configuration profile for this Bedrock instance.
+}
+
+// converseRequest holds the parts of a Converse request that are shared by Converse and ConverseStream.
+type converseRequest struct {
+	messages        []types.Message
+	system          []types.SystemContentBlock
+	inferenceConfig *types.InferenceConfiguration
+	toolConfig      *types.ToolConfiguration
+}
+
+// newConverseRequest converts a request into the Converse format.
+// Tool results are sent as toolResult blocks of a user message; consecutive results share one message.
+// Converse has no model-agnostic structured output, so a requested JSON schema is added to the system prompt.
+func newConverseRequest(req llm.Request, gen appconfig.Generation) (*converseRequest, error) {
+	cr := &converseRequest{inferenceConfig: newInferenceConfiguration(gen)}
+
+	if systemPrompt := req.SystemPrompt(); systemPrompt != "" {
+		cr.system = append(cr.system, &types.SystemContentBlockMemberText{Value: systemPrompt})
+	}
+	if len(req.Schema) > 0 {
+		cr.system = append(cr.system, &types.SystemContentBlockMemberText{Value: req.SchemaInstruction()})
+	}
+
+	documentNames := make(map[string]bool)
+	for _, m := range req.Conversation() {
+		if m.Role == llm.RoleTool {
+			block := &types.ContentBlockMemberToolResult{Value: types.ToolResultBlock{
+				ToolUseId: aws.String(m.ToolCallID),
+				Content:   []types.ToolResultContentBlock{&types.ToolResultContentBlockMemberText{Value: m.Content}},
+			}}
+			if n := len(cr.messages); n > 0 && isToolResultMessage(cr.messages[n-1]) {
+				cr.messages[n-1].Content = append(cr.messages[n-1].Content, block)
+			} else {
+				cr.messages = append(cr.messages, types.Message{Role: types.ConversationRoleUser, Content: []types.ContentBlock{block}})
+			}
+			continue
+		}
+
+		var content []types.ContentBlock
+		for _, part := range m.Parts {
+			format, image, err := partFormat("converse", part)
+			if err != nil {
+				return nil, err
+			}
+			if image {
+				content = append(content, &types.ContentBlockMemberImage{Value: types.ImageBlock{
+					Format: types.ImageFormat(format),
+					Source: &types.ImageSourceMemberBytes{Value: part.Data},
+				}})
+			} else {
+				content = append(content, &types.ContentBlockMemberDocument{Value: types.DocumentBlock{
+					Format: types.DocumentFormat(format),
+					Name:   aws.String(documentName(part, documentNames)),
+					Source: &types.DocumentSourceMemberBytes{Value: part.Data},
+				}})
+			}
+		}
+		if m.Content != "" || len(m.ToolCalls) == 0 {
+			content = append(content, &types.ContentBlockMemberText{Value: m.Content})
+		}
+		for _, tc := range m.ToolCalls {
+			input, err := newDocument(tc.Arguments)
+			if err != nil {
+				return nil, fmt.Errorf("invalid arguments of tool call '%s': %w", tc.Name, err)
+			}
+			content = append(content, &types.ContentBlockMemberToolUse{Value: types.ToolUseBlock{
+				ToolUseId: aws.String(tc.ID),
+				Name:      aws.String(tc.Name),
+				Input:     input,
+			}})
+		}
+		cr.messages = append(cr.messages, types.Message{Role: types.ConversationRole(m.Role), Content: content})
+	}
+
+	if len(req.Tools) > 0 {
+		cr.toolConfig = &types.ToolConfiguration{}
+		for _, t := range req.Tools {
+			schema, err := newDocument(t.Parameters)
+			if err != nil {
+				return nil, fmt.Errorf("invalid parameters schema of tool '%s': %w", t.Name, err)
+			}
+			spec := types.ToolSpecification{
+				Name:        aws.String(t.Name),
+				InputSchema: &types.ToolInputSchemaMemberJson{Value: schema},
+			}
+			if t.Description != "" {
+				spec.Description = aws.String(t.Description)
+			}
+			cr.toolConfig.Tools = append(cr.toolConfig.Tools, &types.ToolMemberToolSpec{Value: spec})
+		}
+	}
+	return cr, nil
+}
+
+// newInferenceConfiguration maps the profile's generation parameters to the Converse inference configuration.
+// Converse has no model-agnostic top_k or seed, so they are ignored. It returns nil if nothing is set.
+func newInferenceConfiguration(gen appconfig.Generation) *types.InferenceConfiguration {
+	if gen.MaxTokens == nil && gen.Temperature == nil && gen.TopP == nil && len(gen.Stop) == 0 {
+		return nil
+	}
+	cfg := &types.InferenceConfiguration{StopSequences: gen.Stop}
+	if gen.MaxTokens != nil {
+		cfg.MaxTokens = aws.Int32(int32(*gen.MaxTokens))
+	}
+	if gen.Temperature != nil {
+		cfg.Temperature = aws.Float32(float32(*gen.Temperature))
+	}
+	if gen.TopP != nil {
+		cfg.TopP = aws.Float32(float32(*gen.TopP))
+	}
+	return cfg
+}
+
+// isToolResultMessage reports whether a message holds tool results.
+func isToolResultMessage(m types.Message) bool {
+	if m.Role != types.ConversationRoleUser || len(m.Content) == 0 {
+		return false
+	}
+	_, ok := m.Content[0].(*types.ContentBlockMemberToolResult)
+	return ok
+}
+
+// newDocument converts a JSON value into a document. An empty value becomes an empty object.
+func newDocument(data json.RawMessage) (document.Interface, error) {
+	var v any = map[string]any{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+	}
+	return document.NewLazyDocument(v), nil
+}
+
+// converseUsage converts the token usage of a Converse response. It returns nil if no usage was reported.
+func converseUsage(u *types.TokenUsage) *llm.Usage {
+	if u == nil {
+		return nil
+	}
+	return &llm.Usage{InputTokens: int(aws.ToInt32(u.InputTokens)), OutputTokens: int(aws.ToInt32(u.OutputTokens))}
+}
+
+// Chat sends a chat request with the Converse operation and returns a single, complete response.
+func (p *ConverseProvider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	cr, err := newConverseRequest(req, p.Profile.Generation)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newBedrockClient(ctx, p.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := client.Converse(ctx, &bedrockruntime.ConverseInput{
+		ModelId:         aws.String(p.Profile.Model),
+		Messages:        cr.messages,
+		System:          cr.system,
+		InferenceConfig: cr.inferenceConfig,
+		ToolConfig:      cr.toolConfig,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to call converse: %w", err)
+	}
+
+	message, ok := output.Output.(*types.ConverseOutputMemberMessage)
+	if !ok {
+		return nil, fmt.Errorf("no message found in converse response")
+	}
+
+	result := &llm.Response{
+		Model:        p.Profile.Model,
+		FinishReason: string(output.StopReason),
+		Usage:        converseUsage(output.Usage),
+	}
+	var text strings.Builder
+	for _, block := range message.Value.Content {
+		switch b := block.(type) {
+		case *types.ContentBlockMemberText:
+			text.WriteString(b.Value)
+		case *types.ContentBlockMemberToolUse:
+			var args json.RawMessage
+			if b.Value.Input != nil {
+				if args, err = b.Value.Input.MarshalSmithyDocument(); err != nil {
+					return nil, fmt.Errorf("error decoding tool call input: %w", err)
+				}
+			}
+			result.ToolCalls = append(result.ToolCalls, llm.ToolCall{
+				ID:        aws.ToString(b.Value.ToolUseId),
+				Name:      aws.ToString(b.Value.Name),
+				Arguments: llm.ToolArguments(args),
+			})
+		}
+	}
+	result.Text = text.String()
+	return result, nil
+}
+
+// ChatStream sends a chat request with the ConverseStream operation and sends the text deltas to a channel.
+func (p *ConverseProvider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	// Note: The caller is responsible for closing the responseChan.
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	cr, err := newConverseRequest(req, p.Profile.Generation)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newBedrockClient(ctx, p.Profile)
+	if err != nil {
+		return nil, fmt.Errorf("error creating bedrock client: %w", err)
+	}
+
+	output, err := client.ConverseStream(ctx, &bedrockruntime.ConverseStreamInput{
+		ModelId:         aws.String(p.Profile.Model),
+		Messages:        cr.messages,
+		System:          cr.system,
+		InferenceConfig: cr.inferenceConfig,
+		ToolConfig:      cr.toolConfig,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to call converse stream: %w", err)
+	}
+
+	result := &llm.Response{Model: p.Profile.Model}
+	toolUses := make(map[int32]*llm.ToolCall) // Tool calls being assembled, by content block index.
+	var toolUseOrder []int32
+
+	stream := output.GetStream()
+	defer stream.Close()
+	for event := range stream.Events() {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		switch e := event.(type) {
+		case *types.ConverseStreamOutputMemberContentBlockStart:
+			if start, ok := e.Value.Start.(*types.ContentBlockStartMemberToolUse); ok {
+				index := aws.ToInt32(e.Value.ContentBlockIndex)
+				toolUses[index] = &llm.ToolCall{ID: aws.ToString(start.Value.ToolUseId), Name: aws.ToString(start.Value.Name)}
+				toolUseOrder = append(toolUseOrder, index)
+			}
+		case *types.ConverseStreamOutputMemberContentBlockDelta:
+			switch delta := e.Value.Delta.(type) {
+			case *types.ContentBlockDeltaMemberText:
+				if delta.Value != "" {
+					responseChan <- delta.Value
+				}
+			case *types.ContentBlockDeltaMemberToolUse:
+				if tc, ok := toolUses[aws.ToInt32(e.Value.ContentBlockIndex)]; ok {
+					tc.Arguments = append(tc.Arguments, aws.ToString(delta.Value.Input)...)
+				}
+			}
+		case *types.ConverseStreamOutputMemberMessageStop:
+			result.FinishReason = string(e.Value.StopReason)
+		case *types.ConverseStreamOutputMemberMetadata:
+			result.Usage = converseUsage(e.Value.Usage)
+		}
+	}
+
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("streaming error: %w", err)
+	}
+
+	for _, index := range toolUseOrder {
+		tc := toolUses[index]
+		tc.Arguments = llm.ToolArguments(tc.Arguments)
+		result.ToolCalls = append(result.ToolCalls, *tc)
+	}
+	return result, nil
+}
+
+// NewConverseProvider is a factory function that returns a new Bedrock provider that uses the Converse API.
+func NewConverseProvider(p appconfig.Profile) (llm.Provider, error) {
+	return &ConverseProvider{Profile: p}, nil
+}
+
+// ValidateConfig checks if the Bedrock provider's configuration is valid.
+func (p *ConverseProvider) ValidateConfig() error {
+	return validateConfig(p.Profile)
+}
@@ -0,0 +1,162 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	appconfig "github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newConverseTestProfile returns a profile that sends requests to a local stand-in for Bedrock.
+func newConverseTestProfile(t *testing.T, endpoint string) appconfig.Profile {
+	t.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+	return appconfig.Profile{
+		Provider:           "bedrock-converse",
+		Model:              "cohere.command-r-v1:0",
+		Endpoint:           endpoint,
+		AWSRegion:          "us-east-1",
+		AWSAccessKeyID:     "test",
+		AWSSecretAccessKey: "test",
+	}
+}
+
+func TestConverseProvider_Chat(t *testing.T) {
+	var gotPath string
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{
+			"output": {"message": {"role": "assistant", "content": [
+				{"text": "Checking."},
+				{"toolUse": {"toolUseId": "t1", "name": "weather", "input": {"city": "Tokyo"}}}
+			]}},
+			"stopReason": "tool_use",
+			"usage": {"inputTokens": 11, "outputTokens": 4, "totalTokens": 15},
+			"metrics": {"latencyMs": 10}
+		}`)
+	}))
+	defer server.Close()
+
+	maxTokens := 128
+	profile := newConverseTestProfile(t, server.URL)
+	profile.Generation = appconfig.Generation{MaxTokens: &maxTokens}
+	p := &ConverseProvider{Profile: profile}
+
+	req := llm.NewRequest("be brief", "weather?")
+	req.Tools = []llm.Tool{{Name: "weather", Description: "Get the weather", Parameters: json.RawMessage(`{"type":"object"}`)}}
+	resp, err := p.Chat(context.Background(), req)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasSuffix(gotPath, "/converse"), gotPath)
+	assert.Equal(t, []any{map[string]any{"text": "be brief"}}, got["system"])
+	assert.Equal(t, map[string]any{"maxTokens": float64(128)}, got["inferenceConfig"])
+	assert.Len(t, got["messages"], 1)
+	assert.NotNil(t, got["toolConfig"])
+
+	assert.Equal(t, "Checking.", resp.Text)
+	assert.Equal(t, "tool_use", resp.FinishReason)
+	assert.Equal(t, &llm.Usage{InputTokens: 11, OutputTokens: 4}, resp.Usage)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "t1", resp.ToolCalls[0].ID)
+	assert.JSONEq(t, `{"city":"Tokyo"}`, string(resp.ToolCalls[0].Arguments))
+}
+
+func TestConverseProvider_ChatStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasSuffix(r.URL.EscapedPath(), "/converse-stream"), r.URL.EscapedPath())
+		w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+
+		encoder := eventstream.NewEncoder()
+		for _, event := range []struct{ eventType, payload string }{
+			{"messageStart", `{"role":"assistant"}`},
+			{"contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"Hello"}}`},
+			{"contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":", world"}}`},
+			{"contentBlockStop", `{"contentBlockIndex":0}`},
+			{"contentBlockStart", `{"contentBlockIndex":1,"start":{"toolUse":{"toolUseId":"t1","name":"weather"}}}`},
+			{"contentBlockDelta", `{"contentBlockIndex":1,"delta":{"toolUse":{"input":"{\"city\":"}}}`},
+			{"contentBlockDelta", `{"contentBlockIndex":1,"delta":{"toolUse":{"input":"\"Tokyo\"}"}}}`},
+			{"messageStop", `{"stopReason":"tool_use"}`},
+			{"metadata", `{"usage":{"inputTokens":7,"outputTokens":5,"totalTokens":12},"metrics":{"latencyMs":10}}`},
+		} {
+			msg := eventstream.Message{Payload: []byte(event.payload)}
+			msg.Headers.Set(":message-type", eventstream.StringValue("event"))
+			msg.Headers.Set(":event-type", eventstream.StringValue(event.eventType))
+			msg.Headers.Set(":content-type", eventstream.StringValue("application/json"))
+			require.NoError(t, encoder.Encode(w, msg))
+		}
+	}))
+	defer server.Close()
+
+	p := &ConverseProvider{Profile: newConverseTestProfile(t, server.URL)}
+	responseChan := make(chan string, 10)
+	resp, err := p.ChatStream(context.Background(), llm.NewRequest("", "hi"), responseChan)
+	require.NoError(t, err)
+	close(responseChan)
+
+	var text string
+	for chunk := range responseChan {
+		text += chunk
+	}
+	assert.Equal(t, "Hello, world", text)
+	assert.Equal(t, "tool_use", resp.FinishReason)
+	assert.Equal(t, &llm.Usage{InputTokens: 7, OutputTokens: 5}, resp.Usage)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "weather", resp.ToolCalls[0].Name)
+	assert.JSONEq(t, `{"city":"Tokyo"}`, string(resp.ToolCalls[0].Arguments))
+}
+
+func TestNewConverseRequest_ToolRound(t *testing.T) {
+	req := llm.Request{
+		Messages: []llm.Message{
+			{Role: llm.RoleUser, Content: "weather?"},
+			{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{ID: "t1", Name: "weather", Arguments: json.RawMessage(`{"city":"Tokyo"}`)}}},
+			{Role: llm.RoleTool, ToolCallID: "t1", Name: "weather", Content: "sunny"},
+		},
+		Schema: json.RawMessage(`{"type":"object"}`),
+	}
+
+	cr, err := newConverseRequest(req, appconfig.Generation{})
+	require.NoError(t, err)
+	require.Len(t, cr.messages, 3)
+	assert.True(t, isToolResultMessage(cr.messages[2]))
+	assert.Nil(t, cr.inferenceConfig)
+	require.Len(t, cr.system, 1)
+
+	req.Messages[1].ToolCalls[0].Arguments = json.RawMessage(`{not json`)
+	_, err = newConverseRequest(req, appconfig.Generation{})
+	assert.Error(t, err)
+}
+
+func TestNewConverseRequest_Parts(t *testing.T) {
+	req := llm.NewRequest("", "summarize")
+	req.Messages[0].Parts = []llm.Part{
+		{Name: "chart.png", MIMEType: "image/png", Data: []byte("png")},
+		{Name: "notes.md", MIMEType: "text/markdown", Data: []byte("# notes")},
+	}
+
+	cr, err := newConverseRequest(req, appconfig.Generation{})
+	require.NoError(t, err)
+	require.Len(t, cr.messages, 1)
+	content := cr.messages[0].Content
+	require.Len(t, content, 3)
+	image := content[0].(*types.ContentBlockMemberImage).Value
+	assert.Equal(t, types.ImageFormatPng, image.Format)
+	assert.Equal(t, []byte("png"), image.Source.(*types.ImageSourceMemberBytes).Value)
+	document := content[1].(*types.ContentBlockMemberDocument).Value
+	assert.Equal(t, types.DocumentFormatMd, document.Format)
+	assert.Equal(t, "notes", *document.Name)
+	assert.Equal(t, "summarize", content[2].(*types.ContentBlockMemberText).Value)
+}
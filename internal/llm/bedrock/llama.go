@@ -0,0 +1,142 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	appconfig "github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+)
+
+// LlamaProvider implements the llm.Provider interface for Meta Llama models (Llama 3 and later) on Bedrock.
+// Llama takes a single prompt string, so the conversation is rendered with the Llama 3 chat template.
+type LlamaProvider struct {
+	Profile appconfig.Profile // The configuration profile for this Bedrock instance.
+}
+
+// llamaRequest represents the request body for Llama models.
+// Llama does not support top_k, stop sequences or a seed, so they are ignored.
+type llamaRequest struct {
+	Prompt      string   `json:"prompt"`
+	MaxGenLen   *int     `json:"max_gen_len,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+}
+
+// llamaResponse represents a response, or a chunk of a streaming response, of a Llama model.
+type llamaResponse struct {
+	Generation           string             `json:"generation"`                       // The generated text (a fragment of it when streaming).
+	PromptTokenCount     int                `json:"prompt_token_count"`               // Number of input tokens.
+	GenerationTokenCount int                `json:"generation_token_count"`           // Number of output tokens.
+	StopReason           string             `json:"stop_reason"`                      // "stop" or "length"; empty until the last chunk.
+	Metrics              *invocationMetrics `json:"amazon-bedrock-invocationMetrics"` // Set on the last chunk of a stream.
+}
+
+// buildLlamaPrompt renders the conversation with the Llama 3 chat template, ending with an open assistant turn.
+func buildLlamaPrompt(req llm.Request) string {
+	var sb strings.Builder
+	writeTurn := func(role, content string) {
+		fmt.Fprintf(&sb, "<|start_header_id|>%s<|end_header_id|>\n\n%s<|eot_id|>", role, content)
+	}
+
+	sb.WriteString("<|begin_of_text|>")
+	system := req.SystemPrompt()
+	if len(req.Schema) > 0 {
+		system = strings.TrimSpace(system + "\n\n" + req.SchemaInstruction())
+	}
+	if system != "" {
+		writeTurn(llm.RoleSystem, system)
+	}
+	for _, m := range req.Conversation() {
+		writeTurn(m.Role, m.Content)
+	}
+	sb.WriteString("<|start_header_id|>assistant<|end_header_id|>\n\n")
+	return sb.String()
+}
+
+// newLlamaRequest converts a request into the Llama request body.
+func newLlamaRequest(req llm.Request, gen appconfig.Generation) llamaRequest {
+	return llamaRequest{
+		Prompt:      buildLlamaPrompt(req),
+		MaxGenLen:   gen.MaxTokens,
+		Temperature: gen.Temperature,
+		TopP:        gen.TopP,
+	}
+}
+
+// Chat sends a chat request to a Llama model on Bedrock and returns a single, complete response.
+func (p *LlamaProvider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	if err := validateTextRequest(req, p.Profile.Model); err != nil {
+		return nil, err
+	}
+
+	data, err := invokeModel(ctx, p.Profile, newLlamaRequest(req, p.Profile.Generation))
+	if err != nil {
+		return nil, err
+	}
+
+	var resp llamaResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+	return &llm.Response{
+		Text:         resp.Generation,
+		Model:        p.Profile.Model,
+		FinishReason: resp.StopReason,
+		Usage:        &llm.Usage{InputTokens: resp.PromptTokenCount, OutputTokens: resp.GenerationTokenCount},
+	}, nil
+}
+
+// ChatStream sends a streaming chat request to a Llama model on Bedrock and sends the generated text to a channel.
+func (p *LlamaProvider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	// Note: The caller is responsible for closing the responseChan.
+	if err := validateTextRequest(req, p.Profile.Model); err != nil {
+		return nil, err
+	}
+
+	result := &llm.Response{Model: p.Profile.Model}
+	err := invokeModelStream(ctx, p.Profile, newLlamaRequest(req, p.Profile.Generation), func(data []byte) error {
+		var chunk llamaResponse
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("error unmarshaling stream chunk: %w", err)
+		}
+		if chunk.Generation != "" {
+			responseChan <- chunk.Generation
+		}
+		if chunk.StopReason != "" {
+			result.FinishReason = chunk.StopReason
+		}
+		if chunk.Metrics != nil {
+			result.Usage = chunk.Metrics.usage()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ValidateConfig checks if the Bedrock provider's configuration is valid.
+func (p *LlamaProvider) ValidateConfig() error {
+	return validateConfig(p.Profile)
+}
+
+// validateTextRequest checks a request for a model family that takes a single prompt string.
+// These bodies have no way to declare tools or attach images and documents, so such requests are rejected.
+func validateTextRequest(req llm.Request, model string) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+	if len(req.Tools) > 0 {
+		return fmt.Errorf("tool calling is not supported for model '%s' on bedrock", model)
+	}
+	for _, m := range req.Messages {
+		if len(m.Parts) > 0 {
+			return fmt.Errorf("image and document input is not supported for model '%s' on bedrock", model)
+		}
+	}
+	return nil
+}
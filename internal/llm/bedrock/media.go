@@ -0,0 +1,62 @@
+package bedrock
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/magifd2/llm-cli/internal/llm"
+)
+
+// imageFormats maps the image types accepted by Nova and the Converse API to their format names.
+var imageFormats = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpeg",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+// documentFormats maps the document types accepted by Nova and the Converse API to their format names.
+var documentFormats = map[string]string{
+	"application/pdf":    "pdf",
+	"text/csv":           "csv",
+	"application/msword": "doc",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": "docx",
+	"application/vnd.ms-excel": "xls",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": "xlsx",
+	"text/html":     "html",
+	"text/plain":    "txt",
+	"text/markdown": "md",
+}
+
+// invalidDocumentNameChars matches the characters Bedrock does not accept in document names.
+var invalidDocumentNameChars = regexp.MustCompile(`[^A-Za-z0-9\s\-()\[\]]+`)
+
+// documentName derives a document name that Bedrock accepts from a file name: only letters, digits, single spaces,
+// hyphens, parentheses and square brackets. Names must be unique within a request, so a counter is added to repeated names.
+func documentName(p llm.Part, used map[string]bool) string {
+	base := strings.TrimSuffix(filepath.Base(p.Name), filepath.Ext(p.Name))
+	base = invalidDocumentNameChars.ReplaceAllString(base, "-")
+	base = strings.Join(strings.Fields(base), " ")
+	if base == "" || base == "-" {
+		base = "document"
+	}
+	name := base
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s (%d)", base, i)
+	}
+	used[name] = true
+	return name
+}
+
+// partFormat returns the Bedrock format name of a part and whether it is an image.
+func partFormat(api string, p llm.Part) (format string, image bool, err error) {
+	if format, ok := imageFormats[p.MIMEType]; ok {
+		return format, true, nil
+	}
+	if format, ok := documentFormats[p.MIMEType]; ok {
+		return format, false, nil
+	}
+	return "", false, llm.UnsupportedPartError(api, p)
+}
@@ -0,0 +1,137 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	appconfig "github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+)
+
+// MistralProvider implements the llm.Provider interface for Mistral AI models on Bedrock that take a prompt string
+// (e.g., Mistral 7B Instruct, Mixtral 8x7B Instruct, Mistral Large 24.02). The conversation is rendered with
+// the Mistral instruction template.
+type MistralProvider struct {
+	Profile appconfig.Profile // The configuration profile for this Bedrock instance.
+}
+
+// mistralRequest represents the request body for Mistral models. Mistral does not support a seed, so it is ignored.
+type mistralRequest struct {
+	Prompt      string   `json:"prompt"`
+	MaxTokens   *int     `json:"max_tokens,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	TopK        *int     `json:"top_k,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+}
+
+// mistralResponse represents a response, or a chunk of a streaming response, of a Mistral model.
+type mistralResponse struct {
+	Outputs []struct {
+		Text       string `json:"text"`        // The generated text (a fragment of it when streaming).
+		StopReason string `json:"stop_reason"` // "stop" or "length"; empty until the last chunk.
+	} `json:"outputs"`
+	Metrics *invocationMetrics `json:"amazon-bedrock-invocationMetrics"` // Set on the last chunk of a stream.
+}
+
+// buildMistralPrompt renders the conversation with the Mistral instruction template.
+// Mistral has no system role, so the system prompt is prepended to the first user message.
+func buildMistralPrompt(req llm.Request) string {
+	system := req.SystemPrompt()
+	if len(req.Schema) > 0 {
+		system = strings.TrimSpace(system + "\n\n" + req.SchemaInstruction())
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<s>")
+	for _, m := range req.Conversation() {
+		if m.Role == llm.RoleAssistant {
+			fmt.Fprintf(&sb, " %s</s>", m.Content)
+			continue
+		}
+		content := m.Content
+		if system != "" {
+			content = system + "\n\n" + content
+			system = ""
+		}
+		fmt.Fprintf(&sb, "[INST] %s [/INST]", content)
+	}
+	return sb.String()
+}
+
+// newMistralRequest converts a request into the Mistral request body.
+func newMistralRequest(req llm.Request, gen appconfig.Generation) mistralRequest {
+	return mistralRequest{
+		Prompt:      buildMistralPrompt(req),
+		MaxTokens:   gen.MaxTokens,
+		Temperature: gen.Temperature,
+		TopP:        gen.TopP,
+		TopK:        gen.TopK,
+		Stop:        gen.Stop,
+	}
+}
+
+// Chat sends a chat request to a Mistral model on Bedrock and returns a single, complete response.
+// The response body carries no token counts, so Usage is nil.
+func (p *MistralProvider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	if err := validateTextRequest(req, p.Profile.Model); err != nil {
+		return nil, err
+	}
+
+	data, err := invokeModel(ctx, p.Profile, newMistralRequest(req, p.Profile.Generation))
+	if err != nil {
+		return nil, err
+	}
+
+	var resp mistralResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+	if len(resp.Outputs) == 0 {
+		return nil, fmt.Errorf("no content found in response")
+	}
+	return &llm.Response{
+		Text:         resp.Outputs[0].Text,
+		Model:        p.Profile.Model,
+		FinishReason: resp.Outputs[0].StopReason,
+	}, nil
+}
+
+// ChatStream sends a streaming chat request to a Mistral model on Bedrock and sends the generated text to a channel.
+func (p *MistralProvider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	// Note: The caller is responsible for closing the responseChan.
+	if err := validateTextRequest(req, p.Profile.Model); err != nil {
+		return nil, err
+	}
+
+	result := &llm.Response{Model: p.Profile.Model}
+	err := invokeModelStream(ctx, p.Profile, newMistralRequest(req, p.Profile.Generation), func(data []byte) error {
+		var chunk mistralResponse
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("error unmarshaling stream chunk: %w", err)
+		}
+		for _, output := range chunk.Outputs {
+			if output.Text != "" {
+				responseChan <- output.Text
+			}
+			if output.StopReason != "" {
+				result.FinishReason = output.StopReason
+			}
+		}
+		if chunk.Metrics != nil {
+			result.Usage = chunk.Metrics.usage()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ValidateConfig checks if the Bedrock provider's configuration is valid.
+func (p *MistralProvider) ValidateConfig() error {
+	return validateConfig(p.Profile)
+}
@@ -4,28 +4,97 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 	appconfig "github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/httpclient"
 	"github.com/magifd2/llm-cli/internal/llm"
 )
 
-// NovaProvider implements the llm.Provider interface for Amazon Bedrock's Anthropic Claude 3 (Nova) models.
+// NovaProvider implements the llm.Provider interface for Amazon Nova models on Bedrock.
 // It handles authentication and communication with the Bedrock Runtime service.
 type NovaProvider struct {
 	Profile appconfig.Profile // The configuration profile for this Bedrock instance.
 }
 
 // novaMessageContent defines the structure for content within a message for Nova models.
-// Currently, it supports text content.
+// Each content block holds either text, a tool call made by the assistant or the result of a tool call.
 type novaMessageContent struct {
-	Text string `json:"text"` // The text content of the message.
+	Text       string          `json:"text,omitempty"`       // The text content of the message.
+	ToolUse    *novaToolUse    `json:"toolUse,omitempty"`    // A tool call requested by the assistant.
+	ToolResult *novaToolResult `json:"toolResult,omitempty"` // The result of a tool call, sent in a user message.
+	Image      *novaMedia      `json:"image,omitempty"`      // An image sent by the user.
+	Document   *novaMedia      `json:"document,omitempty"`   // A document, such as a PDF, sent by the user.
+}
+
+// novaMedia defines an image or document block. Documents also carry a name.
+type novaMedia struct {
+	Format string          `json:"format"`         // e.g., "png", "jpeg" or "pdf".
+	Name   string          `json:"name,omitempty"` // The name of a document.
+	Source novaMediaSource `json:"source"`
+}
+
+// novaMediaSource holds the base64-encoded data of an image or document.
+type novaMediaSource struct {
+	Bytes string `json:"bytes"`
+}
+
+// novaToolUse defines a tool call requested by the model.
+type novaToolUse struct {
+	ToolUseID string          `json:"toolUseId"` // The ID of the call, echoed back in the result.
+	Name      string          `json:"name"`      // The name of the tool.
+	Input     json.RawMessage `json:"input"`     // The arguments as a JSON object.
+}
+
+// novaToolResult defines the result of a tool call.
+type novaToolResult struct {
+	ToolUseID string               `json:"toolUseId"` // The ID of the call this result answers.
+	Content   []novaMessageContent `json:"content"`   // The tool's output as text blocks.
+}
+
+// novaToolConfig defines the tools the model may call.
+type novaToolConfig struct {
+	Tools []novaTool `json:"tools"`
+}
+
+// novaTool defines a single tool specification.
+type novaTool struct {
+	ToolSpec novaToolSpec `json:"toolSpec"`
+}
+
+// novaToolSpec describes a tool and the JSON schema of its input.
+type novaToolSpec struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	InputSchema struct {
+		JSON json.RawMessage `json:"json"`
+	} `json:"inputSchema"`
+}
+
+// newNovaToolConfig converts tool declarations to the Nova tool configuration. It returns nil if there are no tools.
+func newNovaToolConfig(tools []llm.Tool) *novaToolConfig {
+	if len(tools) == 0 {
+		return nil
+	}
+	cfg := &novaToolConfig{}
+	for _, t := range tools {
+		spec := novaToolSpec{Name: t.Name, Description: t.Description}
+		spec.InputSchema.JSON = t.Parameters
+		if len(spec.InputSchema.JSON) == 0 {
+			spec.InputSchema.JSON = json.RawMessage(`{"type":"object"}`)
+		}
+		cfg.Tools = append(cfg.Tools, novaTool{ToolSpec: spec})
+	}
+	return cfg
 }
 
 // novaMessage defines the structure for a single message in the conversation for Nova models.
@@ -43,21 +112,38 @@ type novaSystemPrompt struct {
 
 // inferenceConfig defines the structure for inference parameters for Nova models.
 // These parameters control the model's generation behavior.
+// Unset values are omitted so the model's defaults apply.
 type inferenceConfig struct {
-	MaxTokens     int      `json:"maxTokens,omitempty"`     // The maximum number of tokens to generate in the response.
-	Temperature   float64  `json:"temperature,omitempty"`   // Controls the randomness of the output. Higher values mean more random.
-	TopP          float64  `json:"topP,omitempty"`          // Controls diversity via nucleus sampling.
-	TopK          int      `json:"topK,omitempty"`          // Controls diversity by limiting the number of highest probability tokens.
+	MaxTokens     *int     `json:"maxTokens,omitempty"`     // The maximum number of tokens to generate in the response.
+	Temperature   *float64 `json:"temperature,omitempty"`   // Controls the randomness of the output. Higher values mean more random.
+	TopP          *float64 `json:"topP,omitempty"`          // Controls diversity via nucleus sampling.
+	TopK          *int     `json:"topK,omitempty"`          // Controls diversity by limiting the number of highest probability tokens.
 	StopSequences []string `json:"stopSequences,omitempty"` // A list of sequences that will cause the model to stop generating.
 }
 
+// newInferenceConfig maps the profile's generation parameters to the Nova inference configuration.
+// Nova does not support a sampling seed, so it is ignored.
+func newInferenceConfig(gen appconfig.Generation) *inferenceConfig {
+	if gen.MaxTokens == nil && gen.Temperature == nil && gen.TopP == nil && gen.TopK == nil && len(gen.Stop) == 0 {
+		return nil
+	}
+	return &inferenceConfig{
+		MaxTokens:     gen.MaxTokens,
+		Temperature:   gen.Temperature,
+		TopP:          gen.TopP,
+		TopK:          gen.TopK,
+		StopSequences: gen.Stop,
+	}
+}
+
 // novaMessagesAPIRequest represents the request body for Nova models using the Messages API.
-// This is the primary request format for Claude 3 models on Bedrock.
+// This is the request format of the Amazon Nova models on Bedrock.
 type novaMessagesAPIRequest struct {
 	SchemaVersion   string           `json:"schemaVersion"`     // The schema version for the API request (e.g., "messages-v1").
 	Messages        []novaMessage    `json:"messages"`          // The conversation history, including user and assistant messages.
 	System          []novaSystemPrompt `json:"system,omitempty"`    // Optional system prompts to guide the model's behavior.
-	InferenceConfig inferenceConfig  `json:"inferenceConfig,omitempty"` // Optional inference parameters.
+	InferenceConfig *inferenceConfig `json:"inferenceConfig,omitempty"` // Optional inference parameters.
+	ToolConfig      *novaToolConfig  `json:"toolConfig,omitempty"`      // Optional tools the model may call.
 }
 
 // novaCombinedAPIResponse represents the full response structure for Nova Messages API.
@@ -65,13 +151,11 @@ type novaMessagesAPIRequest struct {
 type novaCombinedAPIResponse struct {
 	Output struct {
 		Message struct {
-			Content []struct {
-				Text string `json:"text"` // The text content of the assistant's response.
-			} `json:"content"` // Content blocks of the message.
+			Content []novaMessageContent `json:"content"` // Content blocks of the message: text or tool calls.
 			Role string `json:"role"` // The role of the message sender (e.g., "assistant").
 		} `json:"message"` // The generated message from the model.
 	} `json:"output"` // The main output block.
-	StopReason string `json:"stopReason"` // The reason the model stopped generating (e.g., "end_turn", "max_tokens").
+	StopReason string `json:"stopReason"` // The reason the model stopped generating (e.g., "end_turn", "max_tokens", "tool_use").
 	Usage      struct {
 		InputTokens            int `json:"inputTokens"`            // Number of input tokens.
 		OutputTokens           int `json:"outputTokens"`           // Number of output tokens.
@@ -82,13 +166,32 @@ type novaCombinedAPIResponse struct {
 }
 
 // novaMessagesAPIStreamChunk represents a single chunk of a streaming response from a Nova Messages API model.
-// It typically contains a delta of content.
+// It typically contains a delta of content; the final chunks carry the stop reason and token usage.
 type novaMessagesAPIStreamChunk struct {
+	ContentBlockStart *struct {
+		Start struct {
+			ToolUse *novaToolUse `json:"toolUse"` // The ID and name of a tool call; its input follows in deltas.
+		} `json:"start"`
+		ContentBlockIndex int `json:"contentBlockIndex"`
+	} `json:"contentBlockStart"` // The content block start event.
 	ContentBlockDelta struct {
 		Delta struct {
-			Text string `json:"text"` // The incremental text content.
+			Text    string `json:"text"` // The incremental text content.
+			ToolUse *struct {
+				Input string `json:"input"` // A fragment of the JSON-encoded tool input.
+			} `json:"toolUse"`
 		} `json:"delta"` // The delta of content.
+		ContentBlockIndex int `json:"contentBlockIndex"`
 	} `json:"contentBlockDelta"` // The content block delta event.
+	MessageStop *struct {
+		StopReason string `json:"stopReason"` // The reason the model stopped generating.
+	} `json:"messageStop"` // The message stop event.
+	Metadata *struct {
+		Usage struct {
+			InputTokens  int `json:"inputTokens"`  // Number of input tokens.
+			OutputTokens int `json:"outputTokens"` // Number of output tokens.
+		} `json:"usage"` // Token usage statistics.
+	} `json:"metadata"` // The metadata event sent at the end of the stream.
 }
 
 // bedrockErrorResponse defines the structure for an error response from Bedrock.
@@ -100,11 +203,21 @@ type bedrockErrorResponse struct {
 }
 
 // newBedrockClient creates a new Bedrock Runtime client.
-// It configures the client with the specified AWS region and optional static credentials.
+// It configures the client with the specified AWS region, optional static credentials and an optional endpoint.
 func newBedrockClient(ctx context.Context, profile appconfig.Profile) (*bedrockruntime.Client, error) {
 	var opts []func(*config.LoadOptions) error
 	// Set the AWS region from the profile.
 	opts = append(opts, config.WithRegion(profile.AWSRegion))
+	// Connect with the connection settings of the profile. The SDK's own client is kept, since it
+	// applies settings such as AWS_CA_BUNDLE on top of them.
+	var transportErr error
+	httpClient := awshttp.NewBuildableClient().WithTransportOptions(func(t *http.Transport) {
+		transportErr = httpclient.Configure(t, profile)
+	})
+	if transportErr != nil {
+		return nil, fmt.Errorf("error configuring HTTP transport: %w", transportErr)
+	}
+	opts = append(opts, config.WithHTTPClient(httpClient))
 
 	// If AWS credentials file is provided, load credentials from it.
 	if profile.CredentialsFile != "" { // Changed from profile.AWSCredentialsFile
@@ -125,126 +238,220 @@ func newBedrockClient(ctx context.Context, profile appconfig.Profile) (*bedrockr
 	}
 
 	// Create and return a new Bedrock Runtime client from the loaded configuration.
-	return bedrockruntime.NewFromConfig(cfg), nil
+	// A profile endpoint replaces the regional endpoint, e.g., for a VPC endpoint or a local stand-in.
+	// The SDK's own retries are disabled, as failed calls are retried by the retry policy of the profile.
+	return bedrockruntime.NewFromConfig(cfg, func(o *bedrockruntime.Options) {
+		o.Retryer = aws.NopRetryer{}
+		if profile.Endpoint != "" {
+			o.BaseEndpoint = aws.String(profile.Endpoint)
+		}
+		// Extra headers are added before the request is signed.
+		for name, value := range profile.Transport.Headers {
+			o.APIOptions = append(o.APIOptions, smithyhttp.SetHeaderValue(name, value))
+		}
+	}), nil
 }
 
-// Chat sends a chat request to the Amazon Bedrock API using the Messages API format.
-// It returns a single, complete response from the model.
-func (p *NovaProvider) Chat(systemPromptText, userPrompt string) (string, error) {
-
-	ctx := context.Background()
-	// Create a new Bedrock client.
-	client, err := newBedrockClient(ctx, p.Profile)
-	if err != nil {
-		return "", err
-	}
+// buildNovaMessages converts a request into Nova messages and system prompts.
+// System messages are sent through the dedicated "system" field; all other turns become messages.
+// Nova has no native structured output, so a requested JSON schema is added to the system prompt.
+// Images and documents are sent ahead of the text of their message.
+func buildNovaMessages(req llm.Request) ([]novaMessage, []novaSystemPrompt, error) {
+	var messages []novaMessage
+	documentNames := make(map[string]bool)
+	for _, m := range req.Conversation() {
+		// Tool results are sent as toolResult blocks of a user message; consecutive results share one message.
+		if m.Role == llm.RoleTool {
+			block := novaMessageContent{ToolResult: &novaToolResult{ToolUseID: m.ToolCallID, Content: []novaMessageContent{{Text: m.Content}}}}
+			if n := len(messages); n > 0 && messages[n-1].Role == llm.RoleUser && messages[n-1].Content[0].ToolResult != nil {
+				messages[n-1].Content = append(messages[n-1].Content, block)
+			} else {
+				messages = append(messages, novaMessage{Role: llm.RoleUser, Content: []novaMessageContent{block}})
+			}
+			continue
+		}
 
-	// Construct the user message for the API request.
-	messages := []novaMessage{
-		{
-			Role: "user",
-			Content: []novaMessageContent{
-				{Text: userPrompt},
-			},
-		},
+		var content []novaMessageContent
+		for _, part := range m.Parts {
+			format, image, err := partFormat("nova", part)
+			if err != nil {
+				return nil, nil, err
+			}
+			if image {
+				content = append(content, novaMessageContent{Image: &novaMedia{Format: format, Source: novaMediaSource{Bytes: part.Base64()}}})
+			} else {
+				content = append(content, novaMessageContent{Document: &novaMedia{Format: format, Name: documentName(part, documentNames), Source: novaMediaSource{Bytes: part.Base64()}}})
+			}
+		}
+		if m.Content != "" || len(m.ToolCalls) == 0 {
+			content = append(content, novaMessageContent{Text: m.Content})
+		}
+		for _, tc := range m.ToolCalls {
+			content = append(content, novaMessageContent{ToolUse: &novaToolUse{ToolUseID: tc.ID, Name: tc.Name, Input: tc.Arguments}})
+		}
+		messages = append(messages, novaMessage{Role: m.Role, Content: content})
 	}
 
 	// Construct the system prompt as a slice of structs, only if it's not empty.
 	var systemContent []novaSystemPrompt
-	if systemPromptText != "" {
+	if systemPromptText := req.SystemPrompt(); systemPromptText != "" {
 		systemContent = append(systemContent, novaSystemPrompt{Text: systemPromptText})
 	}
-
-	// Build the request body for the InvokeModel API call.
-	// InferenceConfig is initialized directly with default values.
-	reqBody := novaMessagesAPIRequest{
-		SchemaVersion: "messages-v1",
-		Messages:      messages,
-		System:        systemContent,
-		InferenceConfig: inferenceConfig{
-			MaxTokens:   500,
-			Temperature: 0.7,
-			TopP:        0.9,
-			TopK:        20,
-		},
+	if len(req.Schema) > 0 {
+		systemContent = append(systemContent, novaSystemPrompt{Text: req.SchemaInstruction()})
 	}
+	return messages, systemContent, nil
+}
 
-	jsonBody, err := json.Marshal(reqBody)
+// newNovaRequest builds the Nova Messages API request body for a request.
+func newNovaRequest(req llm.Request, gen appconfig.Generation) (novaMessagesAPIRequest, error) {
+	// Convert the conversation and system prompt into the Nova message format.
+	messages, systemContent, err := buildNovaMessages(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request body: %w", err)
+		return novaMessagesAPIRequest{}, err
 	}
+	return novaMessagesAPIRequest{
+		SchemaVersion:   "messages-v1",
+		Messages:        messages,
+		System:          systemContent,
+		InferenceConfig: newInferenceConfig(gen),
+		ToolConfig:      newNovaToolConfig(req.Tools),
+	}, nil
+}
 
-	// Invoke the Bedrock model.
-	output, err := client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(p.Profile.Model),
-		ContentType: aws.String("application/json"),
-		Body:        jsonBody,
-	})
+// Chat sends a chat request to the Amazon Bedrock API using the Messages API format.
+// It returns a single, complete response from the model.
+func (p *NovaProvider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	body, err := newNovaRequest(req, p.Profile.Generation)
 	if err != nil {
-		return "", fmt.Errorf("failed to invoke model: %w", err)
+		return nil, err
 	}
+	responseBodyBytes, err := invokeModel(ctx, p.Profile, body)
+	if err != nil {
+		return nil, err
+	}
+	return parseNovaResponse(responseBodyBytes, p.Profile.Model)
+}
 
-	responseBodyBytes := output.Body
-
+// parseNovaResponse extracts the text, tool calls and metadata from a Nova response body.
+func parseNovaResponse(responseBodyBytes []byte, model string) (*llm.Response, error) {
 	// Attempt to unmarshal into the success response structure.
 	var novaResp novaCombinedAPIResponse
 	if err := json.Unmarshal(responseBodyBytes, &novaResp); err == nil {
 		// Success case: Extract text from the response.
 		if len(novaResp.Output.Message.Content) > 0 {
-			return novaResp.Output.Message.Content[0].Text, nil
+			result := &llm.Response{
+				Model:        model,
+				FinishReason: novaResp.StopReason,
+				Usage:        &llm.Usage{InputTokens: novaResp.Usage.InputTokens, OutputTokens: novaResp.Usage.OutputTokens},
+			}
+			var text strings.Builder
+			for _, block := range novaResp.Output.Message.Content {
+				text.WriteString(block.Text)
+				if block.ToolUse != nil {
+					result.ToolCalls = append(result.ToolCalls, llm.ToolCall{ID: block.ToolUse.ToolUseID, Name: block.ToolUse.Name, Arguments: llm.ToolArguments(block.ToolUse.Input)})
+				}
+			}
+			result.Text = text.String()
+			return result, nil
 		}
-		return "", fmt.Errorf("no content found in response")
+		return nil, fmt.Errorf("no content found in response")
 	}
 
 	// If unmarshaling into the success structure fails, try to unmarshal into the error structure.
 	var errorResp bedrockErrorResponse
 	if err := json.Unmarshal(responseBodyBytes, &errorResp); err == nil {
-		return "", fmt.Errorf("model error (%s): %s", errorResp.Type, errorResp.Message)
+		return nil, fmt.Errorf("model error (%s): %s", errorResp.Type, errorResp.Message)
 	}
 
 	// If both fail, return a generic error with the raw response body.
-	return "", fmt.Errorf("failed to unmarshal response body: %s", string(responseBodyBytes))
+	return nil, fmt.Errorf("failed to unmarshal response body: %s", string(responseBodyBytes))
 }
 
 // ChatStream sends a streaming chat request to the Amazon Bedrock API using the Messages API format.
 // It streams response chunks to the provided channel.
-func (p *NovaProvider) ChatStream(ctx context.Context, systemPromptText, userPrompt string, responseChan chan<- string) error {
+func (p *NovaProvider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
 	// Note: The caller is responsible for closing the responseChan.
+	result := &llm.Response{Model: p.Profile.Model}
+	toolUses := make(map[int]*novaToolUse) // Tool calls being assembled, by content block index.
+	var toolUseOrder []int
+
+	body, err := newNovaRequest(req, p.Profile.Generation)
+	if err != nil {
+		return nil, err
+	}
+	err = invokeModelStream(ctx, p.Profile, body, func(data []byte) error {
+		var chunk novaMessagesAPIStreamChunk
+		// Unmarshal the chunk bytes into the streaming response structure.
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			// Log the error but continue processing, as some chunks might be malformed or unexpected.
+			fmt.Fprintf(os.Stderr, "Error unmarshaling stream chunk: %v\n", err)
+			return nil
+		}
+		if start := chunk.ContentBlockStart; start != nil && start.Start.ToolUse != nil {
+			toolUses[start.ContentBlockIndex] = &novaToolUse{ToolUseID: start.Start.ToolUse.ToolUseID, Name: start.Start.ToolUse.Name}
+			toolUseOrder = append(toolUseOrder, start.ContentBlockIndex)
+		}
+		if delta := chunk.ContentBlockDelta; delta.Delta.ToolUse != nil {
+			if tu, ok := toolUses[delta.ContentBlockIndex]; ok {
+				tu.Input = append(tu.Input, delta.Delta.ToolUse.Input...)
+			}
+		}
+		if chunk.MessageStop != nil {
+			result.FinishReason = chunk.MessageStop.StopReason
+		}
+		if chunk.Metadata != nil {
+			result.Usage = &llm.Usage{InputTokens: chunk.Metadata.Usage.InputTokens, OutputTokens: chunk.Metadata.Usage.OutputTokens}
+		}
+		// Send the text content to the response channel.
+		if chunk.ContentBlockDelta.Delta.Text != "" {
+			responseChan <- chunk.ContentBlockDelta.Delta.Text
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, index := range toolUseOrder {
+		tu := toolUses[index]
+		result.ToolCalls = append(result.ToolCalls, llm.ToolCall{ID: tu.ToolUseID, Name: tu.Name, Arguments: llm.ToolArguments(tu.Input)})
+	}
+	return result, nil
+}
 
+// invokeModel sends a request body to the profile's Bedrock model and returns the response body.
+func invokeModel(ctx context.Context, profile appconfig.Profile, reqBody any) ([]byte, error) {
 	// Create a new Bedrock client.
-	client, err := newBedrockClient(ctx, p.Profile)
+	client, err := newBedrockClient(ctx, profile)
 	if err != nil {
-		return fmt.Errorf("error creating bedrock client: %w", err)
+		return nil, err
 	}
 
-	// Construct the user message for the API request.
-	messages := []novaMessage{
-		{
-			Role: "user",
-			Content: []novaMessageContent{
-				{Text: userPrompt},
-			},
-		},
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
 	}
 
-	// Construct the system prompt as a slice of structs, only if it's not empty.
-	var systemContent []novaSystemPrompt
-	if systemPromptText != "" {
-		systemContent = append(systemContent, novaSystemPrompt{Text: systemPromptText})
+	// Invoke the Bedrock model.
+	output, err := client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(profile.Model),
+		ContentType: aws.String("application/json"),
+		Body:        jsonBody,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to invoke model: %w", err)
 	}
+	return output.Body, nil
+}
 
-	// Build the request body for the InvokeModelWithResponseStream API call.
-	// InferenceConfig is initialized directly with default values.
-	reqBody := novaMessagesAPIRequest{
-		SchemaVersion: "messages-v1",
-		Messages:      messages,
-		System:        systemContent,
-		InferenceConfig: inferenceConfig{
-			MaxTokens:   500,
-			Temperature: 0.7,
-			TopP:        0.9,
-			TopK:        20,
-		},
+// invokeModelStream sends a request body to the profile's Bedrock model with a streaming response.
+// handleChunk is called with the bytes of each chunk; an error returned by it ends the stream.
+func invokeModelStream(ctx context.Context, profile appconfig.Profile, reqBody any, handleChunk func(data []byte) error) error {
+	// Create a new Bedrock client.
+	client, err := newBedrockClient(ctx, profile)
+	if err != nil {
+		return fmt.Errorf("error creating bedrock client: %w", err)
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
@@ -254,7 +461,7 @@ func (p *NovaProvider) ChatStream(ctx context.Context, systemPromptText, userPro
 
 	// Invoke the Bedrock model with streaming response.
 	output, err := client.InvokeModelWithResponseStream(ctx, &bedrockruntime.InvokeModelWithResponseStreamInput{
-		ModelId:     aws.String(p.Profile.Model),
+		ModelId:     aws.String(profile.Model),
 		ContentType: aws.String("application/json"),
 		Body:        jsonBody,
 	})
@@ -264,29 +471,20 @@ func (p *NovaProvider) ChatStream(ctx context.Context, systemPromptText, userPro
 
 	// Process the streaming events.
 	stream := output.GetStream()
+	defer stream.Close()
 	for event := range stream.Events() {
 		select {
 		case <-ctx.Done():
-			// If context is cancelled, close the stream and return the context error.
-			stream.Close()
+			// If context is cancelled, return the context error.
 			return ctx.Err()
 		default:
 		}
 
-		switch v := event.(type) {
-		case *types.ResponseStreamMemberChunk:
-			var chunk novaMessagesAPIStreamChunk
-			// Unmarshal the chunk bytes into the streaming response structure.
-			if err := json.Unmarshal(v.Value.Bytes, &chunk); err != nil {
-				// Log the error but continue processing, as some chunks might be malformed or unexpected.
-				fmt.Fprintf(os.Stderr, "Error unmarshaling stream chunk: %v\n", err)
-				continue
+		// Only chunks carry model output; other event types are ignored.
+		if v, ok := event.(*types.ResponseStreamMemberChunk); ok {
+			if err := handleChunk(v.Value.Bytes); err != nil {
+				return err
 			}
-			// Send the text content to the response channel.
-			responseChan <- chunk.ContentBlockDelta.Delta.Text
-		// Handle other event types if necessary.
-			// For example, *types.ResponseStreamMemberContentBlockStart, *types.ResponseStreamMemberContentBlockStop, etc.
-			// fmt.Fprintf(os.Stderr, "unhandled stream event type: %T\n", v)
 		}
 	}
 
@@ -294,10 +492,20 @@ func (p *NovaProvider) ChatStream(ctx context.Context, systemPromptText, userPro
 	if err := stream.Err(); err != nil {
 		return fmt.Errorf("streaming error: %w", err)
 	}
-
 	return nil
 }
 
+// invocationMetrics holds the token counts Bedrock adds to the last stream chunk of most model families.
+type invocationMetrics struct {
+	InputTokenCount  int `json:"inputTokenCount"`
+	OutputTokenCount int `json:"outputTokenCount"`
+}
+
+// usage converts the metrics to token usage.
+func (m *invocationMetrics) usage() *llm.Usage {
+	return &llm.Usage{InputTokens: m.InputTokenCount, OutputTokens: m.OutputTokenCount}
+}
+
 // awsCredentials represents the structure of the AWS credentials JSON file.
 type awsCredentials struct {
 	AWSAccessKeyID     string `json:"aws_access_key_id"`
@@ -306,67 +514,71 @@ type awsCredentials struct {
 
 // loadAWSCredentialsFromFile loads AWS credentials from a specified JSON file.
 func loadAWSCredentialsFromFile(filePath string) (*awsCredentials, error) {
-	resolvedPath, err := appconfig.ResolvePath(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to resolve credentials file path %s: %w", filePath, err)
-	}
-
-	data, err := os.ReadFile(resolvedPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read credentials file %s: %w", resolvedPath, err)
-	}
-
 	var creds awsCredentials
-	if err := json.Unmarshal(data, &creds); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal credentials from file %s: %w", resolvedPath, err)
+	if err := appconfig.LoadCredentials(filePath, &creds); err != nil {
+		return nil, err
 	}
 
 	if creds.AWSAccessKeyID == "" || creds.AWSSecretAccessKey == "" {
-		return nil, fmt.Errorf("aws_access_key_id or aws_secret_access_key is missing in credentials file %s", resolvedPath)
+		return nil, fmt.Errorf("aws_access_key_id or aws_secret_access_key is missing in credentials file %s", filePath)
 	}
 
 	return &creds, nil
 }
 
+// crossRegionPrefixes are the geography prefixes of cross-region inference profile IDs (e.g., "us.anthropic.claude-...").
+var crossRegionPrefixes = []string{"us.", "us-gov.", "eu.", "apac.", "jp.", "au.", "ca.", "global."}
+
+// baseModelID returns the foundation model ID of a model ID, inference profile ID or ARN,
+// e.g., "anthropic.claude-3-haiku-20240307-v1:0" for "us.anthropic.claude-3-haiku-20240307-v1:0".
+func baseModelID(model string) string {
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	for _, prefix := range crossRegionPrefixes {
+		if strings.HasPrefix(model, prefix) {
+			return strings.TrimPrefix(model, prefix)
+		}
+	}
+	return model
+}
+
 // NewProvider is a factory function that returns the correct Bedrock provider
-// based on the model specified in the profile.
+// based on the model specified in the profile. Each model family has its own request body format.
 func NewProvider(p appconfig.Profile) (llm.Provider, error) {
-	if strings.HasPrefix(p.Model, "amazon.nova") {
+	id := baseModelID(p.Model)
+	switch {
+	case strings.HasPrefix(id, "amazon.nova"):
 		return &NovaProvider{Profile: p}, nil
+	case strings.HasPrefix(id, "anthropic.claude"):
+		return &ClaudeProvider{Profile: p}, nil
+	case strings.HasPrefix(id, "meta.llama"):
+		return &LlamaProvider{Profile: p}, nil
+	case strings.HasPrefix(id, "mistral."):
+		return &MistralProvider{Profile: p}, nil
 	}
-	// Future models like Claude can be added here.
-	// if strings.HasPrefix(p.Model, "anthropic.claude") {
-	//     return &ClaudeProvider{Profile: p}, nil
-	// }
 	return nil, fmt.Errorf("model '%s' is not supported by the 'bedrock' provider yet", p.Model)
 }
 
 // ValidateConfig checks if the Bedrock provider's configuration is valid.
-// It requires a model, AWS region, and either direct AWS credentials or a credentials file.
 func (p *NovaProvider) ValidateConfig() error {
-	if p.Profile.Model == "" {
+	return validateConfig(p.Profile)
+}
+
+// validateConfig checks a Bedrock profile, whatever its model family.
+// It requires a model, AWS region, and either direct AWS credentials or a credentials file.
+func validateConfig(profile appconfig.Profile) error {
+	if profile.Model == "" {
 		return fmt.Errorf("Bedrock provider requires a 'model' to be specified in the profile")
 	}
-	if p.Profile.AWSRegion == "" {
+	if profile.AWSRegion == "" {
 		return fmt.Errorf("Bedrock provider requires an 'aws-region' to be specified in the profile")
 	}
 
 	// Check if either direct credentials or a credentials file is provided.
-	if (p.Profile.AWSAccessKeyID == "" || p.Profile.AWSSecretAccessKey == "") && p.Profile.CredentialsFile == "" {
+	if (profile.AWSAccessKeyID == "" || profile.AWSSecretAccessKey == "") && profile.CredentialsFile == "" {
 		return fmt.Errorf("Bedrock provider requires either 'aws-access-key-id' and 'aws-secret-access-key' or a 'credentials-file' to be set in the profile")
 	}
 
-	// If a credentials file is provided, attempt to resolve its path and check existence.
-	if p.Profile.CredentialsFile != "" {
-		resolvedPath, err := appconfig.ResolvePath(p.Profile.CredentialsFile)
-		if err != nil {
-			return fmt.Errorf("failed to resolve credentials file path %s: %w", p.Profile.CredentialsFile, err)
-		}
-		// Check if the file exists and is readable.
-		if _, err := os.Stat(resolvedPath); os.IsNotExist(err) {
-			return fmt.Errorf("credentials file not found at %s", resolvedPath)
-		}
-	}
-
-	return nil
+	return appconfig.CheckCredentialsFile(profile.CredentialsFile)
 }
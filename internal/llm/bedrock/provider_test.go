@@ -0,0 +1,158 @@
+package bedrock
+
+import (
+	"encoding/json"
+	"testing"
+
+	appconfig "github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProvider_DispatchesByModelID(t *testing.T) {
+	tests := []struct {
+		model string
+		want  llm.Provider
+	}{
+		{"amazon.nova-lite-v1:0", &NovaProvider{}},
+		{"us.amazon.nova-pro-v1:0", &NovaProvider{}},
+		{"anthropic.claude-3-haiku-20240307-v1:0", &ClaudeProvider{}},
+		{"arn:aws:bedrock:us-east-1:123456789012:inference-profile/eu.anthropic.claude-3-5-sonnet-20240620-v1:0", &ClaudeProvider{}},
+		{"meta.llama3-8b-instruct-v1:0", &LlamaProvider{}},
+		{"mistral.mixtral-8x7b-instruct-v0:1", &MistralProvider{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			p, err := NewProvider(appconfig.Profile{Model: tt.model})
+			require.NoError(t, err)
+			assert.IsType(t, tt.want, p)
+		})
+	}
+
+	_, err := NewProvider(appconfig.Profile{Model: "cohere.command-r-v1:0"})
+	assert.Error(t, err)
+}
+
+func TestNewClaudeRequest(t *testing.T) {
+	temperature := 0.2
+	req := llm.Request{
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: "be brief"},
+			{Role: llm.RoleUser, Content: "weather in Tokyo and Paris?"},
+			{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{
+				{ID: "a", Name: "weather", Arguments: json.RawMessage(`{"city":"Tokyo"}`)},
+				{ID: "b", Name: "weather", Arguments: json.RawMessage(`{"city":"Paris"}`)},
+			}},
+			{Role: llm.RoleTool, ToolCallID: "a", Name: "weather", Content: "sunny"},
+			{Role: llm.RoleTool, ToolCallID: "b", Name: "weather", Content: "rainy"},
+		},
+		Tools: []llm.Tool{{Name: "weather", Parameters: json.RawMessage(`{"type":"object"}`)}},
+	}
+
+	body, err := newClaudeRequest(req, appconfig.Generation{Temperature: &temperature})
+	require.NoError(t, err)
+	assert.Equal(t, claudeAnthropicVersion, body.AnthropicVersion)
+	assert.Empty(t, body.Model)
+	assert.Equal(t, 4096, body.MaxTokens)
+	assert.Equal(t, "be brief", body.System)
+	assert.Equal(t, &temperature, body.Temperature)
+	require.Len(t, body.Messages, 3)
+	assert.Len(t, body.Messages[1].Content, 2)
+	data, err := json.Marshal(body.Messages[2].Content)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"type": "tool_result", "tool_use_id": "a", "content": "sunny"},
+		{"type": "tool_result", "tool_use_id": "b", "content": "rainy"}
+	]`, string(data))
+	require.Len(t, body.Tools, 1)
+}
+
+func TestBuildLlamaPrompt(t *testing.T) {
+	req := llm.Request{Messages: []llm.Message{
+		{Role: llm.RoleSystem, Content: "be brief"},
+		{Role: llm.RoleUser, Content: "hi"},
+		{Role: llm.RoleAssistant, Content: "hello"},
+		{Role: llm.RoleUser, Content: "bye"},
+	}}
+	assert.Equal(t, "<|begin_of_text|>"+
+		"<|start_header_id|>system<|end_header_id|>\n\nbe brief<|eot_id|>"+
+		"<|start_header_id|>user<|end_header_id|>\n\nhi<|eot_id|>"+
+		"<|start_header_id|>assistant<|end_header_id|>\n\nhello<|eot_id|>"+
+		"<|start_header_id|>user<|end_header_id|>\n\nbye<|eot_id|>"+
+		"<|start_header_id|>assistant<|end_header_id|>\n\n", buildLlamaPrompt(req))
+}
+
+func TestBuildMistralPrompt(t *testing.T) {
+	req := llm.Request{Messages: []llm.Message{
+		{Role: llm.RoleSystem, Content: "be brief"},
+		{Role: llm.RoleUser, Content: "hi"},
+		{Role: llm.RoleAssistant, Content: "hello"},
+		{Role: llm.RoleUser, Content: "bye"},
+	}}
+	assert.Equal(t, "<s>[INST] be brief\n\nhi [/INST] hello</s>[INST] bye [/INST]", buildMistralPrompt(req))
+}
+
+func TestValidateTextRequest_RejectsToolsAndParts(t *testing.T) {
+	req := llm.NewRequest("", "hi")
+	assert.NoError(t, validateTextRequest(req, "meta.llama3-8b-instruct-v1:0"))
+
+	req.Tools = []llm.Tool{{Name: "weather"}}
+	assert.Error(t, validateTextRequest(req, "meta.llama3-8b-instruct-v1:0"))
+
+	req.Tools = nil
+	req.Messages[0].Parts = []llm.Part{{Name: "cat.png", MIMEType: "image/png"}}
+	assert.Error(t, validateTextRequest(req, "meta.llama3-8b-instruct-v1:0"))
+}
+
+func TestNewNovaRequest_Parts(t *testing.T) {
+	req := llm.NewRequest("", "summarize")
+	req.Messages[0].Parts = []llm.Part{
+		{Name: "chart.png", MIMEType: "image/png", Data: []byte("png")},
+		{Name: "q3_report.v2.pdf", MIMEType: "application/pdf", Data: []byte("pdf")},
+	}
+
+	body, err := newNovaRequest(req, appconfig.Generation{})
+	require.NoError(t, err)
+	require.Len(t, body.Messages, 1)
+	assert.Equal(t, []novaMessageContent{
+		{Image: &novaMedia{Format: "png", Source: novaMediaSource{Bytes: "cG5n"}}},
+		{Document: &novaMedia{Format: "pdf", Name: "q3-report-v2", Source: novaMediaSource{Bytes: "cGRm"}}},
+		{Text: "summarize"},
+	}, body.Messages[0].Content)
+
+	req.Messages[0].Parts = []llm.Part{{Name: "clip.mp3", MIMEType: "audio/mpeg"}}
+	_, err = newNovaRequest(req, appconfig.Generation{})
+	assert.ErrorContains(t, err, "does not accept audio/mpeg input")
+}
+
+func TestParseNovaResponse_ToolUse(t *testing.T) {
+	resp, err := parseNovaResponse([]byte(`{
+		"output": {"message": {"role": "assistant", "content": [
+			{"text": "Checking."},
+			{"toolUse": {"toolUseId": "t1", "name": "now"}}
+		]}},
+		"stopReason": "tool_use",
+		"usage": {"inputTokens": 12, "outputTokens": 5}
+	}`), "amazon.nova-lite-v1:0")
+	require.NoError(t, err)
+	assert.Equal(t, "Checking.", resp.Text)
+	require.Len(t, resp.ToolCalls, 1)
+	// A tool without parameters gets an empty object, which can be sent back in the next round.
+	assert.Equal(t, llm.ToolCall{ID: "t1", Name: "now", Arguments: json.RawMessage("{}")}, resp.ToolCalls[0])
+
+	req := llm.NewRequest("", "time?")
+	req.Messages = append(req.Messages, llm.Message{Role: llm.RoleAssistant, ToolCalls: resp.ToolCalls})
+	body, err := newNovaRequest(req, appconfig.Generation{})
+	require.NoError(t, err)
+	data, err := json.Marshal(body.Messages[1])
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"input":{}`)
+}
+
+func TestDocumentName(t *testing.T) {
+	used := make(map[string]bool)
+	assert.Equal(t, "annual report (2024)", documentName(llm.Part{Name: "dir/annual  report (2024).pdf"}, used))
+	assert.Equal(t, "annual report (2024) (2)", documentName(llm.Part{Name: "annual report (2024).pdf"}, used))
+	assert.Equal(t, "document", documentName(llm.Part{Name: "_.pdf"}, used))
+}
@@ -0,0 +1,53 @@
+package llm
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrRequestTimeout is returned when a request is cancelled because it exceeded the profile's request timeout.
+var ErrRequestTimeout = errors.New("request timed out")
+
+// StatusError is returned by providers when an API answers with an unsuccessful HTTP status.
+// It lets callers such as the retry wrapper tell transient failures (e.g., 429 or 503) from permanent ones.
+type StatusError struct {
+	StatusCode int           // The HTTP status code of the response.
+	RetryAfter time.Duration // The delay requested by the server's Retry-After header, or 0 if none was sent.
+	Message    string        // The description of the error, usually including the response body.
+}
+
+// Error returns the description of the error.
+func (e *StatusError) Error() string {
+	return e.Message
+}
+
+// NewStatusError builds a StatusError for an unsuccessful response, reading its Retry-After header.
+func NewStatusError(resp *http.Response, message string) *StatusError {
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		Message:    message,
+	}
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, which is either a number of seconds or an HTTP date.
+// It returns 0 if the value is empty, invalid or in the past.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,20 @@
+package llm
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.Equal(t, 3*time.Second, ParseRetryAfter("3", now))
+	assert.Equal(t, 1500*time.Millisecond, ParseRetryAfter(" 1.5 ", now))
+	assert.Equal(t, 10*time.Second, ParseRetryAfter(now.Add(10*time.Second).Format(http.TimeFormat), now))
+	assert.Zero(t, ParseRetryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now), "dates in the past")
+	assert.Zero(t, ParseRetryAfter("", now))
+	assert.Zero(t, ParseRetryAfter("-1", now))
+	assert.Zero(t, ParseRetryAfter("soon", now))
+}
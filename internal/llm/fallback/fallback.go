@@ -0,0 +1,89 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/magifd2/llm-cli/internal/llm"
+)
+
+// Candidate is one of the providers of a fallback chain.
+type Candidate struct {
+	Name     string       // The name of the candidate in messages, e.g., its profile.
+	Provider llm.Provider // The provider, or nil if it could not be initialized.
+	Err      error        // Why the provider could not be initialized or is misconfigured, if it is unusable.
+}
+
+// Provider tries a chain of providers in order and returns the response of the first one that succeeds.
+// A candidate that fails, e.g., because its server is unreachable, overloaded or out of quota, hands over to the next.
+// Streams only fall back until the first token has been sent to the caller, since the text already printed
+// cannot be taken back. A cancellation by the caller stops the chain.
+type Provider struct {
+	Candidates []Candidate // The providers to try, in order.
+	Log        io.Writer   // Receives a line for every candidate that fails, if set.
+
+	answered atomic.Int64 // The index of the candidate that answered the last call, plus one.
+}
+
+// NewProvider returns a provider that tries the candidates in order.
+func NewProvider(candidates []Candidate, log io.Writer) *Provider {
+	return &Provider{Candidates: candidates, Log: log}
+}
+
+// Answered returns the name of the candidate that answered the last successful call, or "" if there was none.
+func (p *Provider) Answered() string {
+	if i := p.answered.Load(); i > 0 {
+		return p.Candidates[i-1].Name
+	}
+	return ""
+}
+
+// Chat sends the request to the candidates in order until one of them answers.
+func (p *Provider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	return p.do(ctx, func(provider llm.Provider) (*llm.Response, bool, error) {
+		resp, err := provider.Chat(ctx, req)
+		return resp, true, err
+	})
+}
+
+// ChatStream streams the response of the first candidate that answers. Once a candidate has sent a token,
+// its failure ends the stream.
+func (p *Provider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	return p.do(ctx, func(provider llm.Provider) (*llm.Response, bool, error) {
+		relay, emitted, wait := llm.RelayFirstToken(ctx, responseChan, nil)
+		resp, err := provider.ChatStream(ctx, req, relay)
+		wait()
+		return resp, !emitted(), err
+	})
+}
+
+// do runs the call against the candidates until one succeeds. The call reports whether a failure may fall back,
+// e.g., because no output has reached the caller yet.
+func (p *Provider) do(ctx context.Context, call func(llm.Provider) (*llm.Response, bool, error)) (*llm.Response, error) {
+	p.answered.Store(0)
+	var errs []error
+	for i, candidate := range p.Candidates {
+		err := candidate.Err
+		if err == nil {
+			var resp *llm.Response
+			var canFallBack bool
+			resp, canFallBack, err = call(candidate.Provider)
+			if err == nil {
+				p.answered.Store(int64(i + 1))
+				return resp, nil
+			}
+			if !canFallBack || ctx.Err() != nil {
+				return nil, err
+			}
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", candidate.Name, err))
+		if p.Log != nil && i+1 < len(p.Candidates) {
+			fmt.Fprintf(p.Log, "Warning: %s failed: %v. Falling back to %s.\n", candidate.Name, err, p.Candidates[i+1].Name)
+		}
+	}
+	return nil, fmt.Errorf("all providers failed: %w", errors.Join(errs...))
+}
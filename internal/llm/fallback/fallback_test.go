@@ -0,0 +1,102 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider answers with its text, or fails with its error after sending the chunks in partial.
+type fakeProvider struct {
+	text    string
+	err     error
+	partial []string
+	calls   int
+}
+
+func (p *fakeProvider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &llm.Response{Text: p.text}, nil
+}
+
+func (p *fakeProvider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	p.calls++
+	for _, chunk := range p.partial {
+		responseChan <- chunk
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	responseChan <- p.text
+	return &llm.Response{}, nil
+}
+
+func TestProvider_Chat(t *testing.T) {
+	local := &fakeProvider{err: &llm.StatusError{StatusCode: 503, Message: "unavailable"}}
+	cloud := &fakeProvider{text: "from the cloud"}
+	unused := &fakeProvider{text: "unused"}
+	var log strings.Builder
+	p := NewProvider([]Candidate{
+		{Name: "local", Provider: local},
+		{Name: "misconfigured", Err: errors.New("model is required")},
+		{Name: "cloud", Provider: cloud},
+		{Name: "unused", Provider: unused},
+	}, &log)
+
+	resp, err := p.Chat(context.Background(), llm.NewRequest("", "hi"))
+	require.NoError(t, err)
+	assert.Equal(t, "from the cloud", resp.Text)
+	assert.Equal(t, "cloud", p.Answered())
+	assert.Equal(t, 0, unused.calls)
+	assert.Equal(t, "Warning: local failed: unavailable. Falling back to misconfigured.\n"+
+		"Warning: misconfigured failed: model is required. Falling back to cloud.\n", log.String())
+}
+
+func TestProvider_AllFail(t *testing.T) {
+	statusErr := &llm.StatusError{StatusCode: 429, Message: "quota exceeded"}
+	p := NewProvider([]Candidate{
+		{Name: "a", Provider: &fakeProvider{err: statusErr}},
+		{Name: "b", Err: errors.New("provider 'x' not recognized")},
+	}, nil)
+
+	_, err := p.Chat(context.Background(), llm.NewRequest("", "hi"))
+	require.Error(t, err)
+	assert.ErrorIs(t, err, statusErr)
+	assert.Equal(t, "all providers failed: a: quota exceeded\nb: provider 'x' not recognized", err.Error())
+	assert.Equal(t, "", p.Answered())
+}
+
+func TestProvider_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	second := &fakeProvider{text: "ok"}
+	p := NewProvider([]Candidate{{Name: "a", Provider: &fakeProvider{err: context.Canceled}}, {Name: "b", Provider: second}}, nil)
+
+	_, err := p.Chat(ctx, llm.NewRequest("", "hi"))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, second.calls, "a cancellation does not fall back")
+}
+
+func TestProvider_ChatStream(t *testing.T) {
+	second := &fakeProvider{text: "ok"}
+	p := NewProvider([]Candidate{{Name: "a", Provider: &fakeProvider{err: errors.New("refused"), partial: []string{""}}}, {Name: "b", Provider: second}}, nil)
+	responseChan := make(chan string, 10)
+	_, err := p.ChatStream(context.Background(), llm.NewRequest("", "hi"), responseChan)
+	require.NoError(t, err)
+	assert.Equal(t, "b", p.Answered())
+
+	// Once a token has been sent, a failure ends the stream.
+	second = &fakeProvider{text: "ok"}
+	p = NewProvider([]Candidate{{Name: "a", Provider: &fakeProvider{err: errors.New("reset"), partial: []string{"Hel"}}}, {Name: "b", Provider: second}}, nil)
+	_, err = p.ChatStream(context.Background(), llm.NewRequest("", "hi"), make(chan string, 10))
+	assert.EqualError(t, err, "reset")
+	assert.Equal(t, 0, second.calls)
+}
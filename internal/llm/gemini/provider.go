@@ -0,0 +1,95 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/httpclient"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/magifd2/llm-cli/internal/llm/genaichat"
+	"google.golang.org/genai"
+)
+
+// Provider implements the llm.Provider interface for the Gemini Developer API.
+// It uses the same `google.golang.org/genai` SDK as the Vertex AI providers, with the Gemini API backend,
+// so it only needs an API key instead of a GCP project and a service account.
+type Provider struct {
+	Profile config.Profile // The configuration profile for this Gemini instance.
+}
+
+// newGeminiClient initializes a Gemini API client with the API key of the profile.
+// A profile endpoint replaces the default base URL of the API.
+func (p *Provider) newGeminiClient(ctx context.Context) (*genai.Client, error) {
+	apiKey, err := p.getAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := httpclient.New(p.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	clientConfig := &genai.ClientConfig{
+		APIKey:     apiKey,
+		Backend:    genai.BackendGeminiAPI,
+		HTTPClient: httpClient,
+	}
+	if p.Profile.Endpoint != "" {
+		clientConfig.HTTPOptions.BaseURL = p.Profile.Endpoint
+	}
+
+	client, err := genai.NewClient(ctx, clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error creating new genai client: %w", err)
+	}
+	return client, nil
+}
+
+// Chat sends a chat request to the Gemini API and returns a single, complete response.
+// System prompts are sent as the system instruction of the request.
+func (p *Provider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	client, err := p.newGeminiClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return genaichat.Chat(ctx, client, p.Profile, req)
+}
+
+// ChatStream sends a streaming chat request to the Gemini API and sends the generated text to a channel.
+func (p *Provider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	client, err := p.newGeminiClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return genaichat.ChatStream(ctx, client, p.Profile, req, responseChan)
+}
+
+// getAPIKey retrieves the API key from the profile or a credentials file.
+func (p *Provider) getAPIKey() (string, error) {
+	if p.Profile.CredentialsFile != "" {
+		return config.LoadCredentialField(p.Profile.CredentialsFile, "gemini_api_key")
+	}
+	if p.Profile.APIKey == "" {
+		return "", fmt.Errorf("gemini provider requires either 'api-key' or 'credentials-file' to be set in the profile")
+	}
+	return p.Profile.APIKey, nil
+}
+
+// NewProvider is a factory function that returns a new Gemini provider.
+func NewProvider(p config.Profile) (llm.Provider, error) {
+	return &Provider{Profile: p}, nil
+}
+
+// ValidateConfig checks if the Gemini provider's configuration is valid.
+// It requires a model and either an API key or a credentials file; no GCP project or location is needed.
+func (p *Provider) ValidateConfig() error {
+	if p.Profile.Model == "" {
+		return fmt.Errorf("Gemini provider requires a 'model' to be specified in the profile")
+	}
+	if p.Profile.APIKey == "" && p.Profile.CredentialsFile == "" {
+		return fmt.Errorf("Gemini provider requires either 'api-key' or 'credentials-file' to be set in the profile")
+	}
+
+	return config.CheckCredentialsFile(p.Profile.CredentialsFile)
+}
@@ -0,0 +1,89 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_Chat(t *testing.T) {
+	var gotPath, gotKey string
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotKey = r.Header.Get("x-goog-api-key")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{
+			"candidates": [{"content": {"role": "model", "parts": [{"text": "Hello"}]}, "finishReason": "STOP", "index": 0}],
+			"usageMetadata": {"promptTokenCount": 4, "candidatesTokenCount": 1},
+			"modelVersion": "gemini-test-001"
+		}`)
+	}))
+	defer server.Close()
+
+	p := &Provider{Profile: config.Profile{Model: "gemini-test", Endpoint: server.URL, APIKey: "test-key"}}
+	resp, err := p.Chat(context.Background(), llm.NewRequest("be brief", "hi"))
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasSuffix(gotPath, "/models/gemini-test:generateContent"), gotPath)
+	assert.Equal(t, "test-key", gotKey)
+	assert.Equal(t, map[string]any{"parts": []any{map[string]any{"text": "be brief"}}, "role": "user"}, got["systemInstruction"])
+	assert.Len(t, got["contents"], 1)
+
+	assert.Equal(t, "Hello", resp.Text)
+	assert.Equal(t, "gemini-test-001", resp.Model)
+	assert.Equal(t, "STOP", resp.FinishReason)
+	assert.Equal(t, &llm.Usage{InputTokens: 4, OutputTokens: 1}, resp.Usage)
+}
+
+func TestProvider_ChatStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasSuffix(r.URL.Path, ":streamGenerateContent"), r.URL.Path)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"candidates\": [{\"content\": {\"role\": \"model\", \"parts\": [{\"text\": \"Hel\"}]}, \"index\": 0}]}\n\n")
+		fmt.Fprint(w, "data: {\"candidates\": [{\"content\": {\"role\": \"model\", \"parts\": [{\"text\": \"lo\"}]}, \"finishReason\": \"STOP\", \"index\": 0}], \"usageMetadata\": {\"promptTokenCount\": 4, \"candidatesTokenCount\": 2}}\n\n")
+	}))
+	defer server.Close()
+
+	p := &Provider{Profile: config.Profile{Model: "gemini-test", Endpoint: server.URL, APIKey: "test-key"}}
+	responseChan := make(chan string, 10)
+	resp, err := p.ChatStream(context.Background(), llm.NewRequest("", "hi"), responseChan)
+	require.NoError(t, err)
+	close(responseChan)
+
+	var text string
+	for chunk := range responseChan {
+		text += chunk
+	}
+	assert.Equal(t, "Hello", text)
+	assert.Equal(t, "STOP", resp.FinishReason)
+	assert.Equal(t, &llm.Usage{InputTokens: 4, OutputTokens: 2}, resp.Usage)
+}
+
+func TestProvider_CredentialsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gemini.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"gemini_api_key":"from-file"}`), 0600))
+
+	p := &Provider{Profile: config.Profile{Model: "gemini-test", CredentialsFile: path}}
+	require.NoError(t, p.ValidateConfig())
+	key, err := p.getAPIKey()
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", key)
+
+	// No project, location or service account is needed, but a key is.
+	assert.NoError(t, (&Provider{Profile: config.Profile{Model: "gemini-test", APIKey: "k"}}).ValidateConfig())
+	assert.Error(t, (&Provider{Profile: config.Profile{Model: "gemini-test"}}).ValidateConfig())
+	assert.Error(t, (&Provider{Profile: config.Profile{APIKey: "k"}}).ValidateConfig())
+}
@@ -0,0 +1,46 @@
+package genaichat
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"cloud.google.com/go/auth"
+	"cloud.google.com/go/auth/credentials"
+	"cloud.google.com/go/auth/httptransport"
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/httpclient"
+)
+
+// cloudPlatformScope is the OAuth scope required by Vertex AI.
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// NewVertexAIHTTPClient returns an HTTP client that authorizes Vertex AI requests with the given credentials,
+// or with the application default credentials if creds is nil, and connects with the settings of the profile.
+// It stands in for the client the genai SDK would build itself, which always uses the default transport.
+func NewVertexAIHTTPClient(ctx context.Context, profile config.Profile, creds *auth.Credentials) (*http.Client, error) {
+	if creds == nil {
+		var err error
+		creds, err = credentials.DetectDefault(&credentials.DetectOptions{Scopes: []string{cloudPlatformScope}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to find default credentials: %w", err)
+		}
+	}
+	quotaProjectID, err := creds.QuotaProjectID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quota project ID: %w", err)
+	}
+	transport, err := httpclient.NewTransport(profile)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring HTTP transport: %w", err)
+	}
+	client, err := httptransport.NewClient(&httptransport.Options{
+		Credentials:      creds,
+		Headers:          http.Header{"X-Goog-User-Project": []string{quotaProjectID}},
+		BaseRoundTripper: transport,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	return client, nil
+}
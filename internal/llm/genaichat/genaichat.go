@@ -0,0 +1,246 @@
+package genaichat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"google.golang.org/genai"
+)
+
+// Chat sends the request to the profile's model with a genai client and returns a single, complete response.
+// It is shared by the providers built on the google.golang.org/genai SDK, which differ only in how the client is created.
+func Chat(ctx context.Context, client *genai.Client, profile config.Profile, req llm.Request) (*llm.Response, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Models.GenerateContent(ctx, profile.Model, ToContents(req.Conversation()), NewGenerateContentConfig(profile, req))
+	if err != nil {
+		return nil, fmt.Errorf("error generating content: %w", statusError(err))
+	}
+
+	result := responseMetadata(resp)
+	texts := candidateTexts(resp)
+	if len(texts) > 0 {
+		result.Text = texts[0]
+	}
+	if len(texts) > 1 {
+		result.Candidates = texts
+	}
+	result.ToolCalls = toolCallsFromResponse(resp)
+	return result, nil
+}
+
+// statusError converts an API error of the genai SDK into an llm.StatusError, so that callers can tell
+// transient failures from permanent ones. Other errors are returned unchanged.
+func statusError(err error) error {
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) && apiErr.Code != 0 {
+		return &llm.StatusError{StatusCode: apiErr.Code, Message: apiErr.Error()}
+	}
+	return err
+}
+
+// ChatStream sends the request to the profile's model with a genai client and sends the text of the first candidate
+// to the channel as it is generated. If several candidates were requested, their full texts are returned in Candidates.
+func ChatStream(ctx context.Context, client *genai.Client, profile config.Profile, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	// Note: The caller is responsible for closing the responseChan.
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	result := &llm.Response{}
+	var toolCalls []llm.ToolCall
+	candidates := make(map[int32]*strings.Builder) // The text of each candidate so far, by candidate index.
+	for resp, err := range client.Models.GenerateContentStream(ctx, profile.Model, ToContents(req.Conversation()), NewGenerateContentConfig(profile, req)) {
+		if err != nil {
+			return nil, fmt.Errorf("error reading stream: %w", statusError(err))
+		}
+
+		// Each chunk carries the metadata so far; the last one has the final finish reason and usage.
+		result = responseMetadata(resp)
+		toolCalls = append(toolCalls, toolCallsFromResponse(resp)...)
+		for _, cand := range resp.Candidates {
+			chunk := candidateText(cand)
+			if chunk == "" {
+				continue
+			}
+			if candidates[cand.Index] == nil {
+				candidates[cand.Index] = &strings.Builder{}
+			}
+			candidates[cand.Index].WriteString(chunk)
+			if cand.Index != 0 {
+				continue
+			}
+			select {
+			case responseChan <- chunk:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	if len(candidates) > 1 {
+		indexes := make([]int32, 0, len(candidates))
+		for index := range candidates {
+			indexes = append(indexes, index)
+		}
+		sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+		for _, index := range indexes {
+			result.Candidates = append(result.Candidates, candidates[index].String())
+		}
+	}
+	result.ToolCalls = toolCalls
+	return result, nil
+}
+
+// NewGenerateContentConfig maps the system prompt, JSON schema and tools of the request, and the profile's
+// generation parameters and safety settings, to a genai generation config.
+// Unset parameters are left out, so the model's defaults apply.
+func NewGenerateContentConfig(profile config.Profile, req llm.Request) *genai.GenerateContentConfig {
+	gen := profile.Generation
+	cfg := &genai.GenerateContentConfig{StopSequences: gen.Stop}
+	if systemPrompt := req.SystemPrompt(); systemPrompt != "" {
+		cfg.SystemInstruction = &genai.Content{Parts: []*genai.Part{{Text: systemPrompt}}}
+	}
+	if len(req.Schema) > 0 {
+		cfg.ResponseMIMEType = "application/json"
+		cfg.ResponseJsonSchema = req.Schema
+	}
+	if len(req.Tools) > 0 {
+		var decls []*genai.FunctionDeclaration
+		for _, t := range req.Tools {
+			decls = append(decls, &genai.FunctionDeclaration{Name: t.Name, Description: t.Description, ParametersJsonSchema: t.Parameters})
+		}
+		cfg.Tools = []*genai.Tool{{FunctionDeclarations: decls}}
+	}
+	for _, s := range profile.SafetySettings {
+		cfg.SafetySettings = append(cfg.SafetySettings, &genai.SafetySetting{
+			Category:  genai.HarmCategory(s.Category),
+			Threshold: genai.HarmBlockThreshold(s.Threshold),
+		})
+	}
+	if gen.Temperature != nil {
+		cfg.Temperature = genai.Ptr(float32(*gen.Temperature))
+	}
+	if gen.TopP != nil {
+		cfg.TopP = genai.Ptr(float32(*gen.TopP))
+	}
+	if gen.TopK != nil {
+		cfg.TopK = genai.Ptr(float32(*gen.TopK))
+	}
+	if gen.MaxTokens != nil {
+		cfg.MaxOutputTokens = int32(*gen.MaxTokens)
+	}
+	if gen.Seed != nil {
+		cfg.Seed = genai.Ptr(int32(*gen.Seed))
+	}
+	if gen.CandidateCount != nil {
+		cfg.CandidateCount = int32(*gen.CandidateCount)
+	}
+	return cfg
+}
+
+// ToContents converts user, assistant and tool messages into genai contents.
+// Tool results are sent as function responses in a user turn; consecutive results share one turn.
+// Images and documents are sent as inline data ahead of the text, as recommended for Gemini models.
+func ToContents(messages []llm.Message) []*genai.Content {
+	var contents []*genai.Content
+	for i, m := range messages {
+		if m.Role == llm.RoleTool {
+			part := &genai.Part{FunctionResponse: &genai.FunctionResponse{ID: m.ToolCallID, Name: m.Name, Response: map[string]any{"output": m.Content}}}
+			if i > 0 && messages[i-1].Role == llm.RoleTool {
+				last := contents[len(contents)-1]
+				last.Parts = append(last.Parts, part)
+			} else {
+				contents = append(contents, &genai.Content{Parts: []*genai.Part{part}, Role: genai.RoleUser})
+			}
+			continue
+		}
+
+		role := genai.RoleUser
+		if m.Role == llm.RoleAssistant {
+			role = genai.RoleModel
+		}
+		var parts []*genai.Part
+		for _, p := range m.Parts {
+			parts = append(parts, &genai.Part{InlineData: &genai.Blob{MIMEType: p.MIMEType, Data: p.Data}})
+		}
+		if m.Content != "" || len(m.ToolCalls) == 0 {
+			parts = append(parts, &genai.Part{Text: m.Content})
+		}
+		for _, tc := range m.ToolCalls {
+			var args map[string]any
+			_ = json.Unmarshal(tc.Arguments, &args)
+			parts = append(parts, &genai.Part{FunctionCall: &genai.FunctionCall{ID: tc.ID, Name: tc.Name, Args: args}})
+		}
+		contents = append(contents, &genai.Content{Parts: parts, Role: role})
+	}
+	return contents
+}
+
+// toolCallsFromResponse returns the function calls requested in the first candidate of a response.
+func toolCallsFromResponse(resp *genai.GenerateContentResponse) []llm.ToolCall {
+	if resp == nil {
+		return nil
+	}
+	var calls []llm.ToolCall
+	for _, fc := range resp.FunctionCalls() {
+		args, err := json.Marshal(fc.Args)
+		if err != nil || fc.Args == nil {
+			args = []byte("{}")
+		}
+		calls = append(calls, llm.ToolCall{ID: fc.ID, Name: fc.Name, Arguments: args})
+	}
+	return calls
+}
+
+// candidateTexts returns the text of each candidate of a response, in order.
+func candidateTexts(resp *genai.GenerateContentResponse) []string {
+	if resp == nil {
+		return nil
+	}
+	var texts []string
+	for _, cand := range resp.Candidates {
+		texts = append(texts, candidateText(cand))
+	}
+	return texts
+}
+
+// candidateText concatenates the text parts of a candidate.
+func candidateText(cand *genai.Candidate) string {
+	if cand == nil || cand.Content == nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, part := range cand.Content.Parts {
+		sb.WriteString(part.Text)
+	}
+	return sb.String()
+}
+
+// responseMetadata returns the model version, finish reason and token usage of a response.
+// The finish reason is that of the first candidate.
+func responseMetadata(resp *genai.GenerateContentResponse) *llm.Response {
+	result := &llm.Response{}
+	if resp == nil {
+		return result
+	}
+	result.Model = resp.ModelVersion
+	if len(resp.Candidates) > 0 {
+		result.FinishReason = string(resp.Candidates[0].FinishReason)
+	}
+	if resp.UsageMetadata != nil {
+		result.Usage = &llm.Usage{
+			InputTokens:  int(resp.UsageMetadata.PromptTokenCount),
+			OutputTokens: int(resp.UsageMetadata.CandidatesTokenCount),
+		}
+	}
+	return result
+}
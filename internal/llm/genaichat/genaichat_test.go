@@ -0,0 +1,81 @@
+package genaichat
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+func TestNewGenerateContentConfig(t *testing.T) {
+	temperature := 0.3
+	candidates := 2
+	profile := config.Profile{
+		Generation: config.Generation{Temperature: &temperature, CandidateCount: &candidates},
+		SafetySettings: []config.SafetySetting{
+			{Category: "HARM_CATEGORY_HATE_SPEECH", Threshold: "BLOCK_ONLY_HIGH"},
+		},
+	}
+	req := llm.NewRequest("be brief", "hi")
+	req.Schema = json.RawMessage(`{"type":"object"}`)
+
+	cfg := NewGenerateContentConfig(profile, req)
+	require.NotNil(t, cfg.SystemInstruction)
+	assert.Equal(t, "be brief", cfg.SystemInstruction.Parts[0].Text)
+	assert.Equal(t, genai.Ptr(float32(0.3)), cfg.Temperature)
+	assert.Equal(t, int32(2), cfg.CandidateCount)
+	assert.Equal(t, []*genai.SafetySetting{{Category: genai.HarmCategoryHateSpeech, Threshold: genai.HarmBlockThresholdBlockOnlyHigh}}, cfg.SafetySettings)
+	assert.Equal(t, "application/json", cfg.ResponseMIMEType)
+
+	// Without a system prompt or parameters, nothing is set and the model's defaults apply.
+	assert.Equal(t, &genai.GenerateContentConfig{}, NewGenerateContentConfig(config.Profile{}, llm.NewRequest("", "hi")))
+}
+
+func TestToContents(t *testing.T) {
+	contents := ToContents([]llm.Message{
+		{Role: llm.RoleUser, Content: "weather in Tokyo and Paris?"},
+		{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{
+			{Name: "weather", Arguments: json.RawMessage(`{"city":"Tokyo"}`)},
+			{Name: "weather", Arguments: json.RawMessage(`{"city":"Paris"}`)},
+		}},
+		{Role: llm.RoleTool, Name: "weather", Content: "sunny"},
+		{Role: llm.RoleTool, Name: "weather", Content: "rainy"},
+	})
+	require.Len(t, contents, 3)
+	assert.Equal(t, genai.RoleModel, contents[1].Role)
+	assert.Equal(t, map[string]any{"city": "Tokyo"}, contents[1].Parts[0].FunctionCall.Args)
+	assert.Equal(t, genai.RoleUser, contents[2].Role)
+	require.Len(t, contents[2].Parts, 2)
+	assert.Equal(t, map[string]any{"output": "rainy"}, contents[2].Parts[1].FunctionResponse.Response)
+}
+
+func TestToContents_Parts(t *testing.T) {
+	contents := ToContents([]llm.Message{{
+		Role:    llm.RoleUser,
+		Content: "what is this?",
+		Parts:   []llm.Part{{Name: "cat.png", MIMEType: "image/png", Data: []byte("png")}},
+	}})
+	require.Len(t, contents, 1)
+	require.Len(t, contents[0].Parts, 2)
+	assert.Equal(t, &genai.Blob{MIMEType: "image/png", Data: []byte("png")}, contents[0].Parts[0].InlineData)
+	assert.Equal(t, "what is this?", contents[0].Parts[1].Text)
+}
+
+func TestCandidateTexts(t *testing.T) {
+	resp := &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{
+			{Index: 0, FinishReason: genai.FinishReasonStop, Content: &genai.Content{Parts: []*genai.Part{{Text: "one"}, {Text: " two"}}}},
+			{Index: 1, Content: &genai.Content{Parts: []*genai.Part{{Text: "other"}}}},
+		},
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 3, CandidatesTokenCount: 5},
+	}
+	assert.Equal(t, []string{"one two", "other"}, candidateTexts(resp))
+
+	meta := responseMetadata(resp)
+	assert.Equal(t, "STOP", meta.FinishReason)
+	assert.Equal(t, &llm.Usage{InputTokens: 3, OutputTokens: 5}, meta.Usage)
+}
@@ -3,42 +3,171 @@ package mock
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/magifd2/llm-cli/internal/config"
 	"github.com/magifd2/llm-cli/internal/llm"
 )
 
 // Provider is a dummy implementation of the LLM Provider interface.
-// It's used for testing and as a fallback when a real provider is not configured or recognized.
-type Provider struct{}
+// It's used for testing: it echoes the request or returns scripted responses and errors,
+// with a configurable latency and chunking of streams.
+type Provider struct {
+	Model      string        // The model name reported in responses.
+	Script     *Script       // The scripted responses, or nil to echo the request.
+	Latency    time.Duration // Delay before the response, or before the first chunk of a stream.
+	ChunkSize  int           // Number of characters per streamed chunk. 0 sends the whole response in one chunk.
+	ChunkDelay time.Duration // Delay between streamed chunks.
+}
 
-// NewProvider is a factory function that returns a new mock provider.
+// NewProvider is a factory function that returns a new mock provider configured by the profile's mock settings.
 func NewProvider(p config.Profile) (llm.Provider, error) {
-	return &Provider{}, nil
+	provider := &Provider{
+		Model:      p.Model,
+		Latency:    time.Duration(p.Mock.Latency),
+		ChunkSize:  p.Mock.ChunkSize,
+		ChunkDelay: time.Duration(p.Mock.ChunkDelay),
+	}
+	if p.Mock.ResponsesFile != "" {
+		path, err := config.ResolvePath(p.Mock.ResponsesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve mock responses file path %s: %w", p.Mock.ResponsesFile, err)
+		}
+		if provider.Script, err = LoadScript(path); err != nil {
+			return nil, err
+		}
+	}
+	return provider, nil
 }
 
 // Chat provides a mock response for a single chat interaction.
-// It returns a formatted string containing the system and user prompts.
-func (p *Provider) Chat(systemPrompt, userPrompt string) (string, error) {
-	response := fmt.Sprintf("\n--- Mock Response ---\nSystem Prompt: %s\nUser Prompt: %s\n---------------------\n", systemPrompt, userPrompt)
-	return response, nil
+// Without a script, or if no scripted response matches, it returns a formatted string containing the system prompt,
+// the number of turns and the last user prompt. Token usage is approximated by counting words.
+func (p *Provider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	scripted := p.respond(req)
+	if err := sleep(ctx, p.latency(scripted)); err != nil {
+		return nil, err
+	}
+	if err := scripted.err(); err != nil {
+		return nil, err
+	}
+	return p.response(req, scripted), nil
 }
 
 // ChatStream provides a mock streaming response.
-// It sends the full mock response as a single chunk to the response channel.
-// The context is checked for cancellation.
-func (p *Provider) ChatStream(ctx context.Context, systemPrompt, userPrompt string, responseChan chan<- string) error {
-	defer close(responseChan)
-	response, _ := p.Chat(systemPrompt, userPrompt)
-	select {
-	case responseChan <- response:
-	case <-ctx.Done():
-		return ctx.Err()
+// It sends the response to the response channel in chunks of ChunkSize characters, waiting ChunkDelay between them.
+// A scripted error is returned after the text has been sent. The context is checked for cancellation.
+func (p *Provider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	scripted := p.respond(req)
+	if err := sleep(ctx, p.latency(scripted)); err != nil {
+		return nil, err
 	}
-	return nil
+	response := p.response(req, scripted)
+	for i, chunk := range p.chunks(response.Text) {
+		if i > 0 {
+			if err := sleep(ctx, p.ChunkDelay); err != nil {
+				return nil, err
+			}
+		}
+		select {
+		case responseChan <- chunk:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if err := scripted.err(); err != nil {
+		return nil, err
+	}
+	response.Text = ""
+	return response, nil
 }
 
 // ValidateConfig always returns nil for the mock provider, as it has no specific configuration requirements.
 func (p *Provider) ValidateConfig() error {
 	return nil
-}
\ No newline at end of file
+}
+
+// respond picks the scripted response for the request. It returns a response with the echo text
+// if there is no script or no scripted response matches.
+func (p *Provider) respond(req llm.Request) ScriptedResponse {
+	var userPrompt string
+	var parts []llm.Part
+	conv := req.Conversation()
+	if len(conv) > 0 {
+		userPrompt = conv[len(conv)-1].Content
+		parts = conv[len(conv)-1].Parts
+	}
+	if p.Script != nil {
+		if scripted, ok := p.Script.Next(userPrompt); ok {
+			return scripted
+		}
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\n--- Mock Response ---\nSystem Prompt: %s\nTurns: %d\n", req.SystemPrompt(), len(conv))
+	// Attached images and documents are only listed when there are any, so the echo of text prompts is unchanged.
+	for _, part := range parts {
+		fmt.Fprintf(&sb, "Part: %s (%s, %d bytes)\n", part.Name, part.MIMEType, len(part.Data))
+	}
+	fmt.Fprintf(&sb, "User Prompt: %s\n---------------------\n", userPrompt)
+	return ScriptedResponse{Text: sb.String()}
+}
+
+// response builds the response to the request from a scripted response.
+func (p *Provider) response(req llm.Request, scripted ScriptedResponse) *llm.Response {
+	var inputTokens int
+	for _, m := range req.Messages {
+		inputTokens += len(strings.Fields(m.Content))
+	}
+	finishReason := scripted.FinishReason
+	if finishReason == "" {
+		finishReason = "stop"
+	}
+	return &llm.Response{
+		Text:         scripted.Text,
+		Model:        p.Model,
+		FinishReason: finishReason,
+		Usage:        &llm.Usage{InputTokens: inputTokens, OutputTokens: len(strings.Fields(scripted.Text))},
+	}
+}
+
+// latency returns the delay before the scripted response.
+func (p *Provider) latency(scripted ScriptedResponse) time.Duration {
+	if scripted.Latency != nil {
+		return *scripted.Latency
+	}
+	return p.Latency
+}
+
+// chunks splits the text into chunks of ChunkSize characters.
+func (p *Provider) chunks(text string) []string {
+	if text == "" {
+		return nil
+	}
+	runes := []rune(text)
+	if p.ChunkSize <= 0 || len(runes) <= p.ChunkSize {
+		return []string{text}
+	}
+	var chunks []string
+	for len(runes) > 0 {
+		n := min(p.ChunkSize, len(runes))
+		chunks = append(chunks, string(runes[:n]))
+		runes = runes[n:]
+	}
+	return chunks
+}
+
+// sleep waits for the duration or until the context is cancelled.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
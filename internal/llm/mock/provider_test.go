@@ -0,0 +1,106 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testScript = `
+responses:
+  - match: weather
+    text: Sunny all day.
+  - text: First answer.
+  - error: overloaded
+    status: 503
+    retry_after: 2s
+  - text: Partial
+    error: connection reset
+`
+
+func newScriptedProvider(t *testing.T, mockSettings config.Mock) llm.Provider {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "responses.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testScript), 0o600))
+	mockSettings.ResponsesFile = path
+	provider, err := NewProvider(config.Profile{Model: "mock-model", Mock: mockSettings})
+	require.NoError(t, err)
+	return provider
+}
+
+func TestProvider_Script(t *testing.T) {
+	provider := newScriptedProvider(t, config.Mock{})
+	ctx := context.Background()
+
+	resp, err := provider.Chat(ctx, llm.NewRequest("", "hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "First answer.", resp.Text, "responses with a match are skipped for other prompts")
+	assert.Equal(t, "mock-model", resp.Model)
+	assert.Equal(t, "stop", resp.FinishReason)
+
+	_, err = provider.Chat(ctx, llm.NewRequest("", "hello"))
+	var statusErr *llm.StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, 503, statusErr.StatusCode)
+	assert.Equal(t, 2*time.Second, statusErr.RetryAfter)
+
+	_, err = provider.Chat(ctx, llm.NewRequest("", "what's the weather?"))
+	assert.EqualError(t, err, "connection reset", "responses without a match apply to every prompt")
+
+	resp, err = provider.Chat(ctx, llm.NewRequest("", "what's the weather?"))
+	require.NoError(t, err)
+	assert.Equal(t, "Sunny all day.", resp.Text, "the script starts over after the last response")
+}
+
+func TestProvider_ChatStream(t *testing.T) {
+	provider := newScriptedProvider(t, config.Mock{ChunkSize: 4})
+
+	var chunks []string
+	responseChan := make(chan string, 10)
+	resp, err := provider.ChatStream(context.Background(), llm.NewRequest("", "weather"), responseChan)
+	require.NoError(t, err)
+	close(responseChan)
+	for chunk := range responseChan {
+		chunks = append(chunks, chunk)
+	}
+	assert.Equal(t, []string{"Sunn", "y al", "l da", "y."}, chunks)
+	assert.Empty(t, resp.Text)
+
+	// A scripted error of a stream is returned after its text has been sent.
+	provider = newScriptedProvider(t, config.Mock{})
+	for range 2 {
+		_, _ = provider.Chat(context.Background(), llm.NewRequest("", "hello"))
+	}
+	responseChan = make(chan string, 10)
+	_, err = provider.ChatStream(context.Background(), llm.NewRequest("", "hello"), responseChan)
+	assert.EqualError(t, err, "connection reset")
+	assert.Equal(t, "Partial", <-responseChan)
+}
+
+func TestProvider_Latency(t *testing.T) {
+	provider := &Provider{Latency: time.Minute}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := provider.Chat(ctx, llm.NewRequest("", "hello"))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "the latency is cut short by the context")
+}
+
+func TestParseScript_Invalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"empty":          "responses: []",
+		"no text":        "responses:\n  - match: x",
+		"invalid status": "responses:\n  - error: x\n    status: 200",
+	} {
+		_, err := ParseScript([]byte(data))
+		assert.Error(t, err, name)
+	}
+}
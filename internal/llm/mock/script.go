@@ -0,0 +1,92 @@
+package mock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/magifd2/llm-cli/internal/llm"
+	"gopkg.in/yaml.v3"
+)
+
+// ScriptedResponse is an answer, or an error, that the mock provider returns instead of echoing the request.
+type ScriptedResponse struct {
+	Match        string         `yaml:"match"`         // If set, the response is only used when the last user message contains this text.
+	Text         string         `yaml:"text"`          // The response text.
+	FinishReason string         `yaml:"finish_reason"` // The reported finish reason. Default: "stop".
+	Error        string         `yaml:"error"`         // If set, the call fails with this message. A stream fails after sending Text.
+	Status       int            `yaml:"status"`        // The HTTP status of the error (e.g., 429 or 503), so it is retried like an API error.
+	RetryAfter   time.Duration  `yaml:"retry_after"`   // The delay requested by the error, as by a Retry-After header.
+	Latency      *time.Duration `yaml:"latency"`       // Overrides the profile's latency for this response.
+}
+
+// err returns the error of the response, or nil if it succeeds.
+func (r ScriptedResponse) err() error {
+	if r.Error == "" {
+		return nil
+	}
+	if r.Status != 0 {
+		return &llm.StatusError{StatusCode: r.Status, RetryAfter: r.RetryAfter, Message: fmt.Sprintf("mock error (status %d): %s", r.Status, r.Error)}
+	}
+	return errors.New(r.Error)
+}
+
+// scriptFile is the top-level structure of a responses file.
+type scriptFile struct {
+	Responses []ScriptedResponse `yaml:"responses"`
+}
+
+// Script hands out scripted responses in order, starting over after the last one.
+// It is safe for concurrent use.
+type Script struct {
+	responses []ScriptedResponse
+	mu        sync.Mutex
+	next      int
+}
+
+// LoadScript reads a responses file in YAML format.
+func LoadScript(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading mock responses file: %w", err)
+	}
+	return ParseScript(data)
+}
+
+// ParseScript parses the contents of a responses file.
+func ParseScript(data []byte) (*Script, error) {
+	var f scriptFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("error parsing mock responses file: %w", err)
+	}
+	if len(f.Responses) == 0 {
+		return nil, fmt.Errorf("mock responses file does not define any responses")
+	}
+	for i, r := range f.Responses {
+		if r.Text == "" && r.Error == "" {
+			return nil, fmt.Errorf("mock response %d has neither text nor error", i+1)
+		}
+		if r.Status != 0 && (r.Status < 400 || r.Status > 599) {
+			return nil, fmt.Errorf("mock response %d has an invalid status %d: must be between 400 and 599", i+1, r.Status)
+		}
+	}
+	return &Script{responses: f.Responses}, nil
+}
+
+// Next returns the next response whose match applies to the prompt, skipping the others.
+// It returns false if no response matches.
+func (s *Script) Next(prompt string) (ScriptedResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.responses {
+		index := (s.next + i) % len(s.responses)
+		if r := s.responses[index]; strings.Contains(prompt, r.Match) {
+			s.next = index + 1
+			return r, true
+		}
+	}
+	return ScriptedResponse{}, false
+}
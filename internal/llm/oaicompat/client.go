@@ -0,0 +1,269 @@
+// Package oaicompat implements the client side of the OpenAI Chat Completions wire protocol.
+// It is shared by the providers for OpenAI, Azure OpenAI and compatible servers such as LM Studio,
+// vLLM, the llama.cpp server, Groq and Mistral, which differ only in their URLs and authentication.
+package oaicompat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+)
+
+// DefaultEndpoint is the Chat Completions endpoint of the OpenAI API, used when a profile sets none.
+const DefaultEndpoint = "https://api.openai.com/v1/chat/completions"
+
+// Auth sets the authentication headers of a request.
+type Auth func(req *http.Request)
+
+// BearerAuth sends the token in an `Authorization: Bearer` header. An empty token sends no header,
+// for local servers that need no authentication.
+func BearerAuth(token string) Auth {
+	return func(req *http.Request) {
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+}
+
+// HeaderAuth sends the key in the named header, e.g., the `api-key` header of Azure OpenAI.
+func HeaderAuth(name, key string) Auth {
+	return func(req *http.Request) {
+		req.Header.Set(name, key)
+	}
+}
+
+// Client sends Chat Completions requests to an OpenAI-compatible server.
+type Client struct {
+	Endpoint   string       // The full URL of the chat completions operation, used as is.
+	Auth       Auth         // Sets the authentication headers. Nil sends none.
+	HTTPClient *http.Client // The HTTP client to use. Nil uses a default client.
+}
+
+// ChatCompletionsURL resolves the chat completions URL of a profile endpoint.
+// The endpoint may be the full URL of the operation or a base URL such as `https://api.groq.com/openai/v1`;
+// a base URL without a path is assumed to serve the API under `/v1`. An empty endpoint resolves to DefaultEndpoint.
+func ChatCompletionsURL(endpoint string) string {
+	if endpoint == "" {
+		return DefaultEndpoint
+	}
+	return operationURL(endpoint, "chat/completions")
+}
+
+// ModelsURL resolves the URL of the models operation of a profile endpoint, which sits next to chat completions.
+func ModelsURL(endpoint string) string {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	return operationURL(endpoint, "models")
+}
+
+// operationURL replaces the chat completions operation at the end of an endpoint's path, or appends one to a base URL.
+// The query, e.g., an `api-version`, is kept.
+func operationURL(endpoint, operation string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		// Not an absolute URL; the HTTP client reports it.
+		return endpoint
+	}
+	path := strings.TrimSuffix(strings.TrimRight(u.Path, "/"), "/chat/completions")
+	if path == "" {
+		path = "/v1"
+	}
+	u.Path = path + "/" + operation
+	u.RawPath = ""
+	return u.String()
+}
+
+// newRequest creates a request with the authentication headers set.
+func (c *Client) newRequest(ctx context.Context, method, endpoint string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Auth != nil {
+		c.Auth(req)
+	}
+	return req, nil
+}
+
+// do sends a request and returns the response once the status is checked. The caller must close the response body.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	client := c.HTTPClient
+	if client == nil {
+		client = &http.Client{}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request to openai-compatible api: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, llm.NewStatusError(resp, fmt.Sprintf("openai-compatible api request failed with status %d: %s", resp.StatusCode, string(body)))
+	}
+	return resp, nil
+}
+
+// postChat sends a chat request body to the endpoint.
+func (c *Client) postChat(ctx context.Context, model string, req llm.Request, stream bool, gen config.Generation) (*http.Response, error) {
+	body, err := newChatRequest(model, req, stream, gen)
+	if err != nil {
+		return nil, err
+	}
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling request body: %w", err)
+	}
+	httpReq, err := c.newRequest(ctx, "POST", c.Endpoint, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	return c.do(httpReq)
+}
+
+// Chat sends a chat request and returns a single, complete response.
+// The model is reported if the server does not echo it back; it may be empty for servers that route by URL.
+func (c *Client) Chat(ctx context.Context, model string, req llm.Request, gen config.Generation) (*llm.Response, error) {
+	resp, err := c.postChat(ctx, model, req, false, gen)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var chatResp chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return nil, fmt.Errorf("error decoding openai response: %w", err)
+	}
+	if len(chatResp.Choices) == 0 {
+		return nil, fmt.Errorf("no choices returned from openai-compatible api")
+	}
+
+	if chatResp.Model != "" {
+		model = chatResp.Model
+	}
+	return &llm.Response{
+		Text:         chatResp.Choices[0].Message.Content,
+		ToolCalls:    fromToolCalls(chatResp.Choices[0].Message.ToolCalls),
+		Model:        model,
+		FinishReason: chatResp.Choices[0].FinishReason,
+		Usage:        chatResp.Usage.toLLMUsage(),
+	}, nil
+}
+
+// ChatStream sends a streaming chat request, sends the content deltas to the channel and returns
+// the response metadata and tool calls once the stream ends. The caller is responsible for closing the channel.
+// An error frame in the stream, either an "error" event or a chunk carrying an error, ends it with an error.
+func (c *Client) ChatStream(ctx context.Context, model string, req llm.Request, gen config.Generation, responseChan chan<- string) (*llm.Response, error) {
+	resp, err := c.postChat(ctx, model, req, true, gen)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &llm.Response{Model: model}
+	var toolCalls []toolCall
+	events := newEventReader(resp.Body)
+	for {
+		ev, err := events.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading stream: %w", err)
+		}
+		if ev.Data == "[DONE]" {
+			break
+		}
+
+		var chunk streamChunk
+		parseErr := json.Unmarshal([]byte(ev.Data), &chunk)
+		if ev.Name == "error" {
+			message := ev.Data
+			if parseErr == nil && chunk.errorMessage() != "" {
+				message = chunk.errorMessage()
+			}
+			return nil, fmt.Errorf("streaming error: %s", message)
+		}
+		if parseErr != nil {
+			// Skip payloads that are not chunks, such as metadata some servers interleave.
+			continue
+		}
+		if message := chunk.errorMessage(); message != "" {
+			return nil, fmt.Errorf("streaming error: %s", message)
+		}
+
+		// Record the response metadata. The usage arrives in a final chunk without choices.
+		if chunk.Model != "" {
+			result.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			result.Usage = chunk.Usage.toLLMUsage()
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		choice := chunk.Choices[0]
+		if choice.FinishReason != "" {
+			result.FinishReason = choice.FinishReason
+		}
+		// Assemble the tool calls from their fragments.
+		for _, fragment := range choice.Delta.ToolCalls {
+			if fragment.Index < 0 {
+				return nil, fmt.Errorf("streaming error: invalid tool call index %d", fragment.Index)
+			}
+			for len(toolCalls) <= fragment.Index {
+				toolCalls = append(toolCalls, toolCall{Type: "function"})
+			}
+			tc := &toolCalls[fragment.Index]
+			if fragment.ID != "" {
+				tc.ID = fragment.ID
+			}
+			tc.Function.Name += fragment.Function.Name
+			tc.Function.Arguments += fragment.Function.Arguments
+		}
+
+		select {
+		case responseChan <- choice.Delta.Content:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	result.ToolCalls = fromToolCalls(toolCalls)
+	return result, nil
+}
+
+// Models lists the IDs of the models served at the URL of the models operation.
+func (c *Client) Models(ctx context.Context, modelsURL string) ([]string, error) {
+	req, err := c.newRequest(ctx, "GET", modelsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var modelsResp modelsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&modelsResp); err != nil {
+		return nil, fmt.Errorf("error decoding models response: %w", err)
+	}
+
+	var modelIDs []string
+	for _, model := range modelsResp.Data {
+		modelIDs = append(modelIDs, model.ID)
+	}
+	return modelIDs, nil
+}
@@ -0,0 +1,201 @@
+package oaicompat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collect drains a response channel filled by ChatStream.
+func collect(responseChan chan string) string {
+	close(responseChan)
+	var text string
+	for chunk := range responseChan {
+		text += chunk
+	}
+	return text
+}
+
+func TestChatCompletionsURL(t *testing.T) {
+	tests := map[string]string{
+		"": DefaultEndpoint,
+		"http://localhost:1234/v1/chat/completions":             "http://localhost:1234/v1/chat/completions",
+		"http://localhost:1234/v1/chat/completions/":            "http://localhost:1234/v1/chat/completions",
+		"http://localhost:1234/v1":                              "http://localhost:1234/v1/chat/completions",
+		"http://localhost:8000":                                 "http://localhost:8000/v1/chat/completions",
+		"https://api.groq.com/openai/v1/":                       "https://api.groq.com/openai/v1/chat/completions",
+		"https://example.com/v1/chat/completions?api-version=1": "https://example.com/v1/chat/completions?api-version=1",
+	}
+	for endpoint, want := range tests {
+		assert.Equal(t, want, ChatCompletionsURL(endpoint), endpoint)
+	}
+
+	assert.Equal(t, "https://api.openai.com/v1/models", ModelsURL(""))
+	assert.Equal(t, "http://localhost:1234/v1/models", ModelsURL("http://localhost:1234/v1/chat/completions"))
+}
+
+func TestClient_Chat(t *testing.T) {
+	var got map[string]any
+	var gotHeader http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		fmt.Fprint(w, `{
+			"model": "gpt-4o-2024-08-06",
+			"choices": [{"message": {"role": "assistant", "content": "", "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "lookup", "arguments": ""}}]}, "finish_reason": "tool_calls"}],
+			"usage": {"prompt_tokens": 7, "completion_tokens": 3}
+		}`)
+	}))
+	defer server.Close()
+
+	client := &Client{Endpoint: server.URL, Auth: BearerAuth("sk-test")}
+	maxTokens := 64
+	resp, err := client.Chat(context.Background(), "gpt-4o", llm.NewRequest("sys", "hi"), config.Generation{MaxTokens: &maxTokens})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Bearer sk-test", gotHeader.Get("Authorization"))
+	assert.Equal(t, "gpt-4o", got["model"])
+	assert.Equal(t, float64(64), got["max_tokens"])
+	assert.Nil(t, got["stream"])
+
+	assert.Equal(t, "gpt-4o-2024-08-06", resp.Model)
+	assert.Equal(t, "tool_calls", resp.FinishReason)
+	assert.Equal(t, []llm.ToolCall{{ID: "call_1", Name: "lookup", Arguments: json.RawMessage("{}")}}, resp.ToolCalls)
+	assert.Equal(t, &llm.Usage{InputTokens: 7, OutputTokens: 3}, resp.Usage)
+}
+
+func TestClient_ChatStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"), "no key, no header")
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": ping\n\n")
+		fmt.Fprint(w, "data: {\"model\":\"m\",\"choices\":[{\"delta\":{\"content\":\"He said \\\"error\\\": none\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"id\":\"call_1\",\"function\":{\"name\":\"lookup\",\"arguments\":\"{\\\"q\\\":\"}}]}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\"1}\"}}]},\"finish_reason\":\"tool_calls\"}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":5,\"completion_tokens\":2}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	client := &Client{Endpoint: server.URL, Auth: BearerAuth("")}
+	responseChan := make(chan string, 10)
+	resp, err := client.ChatStream(context.Background(), "m", llm.NewRequest("", "hi"), config.Generation{}, responseChan)
+	require.NoError(t, err)
+
+	assert.Equal(t, `He said "error": none`, collect(responseChan), "content mentioning an error is not an error frame")
+	assert.Equal(t, "m", resp.Model)
+	assert.Equal(t, "tool_calls", resp.FinishReason)
+	assert.Equal(t, []llm.ToolCall{{ID: "call_1", Name: "lookup", Arguments: json.RawMessage(`{"q":1}`)}}, resp.ToolCalls)
+	assert.Equal(t, &llm.Usage{InputTokens: 5, OutputTokens: 2}, resp.Usage)
+}
+
+func TestClient_ChatStreamErrorFrames(t *testing.T) {
+	tests := map[string]struct {
+		frame string
+		want  string
+	}{
+		"openai error object": {
+			frame: "data: {\"error\":{\"message\":\"Rate limit reached\",\"type\":\"rate_limit_error\"}}\n\n",
+			want:  "streaming error: rate_limit_error: Rate limit reached",
+		},
+		"error string": {
+			frame: "data: {\"error\":\"model not loaded\"}\n\n",
+			want:  "streaming error: model not loaded",
+		},
+		"vllm error object": {
+			frame: "data: {\"object\":\"error\",\"message\":\"context length exceeded\"}\n\n",
+			want:  "streaming error: context length exceeded",
+		},
+		"error event": {
+			frame: "event: error\ndata: upstream closed\n\n",
+			want:  "streaming error: upstream closed",
+		},
+		"negative tool call index": {
+			frame: "data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":-1,\"function\":{\"name\":\"f\"}}]}}]}\n\n",
+			want:  "streaming error: invalid tool call index -1",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"partial\"}}]}\n\n")
+				fmt.Fprint(w, tt.frame)
+			}))
+			defer server.Close()
+
+			client := &Client{Endpoint: server.URL}
+			responseChan := make(chan string, 10)
+			_, err := client.ChatStream(context.Background(), "m", llm.NewRequest("", "hi"), config.Generation{}, responseChan)
+			require.Error(t, err)
+			assert.Equal(t, tt.want, err.Error())
+		})
+	}
+}
+
+func TestClient_Models(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/models", r.URL.Path)
+		assert.Equal(t, "key", r.Header.Get("api-key"))
+		fmt.Fprint(w, `{"data": [{"id": "a"}, {"id": "b"}]}`)
+	}))
+	defer server.Close()
+
+	client := &Client{Auth: HeaderAuth("api-key", "key")}
+	models, err := client.Models(context.Background(), ModelsURL(server.URL))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, models)
+}
+
+func TestValidateProfile(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key.json")
+	require.NoError(t, os.WriteFile(keyFile, []byte(`{"openai_api_key": "sk-file"}`), 0600))
+
+	assert.Error(t, ValidateProfile("OpenAI", config.Profile{}))
+	assert.Error(t, ValidateProfile("OpenAI", config.Profile{Model: "gpt-4o"}), "the default endpoint needs a key")
+	assert.NoError(t, ValidateProfile("OpenAI", config.Profile{Model: "local", Endpoint: "http://localhost:1234/v1"}))
+	assert.NoError(t, ValidateProfile("OpenAI", config.Profile{Model: "gpt-4o", CredentialsFile: keyFile}))
+	assert.Error(t, ValidateProfile("OpenAI", config.Profile{Model: "gpt-4o", CredentialsFile: filepath.Join(dir, "missing.json")}))
+
+	key, err := APIKey(config.Profile{APIKey: "sk-profile", CredentialsFile: keyFile})
+	require.NoError(t, err)
+	assert.Equal(t, "sk-file", key, "the credentials file takes precedence")
+}
+
+func TestNewChatRequest_Parts(t *testing.T) {
+	req := llm.NewRequest("", "what is this?")
+	req.Messages[0].Parts = []llm.Part{
+		{Name: "cat.png", MIMEType: "image/png", Data: []byte("png")},
+		{Name: "report.pdf", MIMEType: "application/pdf", Data: []byte("pdf")},
+	}
+	body, err := newChatRequest("gpt-4o", req, false, config.Generation{})
+	require.NoError(t, err)
+
+	data, err := json.Marshal(body.Messages[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"role": "user", "content": [
+		{"type": "text", "text": "what is this?"},
+		{"type": "image_url", "image_url": {"url": "data:image/png;base64,cG5n"}},
+		{"type": "file", "file": {"filename": "report.pdf", "file_data": "data:application/pdf;base64,cGRm"}}
+	]}`, string(data))
+
+	// Without parts, the content stays a plain string.
+	data, err = json.Marshal(message{Role: "user", Content: "hi"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"role": "user", "content": "hi"}`, string(data))
+
+	req.Messages[0].Parts = []llm.Part{{Name: "notes.docx", MIMEType: "application/msword"}}
+	_, err = newChatRequest("gpt-4o", req, false, config.Generation{})
+	assert.ErrorContains(t, err, "does not accept application/msword input")
+}
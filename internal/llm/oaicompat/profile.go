@@ -0,0 +1,50 @@
+package oaicompat
+
+import (
+	"fmt"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/httpclient"
+)
+
+// NewClient returns a client for the chat completions endpoint of a profile, authenticated with a bearer token
+// from the profile's API key or credentials file. This is how OpenAI and most compatible servers authenticate.
+func NewClient(profile config.Profile) (*Client, error) {
+	apiKey, err := APIKey(profile)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := httpclient.New(profile)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		Endpoint:   ChatCompletionsURL(profile.Endpoint),
+		Auth:       BearerAuth(apiKey),
+		HTTPClient: httpClient,
+	}, nil
+}
+
+// APIKey retrieves the API key from the profile or a credentials file. It is empty if neither is set,
+// since local servers usually need no key.
+func APIKey(profile config.Profile) (string, error) {
+	if profile.CredentialsFile != "" {
+		return config.LoadCredentialField(profile.CredentialsFile, "openai_api_key")
+	}
+	return profile.APIKey, nil
+}
+
+// ValidateProfile checks the configuration shared by the OpenAI-compatible providers.
+// It requires a model and, for the default OpenAI endpoint, an API key or credentials file; custom endpoints
+// (e.g., LM Studio) might not need one. The provider name is used in the error messages.
+func ValidateProfile(providerName string, profile config.Profile) error {
+	if profile.Model == "" {
+		return fmt.Errorf("%s provider requires a 'model' to be specified in the profile", providerName)
+	}
+
+	if ChatCompletionsURL(profile.Endpoint) == DefaultEndpoint && profile.APIKey == "" && profile.CredentialsFile == "" {
+		return fmt.Errorf("%s provider (using default endpoint) requires either 'api-key' or 'credentials-file' to be set in the profile", providerName)
+	}
+
+	return config.CheckCredentialsFile(profile.CredentialsFile)
+}
@@ -0,0 +1,71 @@
+package oaicompat
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// maxEventLineSize bounds a single line of a stream. Chunks carrying large tool call arguments
+// can exceed the 64 KiB default of bufio.Scanner.
+const maxEventLineSize = 4 << 20
+
+// event is a server-sent event.
+type event struct {
+	Name string // The "event:" field; empty for the default "message" type.
+	Data string // The "data:" lines, joined by newlines.
+}
+
+// eventReader reads server-sent events (https://html.spec.whatwg.org/multipage/server-sent-events.html).
+// Each event ends with a blank line and may span several "data:" lines; comments and the "id:" and "retry:" fields are ignored.
+type eventReader struct {
+	scanner *bufio.Scanner
+}
+
+// newEventReader returns an eventReader reading from r.
+func newEventReader(r io.Reader) *eventReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventLineSize)
+	return &eventReader{scanner: scanner}
+}
+
+// Next returns the next event. It returns io.EOF when the stream ends.
+// An event that is not followed by a blank line before the end of the stream is still returned, since some servers omit it.
+func (r *eventReader) Next() (event, error) {
+	var ev event
+	var data []string
+	hasData := false
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		if line == "" {
+			if hasData {
+				ev.Data = strings.Join(data, "\n")
+				return ev, nil
+			}
+			// An event without data is not dispatched.
+			ev = event{}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // A comment, often used as a keep-alive.
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "data":
+			data = append(data, value)
+			hasData = true
+		case "event":
+			ev.Name = value
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return event{}, err
+	}
+	if hasData {
+		ev.Data = strings.Join(data, "\n")
+		return ev, nil
+	}
+	return event{}, io.EOF
+}
@@ -0,0 +1,69 @@
+package oaicompat
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readEvents(t *testing.T, stream string) []event {
+	t.Helper()
+	reader := newEventReader(strings.NewReader(stream))
+	var events []event
+	for {
+		ev, err := reader.Next()
+		if err == io.EOF {
+			return events
+		}
+		require.NoError(t, err)
+		events = append(events, ev)
+	}
+}
+
+func TestEventReader(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   []event
+	}{
+		{
+			name:   "single data lines",
+			stream: "data: {\"a\":1}\n\ndata: [DONE]\n\n",
+			want:   []event{{Data: `{"a":1}`}, {Data: "[DONE]"}},
+		},
+		{
+			name:   "multi-line data",
+			stream: "data: {\"a\":\ndata: 1}\n\n",
+			want:   []event{{Data: "{\"a\":\n1}"}},
+		},
+		{
+			name:   "event field, comments, ids and CRLF",
+			stream: ": keep-alive\r\nid: 7\r\nevent: error\r\ndata:{\"error\":\"boom\"}\r\n\r\n",
+			want:   []event{{Name: "error", Data: `{"error":"boom"}`}},
+		},
+		{
+			name:   "events without data are not dispatched",
+			stream: "event: ping\n\ndata: x\n\n",
+			want:   []event{{Data: "x"}},
+		},
+		{
+			name:   "missing blank line at the end",
+			stream: "data: x\n\ndata: y",
+			want:   []event{{Data: "x"}, {Data: "y"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, readEvents(t, tt.stream))
+		})
+	}
+}
+
+func TestEventReader_LongLine(t *testing.T) {
+	long := strings.Repeat("x", 200*1024)
+	assert.Equal(t, []event{{Data: long}}, readEvents(t, "data: "+long+"\n\n"))
+}
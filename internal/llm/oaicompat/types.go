@@ -0,0 +1,295 @@
+package oaicompat
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+)
+
+// chatRequest represents the JSON structure for requests to the Chat Completions API.
+type chatRequest struct {
+	Model          string          `json:"model,omitempty"`           // The name of the model to use. Azure takes the deployment from the URL instead.
+	Messages       []message       `json:"messages"`                  // A list of messages in the conversation history.
+	Tools          []tool          `json:"tools,omitempty"`           // Functions the model may call.
+	Stream         bool            `json:"stream,omitempty"`          // Whether to stream the response. Omitted if false.
+	StreamOptions  *streamOptions  `json:"stream_options,omitempty"`  // Options for streaming responses. Only set when streaming.
+	ResponseFormat *responseFormat `json:"response_format,omitempty"` // Requests structured output. Only set when the request carries a schema.
+
+	// Generation parameters. Unset values are omitted so the server's defaults apply.
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	TopK        *int     `json:"top_k,omitempty"` // Not part of the OpenAI API, but accepted by many compatible servers.
+	MaxTokens   *int     `json:"max_tokens,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Seed        *int64   `json:"seed,omitempty"`
+}
+
+// streamOptions represents the "stream_options" field of a streaming request.
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"` // Ask the server to send token usage in a final chunk.
+}
+
+// responseFormat represents the "response_format" field used to request structured output.
+type responseFormat struct {
+	Type       string      `json:"type"` // Always "json_schema".
+	JSONSchema *jsonSchema `json:"json_schema"`
+}
+
+// jsonSchema names the JSON schema the reply must conform to.
+type jsonSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+// message represents a message in the Chat Completions format.
+type message struct {
+	Role       string        `json:"role"`
+	Content    string        `json:"content"`
+	ToolCalls  []toolCall    `json:"tool_calls,omitempty"`   // The tools the assistant asked to call.
+	ToolCallID string        `json:"tool_call_id,omitempty"` // The call a tool message answers.
+	Parts      []contentPart `json:"-"`                      // If set, sent as the content instead of the plain text.
+}
+
+// MarshalJSON writes the content as an array of parts if the message carries images or files.
+func (m message) MarshalJSON() ([]byte, error) {
+	type plain message
+	if len(m.Parts) == 0 {
+		return json.Marshal(plain(m))
+	}
+	return json.Marshal(struct {
+		plain
+		Content []contentPart `json:"content"`
+	}{plain(m), m.Parts})
+}
+
+// contentPart is an element of the content array of a message with images or files.
+type contentPart struct {
+	Type     string    `json:"type"` // "text", "image_url" or "file".
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+	File     *file     `json:"file,omitempty"`
+}
+
+// imageURL holds an image, sent inline as a data URI.
+type imageURL struct {
+	URL string `json:"url"`
+}
+
+// file holds a document, such as a PDF, sent inline as a data URI.
+type file struct {
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data"`
+}
+
+// toContentParts converts the text and the parts of a message to content parts.
+// Images are sent as image_url parts and PDF documents as file parts.
+func toContentParts(m llm.Message) ([]contentPart, error) {
+	var parts []contentPart
+	if m.Content != "" {
+		parts = append(parts, contentPart{Type: "text", Text: m.Content})
+	}
+	for _, p := range m.Parts {
+		switch {
+		case p.IsImage():
+			parts = append(parts, contentPart{Type: "image_url", ImageURL: &imageURL{URL: p.DataURI()}})
+		case p.MIMEType == "application/pdf":
+			parts = append(parts, contentPart{Type: "file", File: &file{Filename: p.Name, FileData: p.DataURI()}})
+		default:
+			return nil, llm.UnsupportedPartError("the chat completions API", p)
+		}
+	}
+	return parts, nil
+}
+
+// toolCall represents a tool call made by the assistant.
+type toolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"` // Always "function".
+	Function functionCall `json:"function"`
+}
+
+// functionCall holds the function name and its arguments, encoded as a JSON string.
+type functionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// tool represents a function declared in the "tools" field of a request.
+type tool struct {
+	Type     string   `json:"type"` // Always "function".
+	Function function `json:"function"`
+}
+
+// function describes a function the model may call.
+type function struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"` // A JSON schema of the arguments object.
+}
+
+// toMessages converts messages to the Chat Completions format.
+func toMessages(messages []llm.Message) ([]message, error) {
+	result := make([]message, 0, len(messages))
+	for _, m := range messages {
+		msg := message{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		if len(m.Parts) > 0 {
+			parts, err := toContentParts(m)
+			if err != nil {
+				return nil, err
+			}
+			msg.Parts = parts
+		}
+		for _, tc := range m.ToolCalls {
+			msg.ToolCalls = append(msg.ToolCalls, toolCall{
+				ID:       tc.ID,
+				Type:     "function",
+				Function: functionCall{Name: tc.Name, Arguments: string(tc.Arguments)},
+			})
+		}
+		result = append(result, msg)
+	}
+	return result, nil
+}
+
+// toTools converts tool declarations to the Chat Completions format.
+func toTools(tools []llm.Tool) []tool {
+	var result []tool
+	for _, t := range tools {
+		result = append(result, tool{
+			Type:     "function",
+			Function: function{Name: t.Name, Description: t.Description, Parameters: t.Parameters},
+		})
+	}
+	return result
+}
+
+// fromToolCalls converts the tool calls of a response. Missing arguments become an empty object.
+func fromToolCalls(calls []toolCall) []llm.ToolCall {
+	var result []llm.ToolCall
+	for _, tc := range calls {
+		args := tc.Function.Arguments
+		if strings.TrimSpace(args) == "" {
+			args = "{}"
+		}
+		result = append(result, llm.ToolCall{ID: tc.ID, Name: tc.Function.Name, Arguments: json.RawMessage(args)})
+	}
+	return result
+}
+
+// newChatRequest builds a request body from the request and the profile's generation parameters.
+func newChatRequest(model string, req llm.Request, stream bool, gen config.Generation) (chatRequest, error) {
+	messages, err := toMessages(req.Messages)
+	if err != nil {
+		return chatRequest{}, err
+	}
+	var options *streamOptions
+	if stream {
+		options = &streamOptions{IncludeUsage: true}
+	}
+	var format *responseFormat
+	if len(req.Schema) > 0 {
+		format = &responseFormat{Type: "json_schema", JSONSchema: &jsonSchema{Name: "response", Schema: req.Schema}}
+	}
+	return chatRequest{
+		Model:          model,
+		Messages:       messages,
+		Tools:          toTools(req.Tools),
+		ResponseFormat: format,
+		Stream:         stream,
+		StreamOptions:  options,
+		Temperature:    gen.Temperature,
+		TopP:           gen.TopP,
+		TopK:           gen.TopK,
+		MaxTokens:      gen.MaxTokens,
+		Stop:           gen.Stop,
+		Seed:           gen.Seed,
+	}, nil
+}
+
+// chatResponse represents the JSON structure for a non-streaming response.
+type chatResponse struct {
+	Model   string `json:"model"` // The model that generated the response.
+	Choices []struct {
+		Message      message `json:"message"`       // The assistant's message.
+		FinishReason string  `json:"finish_reason"` // Why the generation ended (e.g., "stop", "length", "tool_calls").
+	} `json:"choices"` // A list of chat completion choices.
+	Usage *usage `json:"usage"` // Token usage for the request.
+}
+
+// usage represents the token usage reported by the server.
+type usage struct {
+	PromptTokens     int `json:"prompt_tokens"`     // The number of tokens in the prompt.
+	CompletionTokens int `json:"completion_tokens"` // The number of tokens generated.
+}
+
+// toLLMUsage converts the reported usage, returning nil if the server did not report it.
+func (u *usage) toLLMUsage() *llm.Usage {
+	if u == nil {
+		return nil
+	}
+	return &llm.Usage{InputTokens: u.PromptTokens, OutputTokens: u.CompletionTokens}
+}
+
+// streamChunk represents a chunk of a streaming response.
+// When usage is requested, the final chunk has no choices and carries the token usage.
+type streamChunk struct {
+	Model   string `json:"model"` // The model that generated the response.
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"` // The content delta for the current chunk.
+			ToolCalls []struct {
+				Index    int          `json:"index"` // The position of the call; its fields arrive in pieces.
+				ID       string       `json:"id"`
+				Function functionCall `json:"function"`
+			} `json:"tool_calls"` // Fragments of the tool calls being generated.
+		} `json:"delta"` // The change in content.
+		FinishReason string `json:"finish_reason"` // Set on the last chunk of a choice.
+	} `json:"choices"` // A list of chat completion choices (usually one for streaming).
+	Usage *usage `json:"usage"` // Token usage, sent in the final chunk.
+
+	// Error frames. OpenAI sends {"error": {...}}; some servers send a string instead,
+	// and vLLM sends {"object": "error", "message": ...}.
+	Error   json.RawMessage `json:"error"`
+	Object  string          `json:"object"`
+	Message string          `json:"message"`
+}
+
+// errorMessage returns the message of an error frame, or "" if the chunk is not an error.
+func (c *streamChunk) errorMessage() string {
+	if c.Object == "error" {
+		return c.Message
+	}
+	if len(c.Error) == 0 || string(c.Error) == "null" {
+		return ""
+	}
+	return decodeErrorMessage(c.Error)
+}
+
+// decodeErrorMessage extracts the message of an "error" value, which is either a string or an object with a "message" field.
+// Anything else is returned as is.
+func decodeErrorMessage(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	var detail struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &detail); err == nil && detail.Message != "" {
+		if detail.Type != "" {
+			return detail.Type + ": " + detail.Message
+		}
+		return detail.Message
+	}
+	return string(raw)
+}
+
+// modelsResponse defines the structure for the response from the /models endpoint.
+type modelsResponse struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
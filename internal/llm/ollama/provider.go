@@ -10,6 +10,7 @@ import (
 	"net/http"
 
 	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/httpclient"
 	"github.com/magifd2/llm-cli/internal/llm"
 )
 
@@ -21,68 +22,197 @@ type Provider struct {
 // ollamaRequest represents the JSON structure for requests to the Ollama chat API.
 type ollamaRequest struct {
 	Model    string         `json:"model"`    // The name of the model to use.
-	Messages []llm.Message `json:"messages"` // A list of messages in the conversation history.
+	Messages []ollamaMessage `json:"messages"` // A list of messages in the conversation history.
+	Tools    []ollamaTool    `json:"tools,omitempty"` // Functions the model may call.
 	Stream   bool           `json:"stream"`   // Whether to stream the response.
+	Options  *ollamaOptions `json:"options,omitempty"` // Generation parameters for the model.
+	Format   json.RawMessage `json:"format,omitempty"` // A JSON schema the reply must conform to.
+}
+
+// ollamaMessage represents a message in the Ollama chat format.
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"` // The tools the assistant asked to call.
+	ToolName  string           `json:"tool_name,omitempty"`  // The tool that produced a tool message.
+	Images    []string         `json:"images,omitempty"`     // Base64-encoded images for vision models.
+}
+
+// ollamaToolCall represents a tool call made by the assistant. Ollama does not assign call IDs.
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"` // The arguments as a JSON object.
+	} `json:"function"`
+}
+
+// ollamaTool represents a function declared in the "tools" field of a request.
+type ollamaTool struct {
+	Type     string `json:"type"` // Always "function".
+	Function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description,omitempty"`
+		Parameters  json.RawMessage `json:"parameters,omitempty"` // A JSON schema of the arguments object.
+	} `json:"function"`
+}
+
+// toOllamaMessages converts messages to the Ollama chat format. Ollama accepts images, but no documents.
+func toOllamaMessages(messages []llm.Message) ([]ollamaMessage, error) {
+	result := make([]ollamaMessage, 0, len(messages))
+	for _, m := range messages {
+		msg := ollamaMessage{Role: m.Role, Content: m.Content, ToolName: m.Name}
+		for _, part := range m.Parts {
+			if !part.IsImage() {
+				return nil, llm.UnsupportedPartError("ollama", part)
+			}
+			msg.Images = append(msg.Images, part.Base64())
+		}
+		for _, tc := range m.ToolCalls {
+			var call ollamaToolCall
+			call.Function.Name = tc.Name
+			call.Function.Arguments = tc.Arguments
+			msg.ToolCalls = append(msg.ToolCalls, call)
+		}
+		result = append(result, msg)
+	}
+	return result, nil
+}
+
+// toOllamaTools converts tool declarations to the Ollama format.
+func toOllamaTools(tools []llm.Tool) []ollamaTool {
+	var result []ollamaTool
+	for _, t := range tools {
+		tool := ollamaTool{Type: "function"}
+		tool.Function.Name = t.Name
+		tool.Function.Description = t.Description
+		tool.Function.Parameters = t.Parameters
+		result = append(result, tool)
+	}
+	return result
+}
+
+// fromOllamaToolCalls converts the tool calls of a response. Missing arguments become an empty object.
+func fromOllamaToolCalls(calls []ollamaToolCall) []llm.ToolCall {
+	var result []llm.ToolCall
+	for _, tc := range calls {
+		result = append(result, llm.ToolCall{Name: tc.Function.Name, Arguments: llm.ToolArguments(tc.Function.Arguments)})
+	}
+	return result
+}
+
+// ollamaOptions represents the generation parameters accepted in the "options" field of the Ollama chat API.
+type ollamaOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	TopK        *int     `json:"top_k,omitempty"`
+	NumPredict  *int     `json:"num_predict,omitempty"` // The maximum number of tokens to generate.
+	Stop        []string `json:"stop,omitempty"`
+	Seed        *int64   `json:"seed,omitempty"`
+}
+
+// newOllamaOptions maps the profile's generation parameters to Ollama options.
+// It returns nil if no parameter is set, so the model's defaults apply.
+func newOllamaOptions(gen config.Generation) *ollamaOptions {
+	if gen.Temperature == nil && gen.TopP == nil && gen.TopK == nil && gen.MaxTokens == nil && len(gen.Stop) == 0 && gen.Seed == nil {
+		return nil
+	}
+	return &ollamaOptions{
+		Temperature: gen.Temperature,
+		TopP:        gen.TopP,
+		TopK:        gen.TopK,
+		NumPredict:  gen.MaxTokens,
+		Stop:        gen.Stop,
+		Seed:        gen.Seed,
+	}
 }
 
 // ollamaResponse represents the JSON structure for responses from the Ollama chat API.
 // For streaming, each chunk will contain a message.
+// The final chunk has Done set and carries the token counts.
 type ollamaResponse struct {
-	Message llm.Message `json:"message"` // The message content from the LLM.
+	Model           string      `json:"model"`             // The model that generated the response.
+	Message         ollamaMessage `json:"message"`         // The message content from the LLM.
+	Done            bool        `json:"done"`              // Whether this is the final response chunk.
+	DoneReason      string      `json:"done_reason"`       // Why the generation ended (e.g., "stop", "length").
+	PromptEvalCount int         `json:"prompt_eval_count"` // The number of tokens in the prompt.
+	EvalCount       int         `json:"eval_count"`        // The number of tokens generated.
+}
+
+// metadata returns the response metadata carried by a final response chunk.
+func (r ollamaResponse) metadata() *llm.Response {
+	return &llm.Response{
+		Model:        r.Model,
+		FinishReason: r.DoneReason,
+		Usage:        &llm.Usage{InputTokens: r.PromptEvalCount, OutputTokens: r.EvalCount},
+	}
 }
 
 // Chat sends a chat request to the Ollama API and returns a single, complete response.
-func (p *Provider) Chat(systemPrompt, userPrompt string) (string, error) {
+func (p *Provider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
 	// Determine the API endpoint. Use a default if not specified in the profile.
 	endpoint := p.Profile.Endpoint
 	if endpoint == "" {
 		endpoint = "http://localhost:11434/api/chat"
 	}
 
-	// Build the messages array, including the system prompt if provided.
-	messages := []llm.Message{}
-	if systemPrompt != "" {
-		messages = append(messages, llm.Message{Role: "system", Content: systemPrompt})
-	}
-	messages = append(messages, llm.Message{Role: "user", Content: userPrompt})
-
 	// Construct the request body for a non-streaming chat.
+	messages, err := toOllamaMessages(req.Messages)
+	if err != nil {
+		return nil, err
+	}
 	reqBody := ollamaRequest{
 		Model:    p.Profile.Model,
 		Messages: messages,
+		Tools:    toOllamaTools(req.Tools),
 		Stream:   false, // Explicitly set to false for non-streaming chat.
+		Options:  newOllamaOptions(p.Profile.Generation),
+		Format:   req.Schema,
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("error marshalling request body: %w", err)
+		return nil, fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	// Create an HTTP request with context for cancellation.
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
 	}
+	httpReq.Header.Set("Content-Type", "application/json")
 
 	// Send the HTTP POST request to the Ollama API.
-	resp, err := http.Post(endpoint, "application/json", bytes.NewBuffer(jsonBody))
+	client, err := httpclient.New(p.Profile)
 	if err != nil {
-		return "", fmt.Errorf("error making request to ollama: %w", err)
+		return nil, err
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("error making request to ollama: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check for non-OK HTTP status codes and return an error with the response body.
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("ollama API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, llm.NewStatusError(resp, fmt.Sprintf("ollama API request failed with status %d: %s", resp.StatusCode, string(body)))
 	}
 
 	// Decode the JSON response.
 	var ollamaResp ollamaResponse
 	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
-		return "", fmt.Errorf("error decoding ollama response: %w", err)
+		return nil, fmt.Errorf("error decoding ollama response: %w", err)
 	}
 
-	return ollamaResp.Message.Content, nil
+	result := ollamaResp.metadata()
+	result.Text = ollamaResp.Message.Content
+	result.ToolCalls = fromOllamaToolCalls(ollamaResp.Message.ToolCalls)
+	return result, nil
 }
 
 // ChatStream sends a streaming chat request to the Ollama API and sends response chunks to a channel.
-func (p *Provider) ChatStream(ctx context.Context, systemPrompt, userPrompt string, responseChan chan<- string) error {
-	defer close(responseChan)
+func (p *Provider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	// Note: The caller is responsible for closing the responseChan.
 
 	// Determine the API endpoint. Use a default if not specified in the profile.
 	endpoint := p.Profile.Endpoint
@@ -90,46 +220,51 @@ func (p *Provider) ChatStream(ctx context.Context, systemPrompt, userPrompt stri
 		endpoint = "http://localhost:11434/api/chat"
 	}
 
-	// Build the messages array, including the system prompt if provided.
-	messages := []llm.Message{}
-	if systemPrompt != "" {
-		messages = append(messages, llm.Message{Role: "system", Content: systemPrompt})
-	}
-	messages = append(messages, llm.Message{Role: "user", Content: userPrompt})
-
 	// Construct the request body for a streaming chat.
+	messages, err := toOllamaMessages(req.Messages)
+	if err != nil {
+		return nil, err
+	}
 	reqBody := ollamaRequest{
 		Model:    p.Profile.Model,
 		Messages: messages,
+		Tools:    toOllamaTools(req.Tools),
 		Stream:   true,
+		Options:  newOllamaOptions(p.Profile.Generation),
+		Format:   req.Schema,
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
-		return fmt.Errorf("error marshalling request body: %w", err)
+		return nil, fmt.Errorf("error marshalling request body: %w", err)
 	}
 
 	// Create an HTTP request with context for cancellation.
-	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return fmt.Errorf("error creating request: %w", err)
+		return nil, fmt.Errorf("error creating request: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	client, err := httpclient.New(p.Profile)
 	if err != nil {
-		return fmt.Errorf("error making request to ollama: %w", err)
+		return nil, err
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("error making request to ollama: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check for non-OK HTTP status codes.
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("ollama API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, llm.NewStatusError(resp, fmt.Sprintf("ollama API request failed with status %d: %s", resp.StatusCode, string(body)))
 	}
 
 	// Read and process the streaming response line by line.
+	result := &llm.Response{}
+	var toolCalls []llm.ToolCall
 	scanner := bufio.NewScanner(resp.Body)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -140,23 +275,30 @@ func (p *Provider) ChatStream(ctx context.Context, systemPrompt, userPrompt stri
 		var streamResp ollamaResponse
 		// Unmarshal each line as a JSON response chunk.
 		if err := json.Unmarshal([]byte(line), &streamResp); err != nil {
-			return fmt.Errorf("error decoding ollama stream response: %w", err)
+			return nil, fmt.Errorf("error decoding ollama stream response: %w", err)
+		}
+
+		// Tool calls arrive complete, but not necessarily in the final chunk.
+		toolCalls = append(toolCalls, fromOllamaToolCalls(streamResp.Message.ToolCalls)...)
+		if streamResp.Done {
+			result = streamResp.metadata()
 		}
 
 		// Send the message content to the response channel or stop if context is cancelled.
 		select {
 		case responseChan <- streamResp.Message.Content:
 		case <-ctx.Done():
-			return ctx.Err()
+			return nil, ctx.Err()
 		}
 	}
 
 	// Check for any errors during scanning.
 	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading stream: %w", err)
+		return nil, fmt.Errorf("error reading stream: %w", err)
 	}
 
-	return nil
+	result.ToolCalls = toolCalls
+	return result, nil
 }
 
 // NewProvider is a factory function that returns a new Ollama provider.
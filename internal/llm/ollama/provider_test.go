@@ -0,0 +1,159 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collect drains a response channel filled by ChatStream.
+func collect(responseChan chan string) string {
+	close(responseChan)
+	var text string
+	for chunk := range responseChan {
+		text += chunk
+	}
+	return text
+}
+
+func TestProvider_Chat(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		fmt.Fprint(w, `{
+			"model": "llama3.1:8b",
+			"message": {"role": "assistant", "content": "", "tool_calls": [{"function": {"name": "weather", "arguments": {"city": "Tokyo"}}}]},
+			"done": true,
+			"done_reason": "stop",
+			"prompt_eval_count": 26,
+			"eval_count": 12
+		}`)
+	}))
+	defer server.Close()
+
+	temperature, maxTokens := 0.3, 128
+	p := &Provider{Profile: config.Profile{
+		Model:      "llama3.1",
+		Endpoint:   server.URL,
+		Generation: config.Generation{Temperature: &temperature, MaxTokens: &maxTokens, Stop: []string{"END"}},
+	}}
+	req := llm.Request{
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: "be brief"},
+			{Role: llm.RoleUser, Content: "weather?", Parts: []llm.Part{{Name: "sky.png", MIMEType: "image/png", Data: []byte("png")}}},
+			{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{Name: "weather", Arguments: json.RawMessage(`{"city":"Paris"}`)}}},
+			{Role: llm.RoleTool, Name: "weather", Content: "rainy"},
+		},
+		Tools:  []llm.Tool{{Name: "weather", Description: "Get the weather", Parameters: json.RawMessage(`{"type":"object"}`)}},
+		Schema: json.RawMessage(`{"type":"object"}`),
+	}
+
+	resp, err := p.Chat(context.Background(), req)
+	require.NoError(t, err)
+
+	assert.Equal(t, "llama3.1", got["model"])
+	assert.Equal(t, false, got["stream"])
+	assert.Equal(t, map[string]any{"temperature": 0.3, "num_predict": float64(128), "stop": []any{"END"}}, got["options"])
+	assert.Equal(t, map[string]any{"type": "object"}, got["format"])
+	messages := got["messages"].([]any)
+	require.Len(t, messages, 4)
+	assert.Equal(t, map[string]any{"role": "system", "content": "be brief"}, messages[0])
+	assert.Equal(t, []any{"cG5n"}, messages[1].(map[string]any)["images"])
+	assert.Equal(t, []any{map[string]any{"function": map[string]any{"name": "weather", "arguments": map[string]any{"city": "Paris"}}}},
+		messages[2].(map[string]any)["tool_calls"])
+	assert.Equal(t, "weather", messages[3].(map[string]any)["tool_name"])
+	tools := got["tools"].([]any)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "function", tools[0].(map[string]any)["type"])
+
+	assert.Equal(t, "llama3.1:8b", resp.Model)
+	assert.Equal(t, "stop", resp.FinishReason)
+	assert.Equal(t, &llm.Usage{InputTokens: 26, OutputTokens: 12}, resp.Usage)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "weather", resp.ToolCalls[0].Name)
+	assert.JSONEq(t, `{"city":"Tokyo"}`, string(resp.ToolCalls[0].Arguments))
+}
+
+func TestProvider_Chat_NoOptions(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		fmt.Fprint(w, `{"model": "llama3", "message": {"role": "assistant", "content": "Hi!"}, "done": true, "done_reason": "stop"}`)
+	}))
+	defer server.Close()
+
+	p := &Provider{Profile: config.Profile{Model: "llama3", Endpoint: server.URL}}
+	resp, err := p.Chat(context.Background(), llm.NewRequest("", "hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "Hi!", resp.Text)
+	assert.Empty(t, resp.ToolCalls)
+	assert.NotContains(t, got, "options", "unset parameters leave the model's defaults")
+	assert.NotContains(t, got, "format")
+	assert.NotContains(t, got, "tools")
+}
+
+func TestProvider_ChatStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var got map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		assert.Equal(t, true, got["stream"])
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for _, line := range []string{
+			`{"model":"llama3.1","message":{"role":"assistant","content":"Let me "},"done":false}`,
+			`{"model":"llama3.1","message":{"role":"assistant","content":"check.","tool_calls":[{"function":{"name":"weather","arguments":{"city":"Tokyo"}}}]},"done":false}`,
+			``,
+			`{"model":"llama3.1","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":10,"eval_count":4}`,
+		} {
+			fmt.Fprintln(w, line)
+		}
+	}))
+	defer server.Close()
+
+	p := &Provider{Profile: config.Profile{Model: "llama3.1", Endpoint: server.URL}}
+	responseChan := make(chan string, 10)
+	resp, err := p.ChatStream(context.Background(), llm.NewRequest("", "weather?"), responseChan)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Let me check.", collect(responseChan))
+	assert.Equal(t, "llama3.1", resp.Model)
+	assert.Equal(t, "stop", resp.FinishReason)
+	assert.Equal(t, &llm.Usage{InputTokens: 10, OutputTokens: 4}, resp.Usage)
+	// The tool call arrived in a chunk before the final one.
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "weather", resp.ToolCalls[0].Name)
+	assert.JSONEq(t, `{"city":"Tokyo"}`, string(resp.ToolCalls[0].Arguments))
+}
+
+func TestProvider_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"model 'missing' not found"}`)
+	}))
+	defer server.Close()
+
+	p := &Provider{Profile: config.Profile{Model: "missing", Endpoint: server.URL}}
+	_, err := p.Chat(context.Background(), llm.NewRequest("", "hi"))
+	var statusErr *llm.StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	assert.Contains(t, err.Error(), "model 'missing' not found")
+
+	_, err = p.ChatStream(context.Background(), llm.NewRequest("", "hi"), make(chan string, 1))
+	require.ErrorAs(t, err, &statusErr)
+
+	// Ollama accepts images, but no documents.
+	req := llm.NewRequest("", "summarize")
+	req.Messages[0].Parts = []llm.Part{{Name: "report.pdf", MIMEType: "application/pdf"}}
+	_, err = p.Chat(context.Background(), req)
+	assert.ErrorContains(t, err, "ollama does not accept application/pdf input")
+}
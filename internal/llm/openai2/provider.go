@@ -1,125 +1,31 @@
 package openai2
 
 import (
-	"bufio"
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
 	"strings"
 
 	"github.com/magifd2/llm-cli/internal/config"
 	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/magifd2/llm-cli/internal/llm/oaicompat"
 )
 
 // Provider implements the llm.Provider interface for enhanced OpenAI-compatible APIs.
 // It adds support for dynamically selecting models by querying the `/v1/models` endpoint,
-// allowing for features like an "auto" mode.
+// allowing for features like an "auto" mode. The wire protocol is implemented by the oaicompat package.
 type Provider struct {
 	Profile config.Profile // The configuration profile for this OpenAI-compatible instance.
 }
 
-// openAIRequest represents the JSON structure for requests to the OpenAI Chat Completions API.
-type openAIRequest struct {
-	Model    string         `json:"model"`          // The name of the model to use.
-	Messages []llm.Message `json:"messages"`       // A list of messages in the conversation history.
-	Stream   bool           `json:"stream,omitempty"` // Whether to stream the response. Omitted if false.
-}
-
-// openAIResponse represents the JSON structure for a non-streaming response from the OpenAI API.
-type openAIResponse struct {
-	Choices []struct {
-		Message llm.Message `json:"message"` // The assistant's message.
-	} `json:"choices"` // A list of chat completion choices.
-}
-
-// openAIStreamResponse represents a chunk of the streaming response from the OpenAI API.
-type openAIStreamResponse struct {
-	Choices []struct {
-		Delta struct {
-			Content string `json:"content"` // The content delta for the current chunk.
-		} `json:"delta"` // The change in content.
-	} `json:"choices"` // A list of chat completion choices (usually one for streaming).
-}
-
-// openAIAPIKey represents the structure of the OpenAI API key JSON file.
-type openAIAPIKey struct {
-	OpenAIAPIKey string `json:"openai_api_key"`
-}
-
-// openAIModelsResponse defines the structure for the response from the /v1/models endpoint.
-type openAIModelsResponse struct {
-	Data []struct {
-		ID string `json:"id"`
-	} `json:"data"`
-}
-
-// getAPIKey retrieves the API key from the profile or a credentials file.
-func (p *Provider) getAPIKey() (string, error) {
-	if p.Profile.CredentialsFile != "" {
-		return loadOpenAIAPIKeyFromFile(p.Profile.CredentialsFile)
-	}
-	return p.Profile.APIKey, nil
-}
-
-// getAvailableModels fetches the list of available models from the /v1/models endpoint.
-func (p *Provider) getAvailableModels() ([]string, error) {
-	// Trim specific suffixes to get the base endpoint URL.
-	baseEndpoint := strings.TrimSuffix(p.Profile.Endpoint, "/v1/chat/completions")
-	baseEndpoint = strings.TrimSuffix(baseEndpoint, "/v1")
-	modelsEndpoint := baseEndpoint + "/v1/models"
-
-	req, err := http.NewRequestWithContext(context.Background(), "GET", modelsEndpoint, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request for models: %w", err)
-	}
-
-	apiKey, err := p.getAPIKey()
-	if err != nil {
-		return nil, err
-	}
-
-	if apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+apiKey)
-	}
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making request to /v1/models: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		// It's okay if this fails (e.g., endpoint not found), so we don't return a hard error.
-		// We'll just fall back to the user-specified model.
-		return nil, fmt.Errorf("models endpoint returned status %d", resp.StatusCode)
-	}
-
-	var modelsResp openAIModelsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&modelsResp); err != nil {
-		return nil, fmt.Errorf("error decoding models response: %w", err)
-	}
-
-	var modelIDs []string
-	for _, model := range modelsResp.Data {
-		modelIDs = append(modelIDs, model.ID)
-	}
-
-	return modelIDs, nil
-}
-
 // resolveModel determines the final model name to use based on a prioritized list from the user's profile setting.
 // It supports combinations like "model1,auto,model2".
-func (p *Provider) resolveModel() (string, error) {
+func (p *Provider) resolveModel(ctx context.Context, client *oaicompat.Client) (string, error) {
 	userModelSetting := p.Profile.Model
 	priorityList := strings.Split(userModelSetting, ",")
 
 	// First, try to get the list of available models from the endpoint.
-	availableModels, err := p.getAvailableModels()
+	// It's okay if this fails (e.g., endpoint not found); we'll fall back to the user-specified model.
+	availableModels, err := client.Models(ctx, oaicompat.ModelsURL(p.Profile.Endpoint))
 
 	// Create a map for quick lookup of available models.
 	availableModelsMap := make(map[string]bool)
@@ -167,184 +73,29 @@ func (p *Provider) resolveModel() (string, error) {
 }
 
 // Chat sends a chat request to the OpenAI-compatible API and returns a single, complete response.
-func (p *Provider) Chat(systemPrompt, userPrompt string) (string, error) {
-	model, err := p.resolveModel()
-	if err != nil {
-		return "", err
-	}
-
-	endpoint := p.Profile.Endpoint
-	if endpoint == "" {
-		endpoint = "https://api.openai.com/v1/chat/completions"
-	}
-
-	messages := []llm.Message{}
-	if systemPrompt != "" {
-		messages = append(messages, llm.Message{Role: "system", Content: systemPrompt})
-	}
-	messages = append(messages, llm.Message{Role: "user", Content: userPrompt})
-
-	reqBody := openAIRequest{
-		Model:    model,
-		Messages: messages,
-	}
-
-	jsonBody, err := json.Marshal(reqBody)
+func (p *Provider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	client, err := oaicompat.NewClient(p.Profile)
 	if err != nil {
-		return "", fmt.Errorf("error marshalling request body: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(context.Background(), "POST", endpoint, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return "", fmt.Errorf("error creating request: %w", err)
-	}
-
-	apiKey, err := p.getAPIKey()
-	if err != nil {
-		return "", err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	if apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+apiKey)
+		return nil, err
 	}
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	model, err := p.resolveModel(ctx, client)
 	if err != nil {
-		return "", fmt.Errorf("error making request to openai-compatible api: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("openai-compatible api request failed with status %d: %s", resp.StatusCode, string(body))
-	}
-
-	var openAIResp openAIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&openAIResp); err != nil {
-		return "", fmt.Errorf("error decoding openai response: %w", err)
-	}
-
-	if len(openAIResp.Choices) == 0 {
-		return "", fmt.Errorf("no choices returned from openai-compatible api")
+		return nil, err
 	}
-
-	return openAIResp.Choices[0].Message.Content, nil
+	return client.Chat(ctx, model, req, p.Profile.Generation)
 }
 
 // ChatStream sends a streaming chat request to the OpenAI-compatible API and sends response chunks to a channel.
-func (p *Provider) ChatStream(ctx context.Context, systemPrompt, userPrompt string, responseChan chan<- string) error {
-	model, err := p.resolveModel()
+func (p *Provider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	client, err := oaicompat.NewClient(p.Profile)
 	if err != nil {
-		return err
-	}
-
-	endpoint := p.Profile.Endpoint
-	if endpoint == "" {
-		endpoint = "https://api.openai.com/v1/chat/completions"
-	}
-
-	messages := []llm.Message{}
-	if systemPrompt != "" {
-		messages = append(messages, llm.Message{Role: "system", Content: systemPrompt})
-	}
-	messages = append(messages, llm.Message{Role: "user", Content: userPrompt})
-
-	reqBody := openAIRequest{
-		Model:    model,
-		Messages: messages,
-		Stream:   true,
-	}
-
-	jsonBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return fmt.Errorf("error marshalling request body: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return fmt.Errorf("error creating request: %w", err)
-	}
-
-	apiKey, err := p.getAPIKey()
-	if err != nil {
-		return err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	if apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+apiKey)
-	}
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("error making request to openai-compatible api: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("openai-compatible api request failed with status %d: %s", resp.StatusCode, string(body))
-	}
-
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !strings.HasPrefix(line, "data: ") || line == "data: [DONE]" {
-			continue
-		}
-
-		data := strings.TrimPrefix(line, "data: ")
-
-		if strings.Contains(data, `"error":`) {
-			return fmt.Errorf("streaming error: %s", data)
-		}
-
-		var streamResp openAIStreamResponse
-		if err := json.Unmarshal([]byte(data), &streamResp); err != nil {
-			continue
-		}
-
-		if len(streamResp.Choices) > 0 {
-			select {
-			case responseChan <- streamResp.Choices[0].Delta.Content:
-			case <-ctx.Done():
-				return ctx.Err()
-			}
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading stream: %w", err)
-	}
-
-	return nil
-}
-
-// loadOpenAIAPIKeyFromFile loads OpenAI API key from a specified JSON file.
-func loadOpenAIAPIKeyFromFile(filePath string) (string, error) {
-	resolvedPath, err := config.ResolvePath(filePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to resolve credentials file path %s: %w", filePath, err)
+		return nil, err
 	}
-
-	data, err := os.ReadFile(resolvedPath)
+	model, err := p.resolveModel(ctx, client)
 	if err != nil {
-		return "", fmt.Errorf("failed to read credentials file %s: %w", resolvedPath, err)
-	}
-
-	var key openAIAPIKey
-	if err := json.Unmarshal(data, &key); err != nil {
-		return "", fmt.Errorf("failed to unmarshal credentials from file %s: %w", resolvedPath, err)
-	}
-
-	if key.OpenAIAPIKey == "" {
-		return "", fmt.Errorf("openai_api_key is missing in credentials file %s", resolvedPath)
+		return nil, err
 	}
-
-	return key.OpenAIAPIKey, nil
+	return client.ChatStream(ctx, model, req, p.Profile.Generation, responseChan)
 }
 
 // NewProvider is a factory function that returns a new OpenAI2 provider.
@@ -355,42 +106,5 @@ func NewProvider(p config.Profile) (llm.Provider, error) {
 // ValidateConfig checks if the OpenAI2 provider's configuration is valid.
 // It requires a model and, for the default OpenAI endpoint, an API key or credentials file.
 func (p *Provider) ValidateConfig() error {
-	// Model is always required.
-	if p.Profile.Model == "" {
-		return fmt.Errorf("OpenAI2 provider requires a 'model' to be specified in the profile")
-	}
-
-	// Determine the effective endpoint. If empty, it will use the default OpenAI endpoint.
-	endpoint := p.Profile.Endpoint
-	if endpoint == "" {
-		endpoint = "https://api.openai.com/v1/chat/completions"
-	}
-
-	// If using the default OpenAI endpoint, an API key or credentials file is mandatory.
-	if endpoint == "https://api.openai.com/v1/chat/completions" {
-		if p.Profile.APIKey == "" && p.Profile.CredentialsFile == "" {
-			return fmt.Errorf("OpenAI2 provider (using default endpoint) requires either 'api-key' or 'credentials-file' to be set in the profile")
-		}
-	} else {
-		// If a custom endpoint is used (e.g., LM Studio), the endpoint itself is the primary requirement.
-		// We don't strictly require API key/credentials file for custom endpoints, as they might not need it.
-		// However, the endpoint must not be empty if it's not the default.
-		if p.Profile.Endpoint == "" { // Check original Profile.Endpoint, not the resolved one
-			return fmt.Errorf("OpenAI2 provider (using custom endpoint) requires 'endpoint' to be specified in the profile")
-		}
-	}
-
-	// If a credentials file is provided, attempt to resolve its path and check existence.
-	if p.Profile.CredentialsFile != "" {
-		resolvedPath, err := config.ResolvePath(p.Profile.CredentialsFile)
-		if err != nil {
-			return fmt.Errorf("failed to resolve credentials file path %s: %w", p.Profile.CredentialsFile, err)
-		}
-		// Check if the file exists and is readable.
-		if _, err := os.Stat(resolvedPath); os.IsNotExist(err) {
-			return fmt.Errorf("credentials file not found at %s", resolvedPath)
-		}
-	}
-
-	return nil
+	return oaicompat.ValidateProfile("OpenAI2", p.Profile)
 }
@@ -0,0 +1,58 @@
+package openai2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_ChatResolvesModel(t *testing.T) {
+	tests := []struct {
+		name      string
+		model     string
+		available string
+		want      string
+	}{
+		{name: "auto picks the first available model", model: "auto", available: `{"data": [{"id": "local-a"}, {"id": "local-b"}]}`, want: "local-a"},
+		{name: "first available in priority order", model: "missing,local-b,auto", available: `{"data": [{"id": "local-a"}, {"id": "local-b"}]}`, want: "local-b"},
+		{name: "no models endpoint falls back to the first named model", model: "auto,local-c", want: "local-c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotModel string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v1/models":
+					if tt.available == "" {
+						http.NotFound(w, r)
+						return
+					}
+					fmt.Fprint(w, tt.available)
+				case "/v1/chat/completions":
+					var body map[string]any
+					require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+					gotModel, _ = body["model"].(string)
+					fmt.Fprint(w, `{"choices": [{"message": {"content": "ok"}}]}`)
+				default:
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+			}))
+			defer server.Close()
+
+			p := &Provider{Profile: config.Profile{Model: tt.model, Endpoint: server.URL + "/v1/chat/completions"}}
+			resp, err := p.Chat(context.Background(), llm.NewRequest("", "hi"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, gotModel)
+			assert.Equal(t, tt.want, resp.Model)
+		})
+	}
+}
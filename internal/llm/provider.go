@@ -1,26 +1,199 @@
 package llm
 
-import "context"
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Roles used in the chat history.
+const (
+	RoleSystem    = "system"    // Instructions that guide the model's behavior.
+	RoleUser      = "user"      // Input from the user.
+	RoleAssistant = "assistant" // Previous output from the model.
+	RoleTool      = "tool"      // The result of a tool call requested by the model.
+)
 
 // Message represents a single message in the chat history, with a role and content.
 type Message struct {
-	Role    string `json:"role"`    // The role of the message sender (e.g., "user", "system", "assistant").
-	Content string `json:"content"` // The content of the message.
+	Role       string     `json:"role"`                   // The role of the message sender (e.g., "user", "system", "assistant", "tool").
+	Content    string     `json:"content"`                // The content of the message. For tool messages, the tool's output.
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // The tools the model asked to call (assistant messages only).
+	ToolCallID string     `json:"tool_call_id,omitempty"` // The ID of the call this message answers (tool messages only).
+	Name       string     `json:"name,omitempty"`         // The name of the tool that produced the result (tool messages only).
+	Parts      []Part     `json:"parts,omitempty"`        // Images and documents sent along with the content (user messages only).
+}
+
+// Part is a binary input, such as an image or a PDF document, sent along with the text of a message.
+type Part struct {
+	Name     string `json:"name,omitempty"` // The file name, which some APIs show to the model or require for documents.
+	MIMEType string `json:"mime_type"`      // The media type of the data (e.g., "image/png" or "application/pdf").
+	Data     []byte `json:"data"`           // The raw data. Encoded as base64 in JSON.
+}
+
+// IsImage reports whether the part is an image.
+func (p Part) IsImage() bool {
+	return strings.HasPrefix(p.MIMEType, "image/")
+}
+
+// Base64 returns the data encoded as standard base64.
+func (p Part) Base64() string {
+	return base64.StdEncoding.EncodeToString(p.Data)
+}
+
+// DataURI returns the part as a data URI, as accepted by OpenAI-compatible APIs.
+func (p Part) DataURI() string {
+	return "data:" + p.MIMEType + ";base64," + p.Base64()
+}
+
+// UnsupportedPartError returns the error for a part whose media type the named API does not accept.
+func UnsupportedPartError(api string, p Part) error {
+	return fmt.Errorf("%s does not accept %s input ('%s')", api, p.MIMEType, p.Name)
+}
+
+// Tool declares a function the model may ask the caller to run.
+type Tool struct {
+	Name        string          `json:"name"`                  // The function name.
+	Description string          `json:"description,omitempty"` // What the function does, to help the model decide when to call it.
+	Parameters  json.RawMessage `json:"parameters,omitempty"`  // A JSON schema describing the arguments object.
+}
+
+// ToolCall is a request from the model to run a tool.
+type ToolCall struct {
+	ID        string          `json:"id,omitempty"` // The provider's ID for the call, if it assigns one.
+	Name      string          `json:"name"`         // The name of the tool to run.
+	Arguments json.RawMessage `json:"arguments"`    // The arguments as a JSON object.
+}
+
+// ToolArguments returns the arguments of a tool call, using an empty object if there are none,
+// as APIs omit or blank them for tools without parameters.
+func ToolArguments(args json.RawMessage) json.RawMessage {
+	if len(bytes.TrimSpace(args)) == 0 {
+		return json.RawMessage("{}")
+	}
+	return args
+}
+
+// Request represents a chat request sent to a provider.
+// It carries the full conversation history, so callers can replay previous turns
+// or build few-shot prompts instead of concatenating transcripts into a single prompt.
+type Request struct {
+	Messages []Message       `json:"messages"`         // The conversation history, in order. The last message is normally a user turn.
+	Schema   json.RawMessage `json:"schema,omitempty"` // An optional JSON schema the reply must conform to. Providers that support structured output request it natively.
+	Tools    []Tool          `json:"tools,omitempty"`  // Tools the model may call instead of answering directly.
+}
+
+// NewRequest builds a Request from an optional system prompt and a single user prompt.
+func NewRequest(systemPrompt, userPrompt string) Request {
+	var messages []Message
+	if systemPrompt != "" {
+		messages = append(messages, Message{Role: RoleSystem, Content: systemPrompt})
+	}
+	messages = append(messages, Message{Role: RoleUser, Content: userPrompt})
+	return Request{Messages: messages}
+}
+
+// SystemPrompt returns the content of all system messages in the request, joined by blank lines.
+// It is intended for providers whose APIs accept the system prompt separately from the conversation.
+func (r Request) SystemPrompt() string {
+	var parts []string
+	for _, m := range r.Messages {
+		if m.Role == RoleSystem && m.Content != "" {
+			parts = append(parts, m.Content)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// SchemaInstruction returns the system prompt instruction that asks for JSON conforming to the request's schema.
+// It is intended for providers whose APIs have no native structured output.
+func (r Request) SchemaInstruction() string {
+	return "Respond only with a JSON document that conforms to this JSON schema, without any other text:\n" + string(r.Schema)
+}
+
+// Conversation returns the user and assistant messages of the request, in order, without system messages.
+func (r Request) Conversation() []Message {
+	var conv []Message
+	for _, m := range r.Messages {
+		if m.Role != RoleSystem {
+			conv = append(conv, m)
+		}
+	}
+	return conv
+}
+
+// Validate checks that the request contains only known roles and ends with a user turn
+// or with the results of the tool calls of the last assistant turn.
+func (r Request) Validate() error {
+	conv := r.Conversation()
+	if len(conv) == 0 {
+		return fmt.Errorf("request contains no user message")
+	}
+	for i, m := range r.Messages {
+		switch m.Role {
+		case RoleSystem, RoleUser, RoleAssistant, RoleTool:
+		default:
+			return fmt.Errorf("message %d has unknown role '%s'", i, m.Role)
+		}
+	}
+	if last := conv[len(conv)-1]; last.Role != RoleUser && last.Role != RoleTool {
+		return fmt.Errorf("the last message in the conversation must be from the user, got '%s'", last.Role)
+	}
+	return nil
+}
+
+// Usage reports the number of tokens consumed by a call, as counted by the provider.
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`  // The number of tokens in the prompt, including the conversation history.
+	OutputTokens int `json:"output_tokens"` // The number of tokens generated by the model.
+}
+
+// Add returns the sum of two usages, for callers that make several calls to answer one request.
+// A nil usage counts as unknown and is skipped; the result is nil only if both are nil.
+func (u *Usage) Add(other *Usage) *Usage {
+	if u == nil && other == nil {
+		return nil
+	}
+	var sum Usage
+	for _, x := range []*Usage{u, other} {
+		if x != nil {
+			sum.InputTokens += x.InputTokens
+			sum.OutputTokens += x.OutputTokens
+		}
+	}
+	return &sum
+}
+
+// Response is the result of a chat call.
+type Response struct {
+	Text         string        // The generated text. Empty for streamed responses, whose text is sent to the response channel.
+	Model        string        // The model that actually served the request, if reported by the provider.
+	FinishReason string        // The provider's reason for ending the generation (e.g., "stop", "end_turn", "length").
+	Usage        *Usage        // Token usage, or nil if the provider did not report it.
+	ToolCalls    []ToolCall    // The tools the model asked to call, if the request declared tools.
+	Candidates   []string      // The text of every candidate, if the model was asked for several. Text holds the first.
+	Latency      time.Duration // The time taken by the call. Set by the caller, as it includes client setup.
 }
 
 // Provider defines the interface for interacting with a Large Language Model (LLM).
 // It specifies methods for both single-response chat and streaming chat interactions.
 type Provider interface {
-	// Chat sends a single user prompt and an optional system prompt to the LLM and returns a single response.
-	Chat(systemPrompt, userPrompt string) (string, error)
-	// ChatStream sends a user prompt and an optional system prompt to the LLM and streams the response.
+	// Chat sends the conversation in the request to the LLM and returns a single response.
+	// The context allows for cancellation of the request.
+	Chat(ctx context.Context, req Request) (*Response, error)
+	// ChatStream sends the conversation in the request to the LLM and streams the response.
 	// The context allows for cancellation of the streaming operation.
-	// Response tokens are sent to the provided response channel.
-	ChatStream(ctx context.Context, systemPrompt, userPrompt string, responseChan chan<- string) error
+	// Response tokens are sent to the provided response channel; the returned Response
+	// carries the metadata (model, finish reason and usage) reported at the end of the stream.
+	ChatStream(ctx context.Context, req Request, responseChan chan<- string) (*Response, error)
 }
 
 // ConfigValidator defines an interface for providers that can validate their configuration.
 // This allows the `profile check` command to verify if a profile has all necessary settings.
 type ConfigValidator interface {
 	ValidateConfig() error
-}
\ No newline at end of file
+}
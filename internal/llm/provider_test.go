@@ -0,0 +1,113 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRequest(t *testing.T) {
+	req := NewRequest("be brief", "hello")
+	require.Len(t, req.Messages, 2)
+	assert.Equal(t, Message{Role: RoleSystem, Content: "be brief"}, req.Messages[0])
+	assert.Equal(t, Message{Role: RoleUser, Content: "hello"}, req.Messages[1])
+
+	// Without a system prompt only the user turn is included.
+	req = NewRequest("", "hello")
+	assert.Equal(t, []Message{{Role: RoleUser, Content: "hello"}}, req.Messages)
+}
+
+func TestRequest_SystemPromptAndConversation(t *testing.T) {
+	req := Request{Messages: []Message{
+		{Role: RoleSystem, Content: "first"},
+		{Role: RoleUser, Content: "q1"},
+		{Role: RoleAssistant, Content: "a1"},
+		{Role: RoleSystem, Content: "second"},
+		{Role: RoleUser, Content: "q2"},
+	}}
+
+	assert.Equal(t, "first\n\nsecond", req.SystemPrompt())
+	assert.Equal(t, []Message{
+		{Role: RoleUser, Content: "q1"},
+		{Role: RoleAssistant, Content: "a1"},
+		{Role: RoleUser, Content: "q2"},
+	}, req.Conversation())
+}
+
+func TestRequest_Validate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		messages    []Message
+		expectError bool
+	}{
+		{
+			name:     "single user turn",
+			messages: []Message{{Role: RoleUser, Content: "hi"}},
+		},
+		{
+			name: "multi-turn ending with user",
+			messages: []Message{
+				{Role: RoleSystem, Content: "sys"},
+				{Role: RoleUser, Content: "q1"},
+				{Role: RoleAssistant, Content: "a1"},
+				{Role: RoleUser, Content: "q2"},
+			},
+		},
+		{
+			name:        "no conversation",
+			messages:    []Message{{Role: RoleSystem, Content: "sys"}},
+			expectError: true,
+		},
+		{
+			name: "ends with assistant",
+			messages: []Message{
+				{Role: RoleUser, Content: "q1"},
+				{Role: RoleAssistant, Content: "a1"},
+			},
+			expectError: true,
+		},
+		{
+			name: "ends with tool result",
+			messages: []Message{
+				{Role: RoleUser, Content: "q1"},
+				{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "1", Name: "lookup", Arguments: []byte(`{}`)}}},
+				{Role: RoleTool, ToolCallID: "1", Name: "lookup", Content: "result"},
+			},
+			expectError: false,
+		},
+		{
+			name:        "unknown role",
+			messages:    []Message{{Role: "function", Content: "x"}, {Role: RoleUser, Content: "q"}},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Request{Messages: tc.messages}.Validate()
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestUsage_Add(t *testing.T) {
+	var total *Usage
+	assert.Nil(t, total.Add(nil))
+
+	total = total.Add(&Usage{InputTokens: 10, OutputTokens: 2})
+	total = total.Add(nil)
+	total = total.Add(&Usage{InputTokens: 15, OutputTokens: 3})
+	assert.Equal(t, &Usage{InputTokens: 25, OutputTokens: 5}, total)
+}
+
+func TestToolArguments(t *testing.T) {
+	assert.JSONEq(t, `{}`, string(ToolArguments(nil)))
+	assert.JSONEq(t, `{}`, string(ToolArguments(json.RawMessage(" \n"))))
+	assert.JSONEq(t, `{"city":"Tokyo"}`, string(ToolArguments(json.RawMessage(`{"city":"Tokyo"}`))))
+}
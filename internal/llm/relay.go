@@ -0,0 +1,34 @@
+package llm
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// RelayFirstToken returns a channel to pass to a provider's ChatStream instead of out, for decorators that must know
+// whether the stream has produced any text, e.g., to retry or fall back only while nothing has reached the caller.
+// Chunks sent to relay are forwarded to out until ctx is done. Empty chunks, which some APIs send before the text,
+// do not count as a token. onFirstToken, if not nil, is called when the first token arrives.
+// emitted reports whether a token has arrived. wait closes relay and returns once every chunk has been forwarded;
+// it must be called after ChatStream returns.
+func RelayFirstToken(ctx context.Context, out chan<- string, onFirstToken func()) (relay chan<- string, emitted func() bool, wait func()) {
+	ch := make(chan string)
+	var first atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for chunk := range ch {
+			if chunk != "" && !first.Swap(true) && onFirstToken != nil {
+				onFirstToken()
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return ch, first.Load, func() {
+		close(ch)
+		<-done
+	}
+}
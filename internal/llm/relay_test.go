@@ -0,0 +1,38 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelayFirstToken(t *testing.T) {
+	out := make(chan string, 10)
+	calls := 0
+	relay, emitted, wait := RelayFirstToken(context.Background(), out, func() { calls++ })
+
+	relay <- ""
+	relay <- "Hello"
+	relay <- " world"
+	wait()
+	close(out)
+
+	var chunks []string
+	for chunk := range out {
+		chunks = append(chunks, chunk)
+	}
+	assert.Equal(t, []string{"", "Hello", " world"}, chunks)
+	assert.True(t, emitted())
+	assert.Equal(t, 1, calls)
+}
+
+func TestRelayFirstToken_EmptyChunksOnly(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	relay, emitted, wait := RelayFirstToken(ctx, make(chan string), nil)
+
+	relay <- "" // Dropped, since nobody reads the output and ctx is done.
+	wait()
+	assert.False(t, emitted())
+}
@@ -0,0 +1,197 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+)
+
+// Defaults of a retry policy.
+const (
+	DefaultMaxAttempts = 3
+	DefaultBaseDelay   = time.Second
+	DefaultMaxDelay    = 30 * time.Second
+)
+
+// Policy decides how often and after which delays failed calls are retried.
+type Policy struct {
+	MaxAttempts int           // Total number of attempts, including the first. 1 or less disables retries.
+	BaseDelay   time.Duration // Delay before the first retry; it doubles with every further retry.
+	MaxDelay    time.Duration // Upper bound of a single delay.
+	Jitter      bool          // Spreads each delay randomly over its upper half, so that parallel clients do not retry in lockstep.
+}
+
+// NewPolicy returns the policy configured in a profile, with defaults for unset values.
+func NewPolicy(c config.Retry) Policy {
+	policy := Policy{
+		MaxAttempts: c.MaxAttempts,
+		BaseDelay:   time.Duration(c.BaseDelay),
+		MaxDelay:    time.Duration(c.MaxDelay),
+		Jitter:      !c.NoJitter,
+	}
+	if policy.MaxAttempts == 0 {
+		policy.MaxAttempts = DefaultMaxAttempts
+	}
+	if policy.BaseDelay == 0 {
+		policy.BaseDelay = DefaultBaseDelay
+	}
+	if policy.MaxDelay == 0 {
+		policy.MaxDelay = DefaultMaxDelay
+	}
+	return policy
+}
+
+// Delay returns the delay before the given retry (1 for the first). A delay requested by the server
+// replaces the exponential backoff; both are capped at MaxDelay.
+func (p Policy) Delay(retry int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, p.MaxDelay)
+	}
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxDelay)
+	if p.Jitter && delay > 1 {
+		delay = delay/2 + rand.N(delay/2)
+	}
+	return delay
+}
+
+// retryableStatus reports whether an HTTP status is worth retrying: request timeouts, rate limits,
+// server errors that are usually temporary, and Anthropic's 529 "overloaded".
+func retryableStatus(code int) bool {
+	switch code {
+	case 408, 425, 429, 500, 502, 503, 504, 529:
+		return true
+	}
+	return false
+}
+
+// retryableCodes are the error codes of AWS service exceptions that are worth retrying.
+var retryableCodes = map[string]bool{
+	"ThrottlingException":         true,
+	"TooManyRequestsException":    true,
+	"ServiceUnavailableException": true,
+	"InternalServerException":     true,
+	"ModelNotReadyException":      true,
+	"RequestTimeout":              true,
+	"RequestTimeoutException":     true,
+	"ModelStreamErrorException":   true,
+}
+
+// Retryable reports whether err is a transient failure worth retrying, and the delay the server asked for, if any.
+// It recognizes llm.StatusError, the service exceptions and HTTP status codes of the AWS SDK, reset or refused
+// connections, request and network timeouts. Cancellation of the caller's context is never retried.
+func Retryable(err error) (bool, time.Duration) {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false, 0
+	}
+
+	var statusErr *llm.StatusError
+	if errors.As(err, &statusErr) {
+		return retryableStatus(statusErr.StatusCode), statusErr.RetryAfter
+	}
+	// The AWS SDK reports service exceptions with an error code and HTTP errors with a status code.
+	var coded interface{ ErrorCode() string }
+	if errors.As(err, &coded) && retryableCodes[coded.ErrorCode()] {
+		return true, 0
+	}
+	var withStatus interface{ HTTPStatusCode() int }
+	if errors.As(err, &withStatus) {
+		return retryableStatus(withStatus.HTTPStatusCode()), 0
+	}
+
+	if errors.Is(err, llm.ErrRequestTimeout) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true, 0
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true, 0
+	}
+	return false, 0
+}
+
+// Provider wraps another provider and retries calls that fail with a transient error.
+// Streams are only retried until the first token has been sent to the caller, since the text already
+// printed cannot be taken back.
+type Provider struct {
+	llm.Provider           // The provider whose calls are retried.
+	Policy       Policy    // When and how often to retry.
+	Log          io.Writer // Receives a line for every retry, if set.
+
+	sleep func(ctx context.Context, d time.Duration) error // Waits between attempts. Replaced in tests.
+}
+
+// NewProvider returns a provider that retries the calls of p according to policy.
+// If the policy allows a single attempt, p is returned unchanged.
+func NewProvider(p llm.Provider, policy Policy, log io.Writer) llm.Provider {
+	if policy.MaxAttempts <= 1 {
+		return p
+	}
+	return &Provider{Provider: p, Policy: policy, Log: log, sleep: sleep}
+}
+
+// sleep waits for the duration or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Chat sends the request, retrying transient failures.
+func (p *Provider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	return p.do(ctx, func() (*llm.Response, bool, error) {
+		resp, err := p.Provider.Chat(ctx, req)
+		return resp, true, err
+	})
+}
+
+// ChatStream streams the response, retrying transient failures that occur before the first token.
+func (p *Provider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	return p.do(ctx, func() (*llm.Response, bool, error) {
+		relay, emitted, wait := llm.RelayFirstToken(ctx, responseChan, nil)
+		resp, err := p.Provider.ChatStream(ctx, req, relay)
+		wait()
+		return resp, !emitted(), err
+	})
+}
+
+// do runs an attempt until it succeeds, fails permanently or the attempts are used up.
+// The attempt reports whether it may be retried at all, e.g., because no output has reached the caller yet.
+func (p *Provider) do(ctx context.Context, attempt func() (*llm.Response, bool, error)) (*llm.Response, error) {
+	for n := 1; ; n++ {
+		resp, canRetry, err := attempt()
+		if err == nil {
+			return resp, nil
+		}
+		retryable, retryAfter := Retryable(err)
+		if !retryable || !canRetry || n >= p.Policy.MaxAttempts || ctx.Err() != nil {
+			if retryable && n > 1 {
+				return nil, fmt.Errorf("giving up after %d attempts: %w", n, err)
+			}
+			return nil, err
+		}
+
+		delay := p.Policy.Delay(n, retryAfter)
+		if p.Log != nil {
+			fmt.Fprintf(p.Log, "Retrying in %s (attempt %d of %d failed): %v\n", delay.Round(time.Millisecond), n, p.Policy.MaxAttempts, err)
+		}
+		if err := p.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyProvider fails with the given errors in order and then succeeds.
+// Before failing a stream it sends the chunks in partial.
+type flakyProvider struct {
+	errs    []error
+	partial []string
+	calls   int
+}
+
+func (p *flakyProvider) next() error {
+	p.calls++
+	if p.calls <= len(p.errs) {
+		return p.errs[p.calls-1]
+	}
+	return nil
+}
+
+func (p *flakyProvider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	return &llm.Response{Text: "ok"}, nil
+}
+
+func (p *flakyProvider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	if err := p.next(); err != nil {
+		for _, chunk := range p.partial {
+			responseChan <- chunk
+		}
+		return nil, err
+	}
+	responseChan <- "ok"
+	return &llm.Response{Text: "ok"}, nil
+}
+
+// awsError mimics the service exceptions of the AWS SDK.
+type awsError struct{ code string }
+
+func (e awsError) Error() string     { return e.code }
+func (e awsError) ErrorCode() string { return e.code }
+
+// newTestProvider returns a retrying provider that records its delays instead of sleeping.
+func newTestProvider(p llm.Provider, policy Policy, delays *[]time.Duration) *Provider {
+	var log strings.Builder
+	return &Provider{Provider: p, Policy: policy, Log: &log, sleep: func(ctx context.Context, d time.Duration) error {
+		*delays = append(*delays, d)
+		return nil
+	}}
+}
+
+func TestNewPolicy(t *testing.T) {
+	assert.Equal(t, Policy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: 30 * time.Second, Jitter: true}, NewPolicy(config.Retry{}))
+	assert.Equal(t,
+		Policy{MaxAttempts: 5, BaseDelay: 200 * time.Millisecond, MaxDelay: 2 * time.Second},
+		NewPolicy(config.Retry{MaxAttempts: 5, BaseDelay: config.Duration(200 * time.Millisecond), MaxDelay: config.Duration(2 * time.Second), NoJitter: true}))
+}
+
+func TestPolicy_Delay(t *testing.T) {
+	p := Policy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	assert.Equal(t, time.Second, p.Delay(1, 0))
+	assert.Equal(t, 2*time.Second, p.Delay(2, 0))
+	assert.Equal(t, 4*time.Second, p.Delay(3, 0))
+	assert.Equal(t, 5*time.Second, p.Delay(4, 0), "capped at the maximum")
+	assert.Equal(t, 3*time.Second, p.Delay(1, 3*time.Second), "Retry-After replaces the backoff")
+	assert.Equal(t, 5*time.Second, p.Delay(1, time.Minute), "Retry-After is capped as well")
+
+	p.Jitter = true
+	for i := 0; i < 100; i++ {
+		d := p.Delay(3, 0)
+		assert.True(t, d >= 2*time.Second && d < 4*time.Second, d)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		want       bool
+		retryAfter time.Duration
+	}{
+		{name: "rate limited", err: &llm.StatusError{StatusCode: 429, RetryAfter: 2 * time.Second}, want: true, retryAfter: 2 * time.Second},
+		{name: "overloaded", err: fmt.Errorf("wrapped: %w", &llm.StatusError{StatusCode: 529}), want: true},
+		{name: "unavailable", err: &llm.StatusError{StatusCode: 503}, want: true},
+		{name: "bad request", err: &llm.StatusError{StatusCode: 400}},
+		{name: "unauthorized", err: &llm.StatusError{StatusCode: 401}},
+		{name: "aws throttling", err: fmt.Errorf("error invoking model: %w", awsError{"ThrottlingException"}), want: true},
+		{name: "aws validation", err: awsError{"ValidationException"}},
+		{name: "request timeout", err: fmt.Errorf("%w after 1m0s", llm.ErrRequestTimeout), want: true},
+		{name: "connection reset", err: fmt.Errorf("read: %w", syscall.ECONNRESET), want: true},
+		{name: "canceled", err: fmt.Errorf("error: %w", context.Canceled)},
+		{name: "other", err: errors.New("invalid JSON")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, retryAfter := Retryable(tt.err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.retryAfter, retryAfter)
+		})
+	}
+}
+
+func TestProvider_Chat(t *testing.T) {
+	policy := Policy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	t.Run("retries transient errors", func(t *testing.T) {
+		var delays []time.Duration
+		inner := &flakyProvider{errs: []error{&llm.StatusError{StatusCode: 503}, &llm.StatusError{StatusCode: 429, RetryAfter: 7 * time.Second}}}
+		resp, err := newTestProvider(inner, policy, &delays).Chat(context.Background(), llm.NewRequest("", "hi"))
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp.Text)
+		assert.Equal(t, 3, inner.calls)
+		assert.Equal(t, []time.Duration{time.Second, 7 * time.Second}, delays)
+	})
+
+	t.Run("gives up after the last attempt", func(t *testing.T) {
+		var delays []time.Duration
+		statusErr := &llm.StatusError{StatusCode: 503, Message: "unavailable"}
+		inner := &flakyProvider{errs: []error{statusErr, statusErr, statusErr}}
+		_, err := newTestProvider(inner, policy, &delays).Chat(context.Background(), llm.NewRequest("", "hi"))
+		require.Error(t, err)
+		assert.ErrorIs(t, err, statusErr)
+		assert.Equal(t, "giving up after 3 attempts: unavailable", err.Error())
+		assert.Equal(t, 3, inner.calls)
+	})
+
+	t.Run("permanent errors are not retried", func(t *testing.T) {
+		var delays []time.Duration
+		inner := &flakyProvider{errs: []error{&llm.StatusError{StatusCode: 401, Message: "unauthorized"}}}
+		_, err := newTestProvider(inner, policy, &delays).Chat(context.Background(), llm.NewRequest("", "hi"))
+		assert.EqualError(t, err, "unauthorized")
+		assert.Equal(t, 1, inner.calls)
+		assert.Empty(t, delays)
+	})
+}
+
+func TestProvider_ChatStream(t *testing.T) {
+	policy := Policy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	t.Run("retried before the first token", func(t *testing.T) {
+		var delays []time.Duration
+		inner := &flakyProvider{errs: []error{&llm.StatusError{StatusCode: 429}}, partial: []string{""}}
+		responseChan := make(chan string, 10)
+		resp, err := newTestProvider(inner, policy, &delays).ChatStream(context.Background(), llm.NewRequest("", "hi"), responseChan)
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp.Text)
+		assert.Equal(t, 2, inner.calls)
+	})
+
+	t.Run("not retried after the first token", func(t *testing.T) {
+		var delays []time.Duration
+		inner := &flakyProvider{errs: []error{&llm.StatusError{StatusCode: 503, Message: "unavailable"}}, partial: []string{"Hel"}}
+		responseChan := make(chan string, 10)
+		_, err := newTestProvider(inner, policy, &delays).ChatStream(context.Background(), llm.NewRequest("", "hi"), responseChan)
+		assert.EqualError(t, err, "unavailable")
+		assert.Equal(t, 1, inner.calls)
+		close(responseChan)
+		var text string
+		for chunk := range responseChan {
+			text += chunk
+		}
+		assert.Equal(t, "Hel", text)
+	})
+}
+
+func TestNewProvider_Disabled(t *testing.T) {
+	inner := &flakyProvider{}
+	assert.Same(t, inner, NewProvider(inner, Policy{MaxAttempts: 1}, nil))
+}
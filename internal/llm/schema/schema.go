@@ -0,0 +1,133 @@
+package schema
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// schemaURL is the location under which the schema is registered with the compiler.
+const schemaURL = "schema.json"
+
+// Schema is a compiled JSON schema used to validate model replies.
+type Schema struct {
+	Raw      json.RawMessage // The schema document, as sent to providers.
+	compiled *jsonschema.Schema
+}
+
+// Compile parses and compiles a JSON schema document.
+func Compile(data []byte) (*Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing JSON schema: %w", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(schemaURL, doc); err != nil {
+		return nil, fmt.Errorf("error loading JSON schema: %w", err)
+	}
+	compiled, err := compiler.Compile(schemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling JSON schema: %w", err)
+	}
+
+	var raw bytes.Buffer
+	if err := json.Compact(&raw, data); err != nil {
+		return nil, fmt.Errorf("error parsing JSON schema: %w", err)
+	}
+	return &Schema{Raw: raw.Bytes(), compiled: compiled}, nil
+}
+
+// Validate checks that a reply is a JSON document conforming to the schema.
+// Models often wrap JSON in a Markdown code fence, so a surrounding fence is removed first.
+// It returns the JSON document without the fence.
+func (s *Schema) Validate(text string) (string, error) {
+	doc := ExtractJSON(text)
+	value, err := jsonschema.UnmarshalJSON(strings.NewReader(doc))
+	if err != nil {
+		return "", fmt.Errorf("reply is not valid JSON: %w", err)
+	}
+	if err := s.compiled.Validate(value); err != nil {
+		return "", err
+	}
+	return doc, nil
+}
+
+// ExtractJSON trims whitespace and a surrounding Markdown code fence (e.g. "```json ... ```") from text.
+func ExtractJSON(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") || !strings.HasSuffix(text, "```") || len(text) < 6 {
+		return text
+	}
+	body := strings.TrimSuffix(strings.TrimPrefix(text, "```"), "```")
+	// Drop the language tag on the opening line.
+	if i := strings.IndexByte(body, '\n'); i >= 0 {
+		body = body[i+1:]
+	}
+	return strings.TrimSpace(body)
+}
+
+// Provider wraps another provider so that every reply is validated against a JSON schema.
+// The schema is attached to each request, and invalid replies are retried up to Retries times
+// with a follow-up message describing the validation error.
+type Provider struct {
+	llm.Provider         // The provider that generates the replies.
+	Schema       *Schema // The schema replies must conform to.
+	Retries      int     // The number of additional attempts after an invalid reply.
+}
+
+// NewProvider returns a provider that validates the replies of p against s.
+func NewProvider(p llm.Provider, s *Schema, retries int) llm.Provider {
+	return &Provider{Provider: p, Schema: s, Retries: retries}
+}
+
+// Chat sends the request and returns the first reply that conforms to the schema.
+// The token usage of all attempts is added up.
+func (p *Provider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	req.Schema = p.Schema.Raw
+	messages := append([]llm.Message(nil), req.Messages...)
+
+	var usage *llm.Usage
+	for attempt := 0; ; attempt++ {
+		req.Messages = messages
+		resp, err := p.Provider.Chat(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		usage = usage.Add(resp.Usage)
+
+		doc, err := p.Schema.Validate(resp.Text)
+		if err == nil {
+			resp.Text = doc
+			resp.Usage = usage
+			return resp, nil
+		}
+		if attempt >= p.Retries {
+			return nil, fmt.Errorf("reply does not match the JSON schema after %d attempt(s): %w", attempt+1, err)
+		}
+
+		messages = append(messages,
+			llm.Message{Role: llm.RoleAssistant, Content: resp.Text},
+			llm.Message{Role: llm.RoleUser, Content: fmt.Sprintf("The previous reply does not match the required JSON schema: %v\nReply again with only a JSON document that conforms to the schema.", err)},
+		)
+	}
+}
+
+// ChatStream validates the complete reply before sending it, so it is sent to the channel as a single chunk.
+func (p *Provider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	resp, err := p.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case responseChan <- resp.Text:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	resp.Text = ""
+	return resp, nil
+}
@@ -0,0 +1,80 @@
+package schema
+
+import (
+	"context"
+	"testing"
+
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const personSchema = `{
+	"type": "object",
+	"properties": {"name": {"type": "string"}, "age": {"type": "integer"}},
+	"required": ["name"]
+}`
+
+// scriptedProvider returns the given replies in order and records the requests it received.
+type scriptedProvider struct {
+	replies  []string
+	requests []llm.Request
+}
+
+func (p *scriptedProvider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	p.requests = append(p.requests, req)
+	reply := p.replies[len(p.requests)-1]
+	return &llm.Response{Text: reply, Usage: &llm.Usage{InputTokens: 10, OutputTokens: 5}}, nil
+}
+
+func (p *scriptedProvider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	return nil, nil
+}
+
+func TestCompile(t *testing.T) {
+	s, err := Compile([]byte(personSchema))
+	require.NoError(t, err)
+	assert.JSONEq(t, personSchema, string(s.Raw))
+
+	_, err = Compile([]byte(`{"type": `))
+	assert.Error(t, err)
+	_, err = Compile([]byte(`{"type": "no-such-type"}`))
+	assert.Error(t, err)
+}
+
+func TestSchema_Validate(t *testing.T) {
+	s, err := Compile([]byte(personSchema))
+	require.NoError(t, err)
+
+	doc, err := s.Validate("```json\n{\"name\": \"Ada\", \"age\": 36}\n```")
+	require.NoError(t, err)
+	assert.Equal(t, `{"name": "Ada", "age": 36}`, doc)
+
+	_, err = s.Validate(`{"age": 36}`)
+	assert.Error(t, err)
+	_, err = s.Validate(`Here you go: {"name": "Ada"}`)
+	assert.Error(t, err)
+}
+
+func TestProvider_Retries(t *testing.T) {
+	s, err := Compile([]byte(personSchema))
+	require.NoError(t, err)
+
+	inner := &scriptedProvider{replies: []string{`{"age": "old"}`, `{"name": "Ada"}`}}
+	resp, err := NewProvider(inner, s, 1).Chat(context.Background(), llm.NewRequest("", "who?"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"name": "Ada"}`, resp.Text)
+	assert.Equal(t, &llm.Usage{InputTokens: 20, OutputTokens: 10}, resp.Usage)
+
+	require.Len(t, inner.requests, 2)
+	assert.JSONEq(t, personSchema, string(inner.requests[0].Schema))
+	// The retry resends the invalid reply and asks for a conforming one.
+	retry := inner.requests[1].Messages
+	require.Len(t, retry, 3)
+	assert.Equal(t, llm.Message{Role: llm.RoleAssistant, Content: `{"age": "old"}`}, retry[1])
+	assert.Equal(t, llm.RoleUser, retry[2].Role)
+
+	inner = &scriptedProvider{replies: []string{"not json"}}
+	_, err = NewProvider(inner, s, 0).Chat(context.Background(), llm.NewRequest("", "who?"))
+	assert.ErrorContains(t, err, "does not match the JSON schema")
+}
@@ -0,0 +1,56 @@
+package timeout
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/magifd2/llm-cli/internal/llm"
+)
+
+// Provider wraps another provider and bounds the time of each call. A call that does not return within
+// the timeout fails with llm.ErrRequestTimeout; a stream fails only if its first token does not arrive in time.
+type Provider struct {
+	llm.Provider               // The provider whose calls are bounded.
+	Timeout      time.Duration // The limit of a call, or of the wait for the first token of a stream.
+}
+
+// NewProvider returns a provider that bounds the calls of p by the given timeout.
+// If the timeout is 0, p is returned unchanged.
+func NewProvider(p llm.Provider, timeout time.Duration) llm.Provider {
+	if timeout <= 0 {
+		return p
+	}
+	return &Provider{Provider: p, Timeout: timeout}
+}
+
+// Chat sends the request, cancelling it when the timeout expires.
+func (p *Provider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	callCtx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+	resp, err := p.Provider.Chat(callCtx, req)
+	return resp, p.timeoutError(ctx, callCtx, err)
+}
+
+// ChatStream streams the response, cancelling the request if no token arrives before the timeout expires.
+// Once the first token has arrived, the stream may take as long as it needs.
+func (p *Provider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	callCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	timer := time.AfterFunc(p.Timeout, cancel)
+	defer timer.Stop()
+
+	// Stop the timer at the first token.
+	relay, _, wait := llm.RelayFirstToken(ctx, responseChan, func() { timer.Stop() })
+	resp, err := p.Provider.ChatStream(callCtx, req, relay)
+	wait()
+	return resp, p.timeoutError(ctx, callCtx, err)
+}
+
+// timeoutError replaces the error of a call that was cancelled by the timeout rather than by the caller.
+func (p *Provider) timeoutError(ctx, callCtx context.Context, err error) error {
+	if err != nil && callCtx.Err() != nil && ctx.Err() == nil {
+		return fmt.Errorf("%w after %s", llm.ErrRequestTimeout, p.Timeout)
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+package timeout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowProvider sends the given chunks with a delay before each, and fails if the context is done first.
+type slowProvider struct {
+	delay  time.Duration
+	chunks []string
+}
+
+func (p *slowProvider) wait(ctx context.Context) error {
+	select {
+	case <-time.After(p.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *slowProvider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	if err := p.wait(ctx); err != nil {
+		return nil, err
+	}
+	return &llm.Response{Text: "ok"}, nil
+}
+
+func (p *slowProvider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	for _, chunk := range p.chunks {
+		if err := p.wait(ctx); err != nil {
+			return nil, err
+		}
+		responseChan <- chunk
+	}
+	return &llm.Response{}, nil
+}
+
+func TestProvider_Chat(t *testing.T) {
+	resp, err := NewProvider(&slowProvider{delay: time.Millisecond}, time.Second).Chat(context.Background(), llm.NewRequest("", "hi"))
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.Text)
+
+	_, err = NewProvider(&slowProvider{delay: time.Second}, 10*time.Millisecond).Chat(context.Background(), llm.NewRequest("", "hi"))
+	assert.ErrorIs(t, err, llm.ErrRequestTimeout)
+	assert.EqualError(t, err, "request timed out after 10ms")
+
+	// A cancellation by the caller is not reported as a timeout.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = NewProvider(&slowProvider{delay: time.Second}, time.Minute).Chat(ctx, llm.NewRequest("", "hi"))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestProvider_ChatStream(t *testing.T) {
+	// The timeout only applies until the first token: the stream as a whole takes longer.
+	p := NewProvider(&slowProvider{delay: 20 * time.Millisecond, chunks: []string{"a", "b", "c", "d"}}, 50*time.Millisecond)
+	responseChan := make(chan string, 10)
+	_, err := p.ChatStream(context.Background(), llm.NewRequest("", "hi"), responseChan)
+	require.NoError(t, err)
+	assert.Len(t, responseChan, 4)
+
+	p = NewProvider(&slowProvider{delay: time.Second, chunks: []string{"a"}}, 10*time.Millisecond)
+	_, err = p.ChatStream(context.Background(), llm.NewRequest("", "hi"), make(chan string, 10))
+	assert.ErrorIs(t, err, llm.ErrRequestTimeout)
+}
+
+func TestNewProvider_Disabled(t *testing.T) {
+	inner := &slowProvider{}
+	assert.Same(t, inner, NewProvider(inner, 0))
+}
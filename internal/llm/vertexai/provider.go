@@ -11,6 +11,7 @@ import (
 	"cloud.google.com/go/auth"
 	"github.com/magifd2/llm-cli/internal/config"
 	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/magifd2/llm-cli/internal/llm/genaichat"
 	"google.golang.org/genai"
 )
 
@@ -83,6 +84,13 @@ func (p *Provider) newVertexAIClient(ctx context.Context) (*genai.Client, error)
 		clientConfig.Credentials = auth.NewCredentials(&auth.CredentialsOptions{TokenProvider: tp})
 	}
 
+	// Authorize the requests with an HTTP client that uses the connection settings of the profile.
+	httpClient, err := genaichat.NewVertexAIHTTPClient(ctx, p.Profile, clientConfig.Credentials)
+	if err != nil {
+		return nil, err
+	}
+	clientConfig.HTTPClient = httpClient
+
 	// Create and return the new genai client.
 	client, err := genai.NewClient(ctx, clientConfig)
 	if err != nil {
@@ -105,93 +113,22 @@ func expandPath(path string) (string, error) {
 }
 
 // Chat sends a chat request to the Vertex AI API and returns a single, complete response.
-// System prompts are sent as the first message in the conversation.
-func (p *Provider) Chat(systemPrompt, userPrompt string) (string, error) {
-	ctx := context.Background()
+// System prompts are sent as the system instruction of the request.
+func (p *Provider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
 	client, err := p.newVertexAIClient(ctx)
 	if err != nil {
-		return "", err
-	}
-
-	// Create a new chat session.
-	chat, err := client.Chats.Create(ctx, p.Profile.Model, nil, nil)
-	if err != nil {
-		return "", fmt.Errorf("error creating chat: %w", err)
-	}
-
-	// If a system prompt is provided, send it as the first message.
-	if systemPrompt != "" {
-		_, err := chat.SendMessage(ctx, genai.Part{Text: systemPrompt})
-		if err != nil {
-			return "", fmt.Errorf("error sending system prompt to vertexai: %w", err)
-		}
+		return nil, err
 	}
-
-	// Send the user prompt and get the response.
-	resp, err := chat.SendMessage(ctx, genai.Part{Text: userPrompt})
-	if err != nil {
-		return "", fmt.Errorf("error sending message to vertexai: %w", err)
-	}
-
-	return extractTextFromResponse(resp), nil
+	return genaichat.Chat(ctx, client, p.Profile, req)
 }
 
-// ChatStream sends a streaming chat request to the Vertex AI API and streams response chunks to a channel.
-// System prompts are sent as the first message in the conversation.
-func (p *Provider) ChatStream(ctx context.Context, systemPrompt, userPrompt string, responseChan chan<- string) error {
+// ChatStream sends a streaming chat request to the Vertex AI API and sends the generated text to a channel.
+func (p *Provider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
 	client, err := p.newVertexAIClient(ctx)
 	if err != nil {
-		return err
-	}
-
-	// Create a new chat session.
-	chat, err := client.Chats.Create(ctx, p.Profile.Model, nil, nil)
-	if err != nil {
-		return fmt.Errorf("error creating chat: %w", err)
-	}
-
-	// If a system prompt is provided, send it as the first message.
-	if systemPrompt != "" {
-		_, err := chat.SendMessage(ctx, genai.Part{Text: systemPrompt})
-		if err != nil {
-			return fmt.Errorf("error sending system prompt to vertexai: %w", err)
-		}
-	}
-
-	// Stream the user prompt and process each response chunk.
-	for resp, err := range chat.SendMessageStream(ctx, genai.Part{Text: userPrompt}) {
-		if err != nil {
-			return fmt.Errorf("error reading stream from vertexai: %w", err)
-		}
-
-		chunk := extractTextFromResponse(resp)
-		if chunk != "" {
-			select {
-			case responseChan <- chunk:
-			case <-ctx.Done():
-				return ctx.Err()
-			}
-		}
+		return nil, err
 	}
-
-	return nil
-}
-
-// extractTextFromResponse extracts and concatenates text content from a Vertex AI GenerateContentResponse.
-func extractTextFromResponse(resp *genai.GenerateContentResponse) string {
-	var sb strings.Builder
-	if resp == nil || len(resp.Candidates) == 0 {
-		return ""
-	}
-
-	for _, cand := range resp.Candidates {
-		if cand.Content != nil {
-			for _, part := range cand.Content.Parts {
-				sb.WriteString(part.Text)
-			}
-		}
-	}
-	return sb.String()
+	return genaichat.ChatStream(ctx, client, p.Profile, req, responseChan)
 }
 
 // NewProvider is a factory function that returns a new VertexAI provider.
@@ -215,15 +152,5 @@ func (p *Provider) ValidateConfig() error {
 		return fmt.Errorf("Vertex AI provider requires a 'credentials-file' to be set in the profile")
 	}
 
-	// If a credentials file is provided, attempt to resolve its path and check existence.
-	resolvedPath, err := config.ResolvePath(p.Profile.CredentialsFile)
-	if err != nil {
-		return fmt.Errorf("failed to resolve credentials file path %s: %w", p.Profile.CredentialsFile, err)
-	}
-	// Check if the file exists and is readable.
-	if _, err := os.Stat(resolvedPath); os.IsNotExist(err) {
-		return fmt.Errorf("credentials file not found at %s", resolvedPath)
-	}
-
-	return nil
+	return config.CheckCredentialsFile(p.Profile.CredentialsFile)
 }
\ No newline at end of file
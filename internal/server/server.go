@@ -0,0 +1,482 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+)
+
+// ProfileHeader selects the profile of a request, taking precedence over the model name.
+const ProfileHeader = "X-LLM-CLI-Profile"
+
+// maxRequestBodyBytes bounds the size of a request body, including base64-encoded images and files,
+// before the profile's own limits are applied.
+const maxRequestBodyBytes = 64 << 20
+
+// Server exposes the profiles of a configuration through an OpenAI-compatible HTTP API,
+// so that any OpenAI SDK client can reach the providers configured in llm-cli.
+type Server struct {
+	Config         *config.Config                                     // The profiles requests are routed to.
+	DefaultProfile string                                             // The profile of requests without a model.
+	Token          string                                             // If set, requests must send it as a bearer token.
+	NewProvider    func(profile config.Profile) (llm.Provider, error) // Creates the provider of a profile, once per profile and generation parameters.
+	Log            io.Writer                                          // Receives a line per request and the warnings of the limits.
+
+	mu        sync.Mutex
+	providers map[providerKey]llm.Provider
+}
+
+// maxCachedProviders bounds the number of providers kept for reuse. Requests whose generation parameters vary
+// endlessly, e.g., with a new seed each time, would otherwise add a provider each.
+const maxCachedProviders = 256
+
+// providerKey identifies a cached provider: the profile, and the generation parameters after the request's overrides.
+type providerKey struct {
+	profile    string
+	generation string // The generation parameters as JSON.
+}
+
+// provider returns the provider of the named profile, creating it on first use. The provider is shared by all
+// the requests with the same generation parameters, so that its clients and SDK configuration are built once.
+// Errors are not cached, so a request after, e.g., a fixed credentials file succeeds.
+func (s *Server) provider(name string, profile config.Profile) (llm.Provider, error) {
+	generation, err := json.Marshal(profile.Generation)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding generation parameters: %w", err)
+	}
+	key := providerKey{profile: name, generation: string(generation)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if provider, ok := s.providers[key]; ok {
+		return provider, nil
+	}
+	provider, err := s.NewProvider(profile)
+	if err != nil {
+		return nil, err
+	}
+	if s.providers == nil {
+		s.providers = make(map[providerKey]llm.Provider)
+	}
+	if len(s.providers) < maxCachedProviders {
+		s.providers[key] = provider
+	}
+	return provider, nil
+}
+
+// Handler returns the HTTP handler of the API: /v1/chat/completions and /v1/models.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/chat/completions", s.handleChatCompletions)
+	mux.HandleFunc("GET /v1/models", s.handleModels)
+	return s.authenticate(mux)
+}
+
+// authenticate rejects requests without the bearer token, if one is configured. Without a token, the server
+// spends the user's credentials for anyone who can reach it, so it only answers requests addressed to a loopback
+// host: a web page cannot reach it through DNS rebinding. POST bodies must be JSON, which a cross-site form or
+// simple request cannot send without a CORS preflight, and the server answers no preflight.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Token != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+				writeError(w, http.StatusUnauthorized, "invalid_request_error", "invalid_api_key", "missing or invalid bearer token")
+				return
+			}
+		} else if !isLoopbackHost(r.Host) {
+			writeError(w, http.StatusForbidden, "invalid_request_error", "", fmt.Sprintf("host '%s' is not a loopback address: set a token to accept remote requests", r.Host))
+			return
+		}
+		if r.Method == http.MethodPost {
+			if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, "invalid_request_error", "", "the request body must be application/json")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLoopbackHost reports whether the Host header of a request names this machine: localhost or a loopback address.
+func isLoopbackHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+// handleModels lists the profiles as models.
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(s.Config.Profiles))
+	for name := range s.Config.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := modelList{Object: "list", Data: []model{}}
+	for _, name := range names {
+		list.Data = append(list.Data, model{ID: name, Object: "model", OwnedBy: s.Config.Profiles[name].Provider})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// route picks the profile of a request: the one named by the profile header, else the one named by the model,
+// else the first profile, by name, configured with that model. Requests without a model use the default profile.
+func (s *Server) route(r *http.Request, modelName string) (string, config.Profile, error) {
+	name := r.Header.Get(ProfileHeader)
+	if name == "" {
+		name = modelName
+	}
+	if name == "" {
+		name = s.DefaultProfile
+	}
+	if profile, ok := s.Config.Profiles[name]; ok {
+		return name, profile, nil
+	}
+	if r.Header.Get(ProfileHeader) == "" && modelName != "" {
+		var matches []string
+		for profileName, profile := range s.Config.Profiles {
+			if profile.Model == modelName {
+				matches = append(matches, profileName)
+			}
+		}
+		if len(matches) > 0 {
+			sort.Strings(matches)
+			return matches[0], s.Config.Profiles[matches[0]], nil
+		}
+	}
+	return "", config.Profile{}, fmt.Errorf("no profile matches '%s'", name)
+}
+
+// handleChatCompletions answers a Chat Completions request with the provider of the routed profile.
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	var body chatRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+	if err := decoder.Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	name, profile, err := s.route(r, body.Model)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "invalid_request_error", "model_not_found", err.Error())
+		return
+	}
+	req, err := body.toLLMRequest()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", err.Error())
+		return
+	}
+	if err := s.limitInput(&req, profile.Limits); err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "invalid_request_error", "", err.Error())
+		return
+	}
+	body.applyGeneration(&profile.Generation)
+	provider, err := s.provider(name, profile)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", "", fmt.Sprintf("error initializing provider for profile '%s': %v", name, err))
+		return
+	}
+
+	modelName := body.Model
+	if modelName == "" {
+		modelName = name
+	}
+	completion := completionMeta{id: newCompletionID(), created: start.Unix(), model: modelName}
+	status := http.StatusOK
+	if body.Stream {
+		includeUsage := body.StreamOptions != nil && body.StreamOptions.IncludeUsage
+		err = s.stream(w, r.Context(), provider, req, profile.Limits, completion, includeUsage)
+	} else {
+		status, err = s.complete(w, r.Context(), provider, req, profile.Limits, completion)
+	}
+	outcome := fmt.Sprintf("status=%d", status)
+	if err != nil {
+		outcome += fmt.Sprintf(" error=%q", err.Error())
+	}
+	s.logf("POST /v1/chat/completions profile=%s stream=%t %s latency=%dms", name, body.Stream, outcome, time.Since(start).Milliseconds())
+}
+
+// completionMeta holds the fields shared by the response and all chunks of a completion.
+type completionMeta struct {
+	id      string
+	created int64
+	model   string // The model name of the request, echoed back as OpenAI does.
+}
+
+// complete answers with a single response. It returns the status that was sent and the error, if any.
+func (s *Server) complete(w http.ResponseWriter, ctx context.Context, provider llm.Provider, req llm.Request, limits config.Limits, meta completionMeta) (int, error) {
+	resp, err := provider.Chat(ctx, req)
+	if err != nil {
+		return writeProviderError(w, err), err
+	}
+	texts := resp.Candidates
+	if len(texts) == 0 {
+		texts = []string{resp.Text}
+	}
+	completion := chatCompletion{ID: meta.id, Object: "chat.completion", Created: meta.created, Model: meta.model, Usage: newUsage(resp.Usage)}
+	for i, text := range texts {
+		if text, err = s.limitOutput(text, limits); err != nil {
+			writeError(w, http.StatusBadGateway, "server_error", "", err.Error())
+			return http.StatusBadGateway, err
+		}
+		c := choice{Index: i, Message: assistantMessage{Role: llm.RoleAssistant, Content: &text}, FinishReason: finishReason(resp)}
+		if i == 0 {
+			c.Message.ToolCalls = newToolCalls(resp.ToolCalls, false)
+		}
+		completion.Choices = append(completion.Choices, c)
+	}
+	writeJSON(w, http.StatusOK, completion)
+	return http.StatusOK, nil
+}
+
+// stream answers with server-sent events: a chunk per token, a final chunk with the finish reason and tool calls,
+// the token usage if requested, and "[DONE]". Errors after the first event are sent as an error event.
+func (s *Server) stream(w http.ResponseWriter, ctx context.Context, provider llm.Provider, req llm.Request, limits config.Limits, meta completionMeta, includeUsage bool) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	responseChan := make(chan string)
+	type result struct {
+		resp *llm.Response
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer close(responseChan)
+		resp, err := provider.ChatStream(ctx, req, responseChan)
+		done <- result{resp, err}
+	}()
+
+	flusher, _ := w.(http.Flusher)
+	started := false
+	send := func(v any) {
+		if !started {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	chunk := func(delta assistantMessage, finish *string) chatCompletionChunk {
+		return chatCompletionChunk{ID: meta.id, Object: "chat.completion.chunk", Created: meta.created, Model: meta.model,
+			Choices: []chunkChoice{{Delta: delta, FinishReason: finish}}}
+	}
+
+	// As in the prompt command, the stream is cancelled once the output limit is hit and the remaining tokens are drained.
+	var size int64
+	var truncated bool
+	var limitErr error
+	for token := range responseChan {
+		if truncated || limitErr != nil {
+			continue
+		}
+		token = strings.ToValidUTF8(token, "�")
+		if limits.Enabled && size+int64(len(token)) > limits.MaxResponseSizeBytes {
+			if limits.OnOutputExceeded == "stop" {
+				limitErr = fmt.Errorf("output size exceeded the limit of %d bytes", limits.MaxResponseSizeBytes)
+				cancel()
+				continue
+			}
+			s.logf("Warning: Output size exceeded the limit of %d bytes. Truncating...", limits.MaxResponseSizeBytes)
+			token = truncateString(token, limits.MaxResponseSizeBytes-size)
+			truncated = true
+			cancel()
+		}
+		size += int64(len(token))
+		if !started {
+			role := assistantMessage{Role: llm.RoleAssistant}
+			send(chunk(role, nil))
+		}
+		if token != "" {
+			send(chunk(assistantMessage{Content: &token}, nil))
+		}
+	}
+	res := <-done
+
+	err := limitErr
+	if err == nil && !truncated {
+		err = res.err
+	}
+	if err != nil {
+		if !started {
+			writeProviderError(w, err)
+			return err
+		}
+		var body errorBody
+		body.Error.Message = err.Error()
+		body.Error.Type = "server_error"
+		send(body)
+		fmt.Fprint(w, "data: [DONE]\n\n")
+		return err
+	}
+
+	resp := res.resp
+	if resp == nil {
+		resp = &llm.Response{}
+	}
+	reason := finishReason(resp)
+	if truncated {
+		reason = "length"
+	}
+	final := assistantMessage{ToolCalls: newToolCalls(resp.ToolCalls, true)}
+	if !started {
+		final.Role = llm.RoleAssistant
+	}
+	send(chunk(final, &reason))
+	if includeUsage {
+		usageChunk := chatCompletionChunk{ID: meta.id, Object: "chat.completion.chunk", Created: meta.created, Model: meta.model,
+			Choices: []chunkChoice{}, Usage: newUsage(resp.Usage)}
+		if usageChunk.Usage == nil {
+			usageChunk.Usage = &usage{}
+		}
+		send(usageChunk)
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// limitInput applies the profile's input size limit to each message, as the prompt command applies it to each prompt.
+// With "stop" an oversized message is rejected. With "warn" its text is truncated and the images and files that do
+// not fit are left out, which is logged.
+func (s *Server) limitInput(req *llm.Request, limits config.Limits) error {
+	for i := range req.Messages {
+		m := &req.Messages[i]
+		m.Content = strings.ToValidUTF8(m.Content, "�")
+		if !limits.Enabled {
+			continue
+		}
+		if int64(len(m.Content)) > limits.MaxPromptSizeBytes {
+			if limits.OnInputExceeded == "stop" {
+				return fmt.Errorf("message %d exceeds the input size limit of %d bytes", i, limits.MaxPromptSizeBytes)
+			}
+			s.logf("Warning: Message %d exceeds the limit of %d bytes. Truncating...", i, limits.MaxPromptSizeBytes)
+			m.Content = truncateString(m.Content, limits.MaxPromptSizeBytes)
+		}
+		total := int64(len(m.Content))
+		var parts []llm.Part
+		for _, p := range m.Parts {
+			if total+int64(len(p.Data)) > limits.MaxPromptSizeBytes {
+				if limits.OnInputExceeded == "stop" {
+					return fmt.Errorf("message %d exceeds the input size limit of %d bytes with its %s input", i, limits.MaxPromptSizeBytes, p.MIMEType)
+				}
+				s.logf("Warning: Message %d exceeds the limit of %d bytes with its %s input. Skipping it...", i, limits.MaxPromptSizeBytes, p.MIMEType)
+				continue
+			}
+			total += int64(len(p.Data))
+			parts = append(parts, p)
+		}
+		m.Parts = parts
+	}
+	return nil
+}
+
+// limitOutput applies the profile's output size limit to a complete response.
+func (s *Server) limitOutput(text string, limits config.Limits) (string, error) {
+	text = strings.ToValidUTF8(text, "�")
+	if !limits.Enabled || int64(len(text)) <= limits.MaxResponseSizeBytes {
+		return text, nil
+	}
+	if limits.OnOutputExceeded == "stop" {
+		return "", fmt.Errorf("output size (%d bytes) exceeds the limit of %d bytes", len(text), limits.MaxResponseSizeBytes)
+	}
+	s.logf("Warning: Output size (%d bytes) exceeds the limit of %d bytes. Truncating...", len(text), limits.MaxResponseSizeBytes)
+	return truncateString(text, limits.MaxResponseSizeBytes), nil
+}
+
+// logf writes a line to the log, if there is one.
+func (s *Server) logf(format string, args ...any) {
+	if s.Log != nil {
+		fmt.Fprintf(s.Log, format+"\n", args...)
+	}
+}
+
+// truncateString cuts s to at most maxBytes bytes without splitting a UTF-8 character.
+func truncateString(s string, maxBytes int64) string {
+	if int64(len(s)) <= maxBytes {
+		return s
+	}
+	end := int(max(maxBytes, 0))
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end]
+}
+
+// newCompletionID returns a random ID for a completion.
+func newCompletionID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "chatcmpl-" + hex.EncodeToString(b)
+}
+
+// writeJSON writes a JSON response.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error response in the format of the OpenAI API.
+func writeError(w http.ResponseWriter, status int, errType, code, message string) {
+	var body errorBody
+	body.Error.Message = message
+	body.Error.Type = errType
+	body.Error.Code = code
+	writeJSON(w, status, body)
+}
+
+// writeProviderError writes the error of a provider call and returns the status that was sent. The status of
+// an API error is passed on with its Retry-After delay, so that clients retry rate limits and overloads as usual.
+func writeProviderError(w http.ResponseWriter, err error) int {
+	status := http.StatusBadGateway
+	errType := "server_error"
+	var statusErr *llm.StatusError
+	switch {
+	case errors.As(err, &statusErr):
+		status = statusErr.StatusCode
+		if statusErr.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(statusErr.RetryAfter.Seconds()))))
+		}
+		if status == http.StatusTooManyRequests {
+			errType = "rate_limit_error"
+		} else if status < 500 {
+			errType = "invalid_request_error"
+		}
+	case errors.Is(err, llm.ErrRequestTimeout):
+		status = http.StatusGatewayTimeout
+	}
+	writeError(w, status, errType, "", err.Error())
+	return status
+}
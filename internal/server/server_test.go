@@ -0,0 +1,321 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/magifd2/llm-cli/internal/llm/mock"
+	"github.com/magifd2/llm-cli/internal/llm/oaicompat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// toolProvider answers every request with a tool call.
+type toolProvider struct{}
+
+func (toolProvider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	return &llm.Response{ToolCalls: []llm.ToolCall{{Name: "get_weather", Arguments: json.RawMessage(`{"city":"Tokyo"}`)}}}, nil
+}
+
+func (p toolProvider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	return p.Chat(ctx, req)
+}
+
+// newTestServer starts a server for a configuration of mock profiles.
+func newTestServer(t *testing.T, token string) (*httptest.Server, *strings.Builder) {
+	t.Helper()
+	cfg := &config.Config{
+		CurrentProfile: "default",
+		Profiles: map[string]config.Profile{
+			"default": {Provider: "mock", Model: "echo-model"},
+			"small":   {Provider: "mock", Model: "small-model", Limits: config.Limits{Enabled: true, OnInputExceeded: "stop", OnOutputExceeded: "warn", MaxPromptSizeBytes: 1000, MaxResponseSizeBytes: 20}},
+			"strict":  {Provider: "mock", Model: "strict-model", Limits: config.Limits{Enabled: true, OnInputExceeded: "stop", OnOutputExceeded: "stop", MaxPromptSizeBytes: 1000, MaxResponseSizeBytes: 20}},
+			"failing": {Provider: "mock", Model: "failing-model"},
+			"tools":   {Provider: "tools", Model: "tool-model"},
+		},
+	}
+	var log strings.Builder
+	srv := &Server{
+		Config:         cfg,
+		DefaultProfile: "default",
+		Token:          token,
+		Log:            &log,
+		NewProvider: func(profile config.Profile) (llm.Provider, error) {
+			switch {
+			case profile.Provider == "tools":
+				return toolProvider{}, nil
+			case profile.Model == "failing-model":
+				script, err := mock.ParseScript([]byte("responses:\n  - error: overloaded\n    status: 503\n    retry_after: 2s\n"))
+				require.NoError(t, err)
+				return &mock.Provider{Model: profile.Model, Script: script}, nil
+			default:
+				return &mock.Provider{Model: profile.Model, ChunkSize: 5}, nil
+			}
+		},
+	}
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return ts, &log
+}
+
+func newTestClient(ts *httptest.Server, token string) *oaicompat.Client {
+	client := &oaicompat.Client{Endpoint: ts.URL + "/v1/chat/completions"}
+	if token != "" {
+		client.Auth = oaicompat.BearerAuth(token)
+	}
+	return client
+}
+
+func userRequest(system, user string) llm.Request {
+	return llm.Request{Messages: []llm.Message{{Role: llm.RoleSystem, Content: system}, {Role: llm.RoleUser, Content: user}}}
+}
+
+func TestServer_Chat(t *testing.T) {
+	ts, log := newTestServer(t, "secret")
+	client := newTestClient(ts, "secret")
+
+	t.Run("routes by profile name", func(t *testing.T) {
+		resp, err := client.Chat(context.Background(), "default", userRequest("Be brief.", "Hello"), config.Generation{})
+		require.NoError(t, err)
+		assert.Contains(t, resp.Text, "System Prompt: Be brief.\nTurns: 1\nUser Prompt: Hello")
+		assert.Equal(t, "stop", resp.FinishReason)
+		require.NotNil(t, resp.Usage)
+		assert.Positive(t, resp.Usage.InputTokens)
+	})
+
+	t.Run("routes by configured model", func(t *testing.T) {
+		resp, err := client.Chat(context.Background(), "small-model", userRequest("", "Hi"), config.Generation{})
+		require.NoError(t, err)
+		// The output limit of the small profile truncates the response.
+		assert.Equal(t, "\n--- Mock Response -", resp.Text)
+	})
+
+	t.Run("passes tool calls", func(t *testing.T) {
+		resp, err := client.Chat(context.Background(), "tools", userRequest("", "Weather?"), config.Generation{})
+		require.NoError(t, err)
+		require.Len(t, resp.ToolCalls, 1)
+		assert.Equal(t, "call_0", resp.ToolCalls[0].ID)
+		assert.Equal(t, "get_weather", resp.ToolCalls[0].Name)
+		assert.JSONEq(t, `{"city":"Tokyo"}`, string(resp.ToolCalls[0].Arguments))
+		assert.Equal(t, "tool_calls", resp.FinishReason)
+	})
+
+	t.Run("passes the status of provider errors", func(t *testing.T) {
+		_, err := client.Chat(context.Background(), "failing", userRequest("", "Hi"), config.Generation{})
+		var statusErr *llm.StatusError
+		require.True(t, errors.As(err, &statusErr), "unexpected error: %v", err)
+		assert.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+		assert.Equal(t, 2*time.Second, statusErr.RetryAfter)
+	})
+
+	t.Run("rejects unknown models", func(t *testing.T) {
+		_, err := client.Chat(context.Background(), "gpt-unknown", userRequest("", "Hi"), config.Generation{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no profile matches 'gpt-unknown'")
+	})
+
+	t.Run("applies the output limit", func(t *testing.T) {
+		_, err := client.Chat(context.Background(), "strict", userRequest("", "Hi"), config.Generation{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "exceeds the limit of 20 bytes")
+	})
+
+	t.Run("applies the input limit", func(t *testing.T) {
+		_, err := client.Chat(context.Background(), "small", userRequest("", strings.Repeat("a", 1001)), config.Generation{})
+		var statusErr *llm.StatusError
+		require.True(t, errors.As(err, &statusErr), "unexpected error: %v", err)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, statusErr.StatusCode)
+	})
+
+	assert.Contains(t, log.String(), "POST /v1/chat/completions profile=default stream=false status=200")
+}
+
+func TestServer_ChatStream(t *testing.T) {
+	ts, _ := newTestServer(t, "")
+	client := newTestClient(ts, "")
+
+	t.Run("streams the response", func(t *testing.T) {
+		responseChan := make(chan string, 100)
+		resp, err := client.ChatStream(context.Background(), "echo-model", userRequest("S", "Hello"), config.Generation{}, responseChan)
+		require.NoError(t, err)
+		close(responseChan)
+		var chunks []string
+		for chunk := range responseChan {
+			chunks = append(chunks, chunk)
+		}
+		assert.Greater(t, len(chunks), 1)
+		assert.Contains(t, strings.Join(chunks, ""), "System Prompt: S\nTurns: 1\nUser Prompt: Hello")
+		assert.Equal(t, "stop", resp.FinishReason)
+	})
+
+	t.Run("truncates at the output limit", func(t *testing.T) {
+		responseChan := make(chan string, 100)
+		resp, err := client.ChatStream(context.Background(), "small", userRequest("", "Hello"), config.Generation{}, responseChan)
+		require.NoError(t, err)
+		close(responseChan)
+		var text strings.Builder
+		for chunk := range responseChan {
+			text.WriteString(chunk)
+		}
+		assert.Equal(t, "\n--- Mock Response -", text.String())
+		assert.Equal(t, "length", resp.FinishReason)
+	})
+
+	t.Run("sends errors after the first chunk as an event", func(t *testing.T) {
+		responseChan := make(chan string, 100)
+		_, err := client.ChatStream(context.Background(), "strict", userRequest("", "Hello"), config.Generation{}, responseChan)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "output size exceeded the limit of 20 bytes")
+	})
+
+	t.Run("streams tool calls", func(t *testing.T) {
+		responseChan := make(chan string, 100)
+		resp, err := client.ChatStream(context.Background(), "tools", userRequest("", "Weather?"), config.Generation{}, responseChan)
+		require.NoError(t, err)
+		require.Len(t, resp.ToolCalls, 1)
+		assert.Equal(t, "get_weather", resp.ToolCalls[0].Name)
+		assert.Equal(t, "tool_calls", resp.FinishReason)
+	})
+}
+
+func TestServer_Models(t *testing.T) {
+	ts, _ := newTestServer(t, "")
+	models, err := newTestClient(ts, "").Models(context.Background(), ts.URL+"/v1/models")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default", "failing", "small", "strict", "tools"}, models)
+}
+
+func TestServer_Authentication(t *testing.T) {
+	ts, _ := newTestServer(t, "secret")
+
+	for name, token := range map[string]string{"missing token": "", "wrong token": "guess"} {
+		t.Run(name, func(t *testing.T) {
+			_, err := newTestClient(ts, token).Models(context.Background(), ts.URL+"/v1/models")
+			var statusErr *llm.StatusError
+			require.True(t, errors.As(err, &statusErr), "unexpected error: %v", err)
+			assert.Equal(t, http.StatusUnauthorized, statusErr.StatusCode)
+		})
+	}
+}
+
+func TestServer_ProfileHeader(t *testing.T) {
+	ts, _ := newTestServer(t, "")
+	body := `{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}]}`
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/v1/chat/completions", strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(ProfileHeader, "tools")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var completion chatCompletion
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&completion))
+	assert.Equal(t, "gpt-4o", completion.Model)
+	assert.True(t, strings.HasPrefix(completion.ID, "chatcmpl-"))
+	require.Len(t, completion.Choices, 1)
+	assert.Equal(t, "tool_calls", completion.Choices[0].FinishReason)
+}
+
+func TestServer_DefaultProfile(t *testing.T) {
+	ts, _ := newTestServer(t, "")
+	resp, err := http.Post(ts.URL+"/v1/chat/completions", "application/json", strings.NewReader(`{"messages":[{"role":"user","content":"Hi"}]}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, string(data))
+	assert.Contains(t, string(data), `"model":"default"`)
+}
+
+func TestServer_RejectsNonJSONBodies(t *testing.T) {
+	ts, _ := newTestServer(t, "")
+	// A cross-site form or fetch can send a text/plain body without a CORS preflight.
+	resp, err := http.Post(ts.URL+"/v1/chat/completions", "text/plain", strings.NewReader(`{"messages":[{"role":"user","content":"Hi"}]}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+}
+
+func TestServer_Host(t *testing.T) {
+	send := func(t *testing.T, ts *httptest.Server, host, token string) int {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/v1/models", nil)
+		require.NoError(t, err)
+		req.Host = host
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	t.Run("without a token", func(t *testing.T) {
+		ts, _ := newTestServer(t, "")
+		// A page that rebinds its own domain to 127.0.0.1 still sends its domain as the host.
+		assert.Equal(t, http.StatusForbidden, send(t, ts, "attacker.example.com:8080", ""))
+		assert.Equal(t, http.StatusOK, send(t, ts, "localhost:8080", ""))
+	})
+
+	t.Run("with a token", func(t *testing.T) {
+		ts, _ := newTestServer(t, "secret")
+		assert.Equal(t, http.StatusOK, send(t, ts, "llm.internal.example.com", "secret"))
+	})
+}
+
+func TestIsLoopbackHost(t *testing.T) {
+	for host, want := range map[string]bool{
+		"localhost":          true,
+		"LOCALHOST:8080":     true,
+		"api.localhost":      true,
+		"127.0.0.1:8080":     true,
+		"127.1.2.3":          true,
+		"[::1]:8080":         true,
+		"::1":                true,
+		"192.168.1.10:8080":  false,
+		"example.com":        false,
+		"localhost.evil.com": false,
+		"":                   false,
+	} {
+		assert.Equal(t, want, isLoopbackHost(host), host)
+	}
+}
+
+func TestServer_ReusesProviders(t *testing.T) {
+	created := 0
+	srv := &Server{
+		Config: &config.Config{Profiles: map[string]config.Profile{"default": {Provider: "mock", Model: "echo-model"}}},
+		NewProvider: func(profile config.Profile) (llm.Provider, error) {
+			created++
+			return &mock.Provider{Model: profile.Model}, nil
+		},
+	}
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+	client := newTestClient(ts, "")
+
+	for range 3 {
+		_, err := client.Chat(context.Background(), "default", userRequest("", "Hi"), config.Generation{})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, created)
+
+	// Other generation parameters need a provider of their own.
+	temperature := 0.5
+	for range 2 {
+		_, err := client.Chat(context.Background(), "default", userRequest("", "Hi"), config.Generation{Temperature: &temperature})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, created)
+}
@@ -0,0 +1,342 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+)
+
+// chatRequest is the body of a Chat Completions request. Fields that the profiles cannot honor are ignored.
+type chatRequest struct {
+	Model               string          `json:"model"`
+	Messages            []chatMessage   `json:"messages"`
+	Stream              bool            `json:"stream"`
+	StreamOptions       *streamOptions  `json:"stream_options"`
+	Temperature         *float64        `json:"temperature"`
+	TopP                *float64        `json:"top_p"`
+	MaxTokens           *int            `json:"max_tokens"`
+	MaxCompletionTokens *int            `json:"max_completion_tokens"` // Replaces max_tokens in newer clients.
+	Stop                stopSequences   `json:"stop"`
+	Seed                *int64          `json:"seed"`
+	N                   *int            `json:"n"`
+	Tools               []tool          `json:"tools"`
+	ResponseFormat      *responseFormat `json:"response_format"`
+}
+
+// streamOptions asks for the token usage to be sent in a final chunk of a stream.
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// stopSequences accepts the stop parameter as a single string or an array of strings.
+type stopSequences []string
+
+// UnmarshalJSON decodes a string or an array of strings.
+func (s *stopSequences) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = stopSequences{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("stop must be a string or an array of strings")
+	}
+	*s = list
+	return nil
+}
+
+// chatMessage is a message of a Chat Completions request.
+type chatMessage struct {
+	Role       string         `json:"role"`
+	Content    messageContent `json:"content"`
+	ToolCalls  []toolCall     `json:"tool_calls,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+}
+
+// messageContent is the content of a message: a string, or an array of text, image and file parts.
+type messageContent struct {
+	Text  string
+	Parts []llm.Part
+}
+
+// contentPart is an element of a content array.
+type contentPart struct {
+	Type     string `json:"type"` // "text", "image_url" or "file".
+	Text     string `json:"text"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url"`
+	File *struct {
+		Filename string `json:"filename"`
+		FileData string `json:"file_data"`
+	} `json:"file"`
+}
+
+// UnmarshalJSON decodes the content. The text parts are joined by blank lines, and images and files must be data URIs,
+// as the server does not fetch remote URLs.
+func (c *messageContent) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if err := json.Unmarshal(data, &c.Text); err == nil {
+		return nil
+	}
+	var parts []contentPart
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return fmt.Errorf("content must be a string or an array of content parts")
+	}
+	var texts []string
+	for _, p := range parts {
+		switch {
+		case p.Type == "text":
+			texts = append(texts, p.Text)
+		case p.Type == "image_url" && p.ImageURL != nil:
+			part, err := parseDataURI(p.ImageURL.URL)
+			if err != nil {
+				return err
+			}
+			c.Parts = append(c.Parts, part)
+		case p.Type == "file" && p.File != nil:
+			part, err := parseDataURI(p.File.FileData)
+			if err != nil {
+				return err
+			}
+			part.Name = p.File.Filename
+			c.Parts = append(c.Parts, part)
+		default:
+			return fmt.Errorf("unsupported content part type '%s'", p.Type)
+		}
+	}
+	c.Text = strings.Join(texts, "\n\n")
+	return nil
+}
+
+// parseDataURI decodes a base64 data URI such as "data:image/png;base64,...".
+func parseDataURI(uri string) (llm.Part, error) {
+	rest, ok := strings.CutPrefix(uri, "data:")
+	if !ok {
+		return llm.Part{}, fmt.Errorf("only data URIs are supported for images and files")
+	}
+	header, encoded, ok := strings.Cut(rest, ",")
+	mimeType, isBase64 := strings.CutSuffix(header, ";base64")
+	if !ok || !isBase64 || mimeType == "" {
+		return llm.Part{}, fmt.Errorf("invalid data URI: must be of the form data:<type>;base64,<data>")
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return llm.Part{}, fmt.Errorf("invalid data URI: %w", err)
+	}
+	return llm.Part{MIMEType: mimeType, Data: data}, nil
+}
+
+// tool is a tool declaration of a Chat Completions request.
+type tool struct {
+	Type     string `json:"type"` // Always "function".
+	Function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description,omitempty"`
+		Parameters  json.RawMessage `json:"parameters,omitempty"`
+	} `json:"function"`
+}
+
+// toolCall is a tool call in an assistant message, of a request or a response.
+type toolCall struct {
+	Index    *int   `json:"index,omitempty"` // The position of the call; only set in stream chunks.
+	ID       string `json:"id"`
+	Type     string `json:"type"` // Always "function".
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"` // The arguments as a JSON string.
+	} `json:"function"`
+}
+
+// responseFormat asks for JSON output, optionally conforming to a schema.
+type responseFormat struct {
+	Type       string `json:"type"` // "text", "json_object" or "json_schema".
+	JSONSchema *struct {
+		Schema json.RawMessage `json:"schema"`
+	} `json:"json_schema"`
+}
+
+// toLLMRequest converts the request to the provider-neutral format.
+func (r *chatRequest) toLLMRequest() (llm.Request, error) {
+	var req llm.Request
+	toolNames := make(map[string]string) // Tool names by call ID, since tool messages only carry the ID.
+	for i, m := range r.Messages {
+		msg := llm.Message{Role: m.Role, Content: m.Content.Text, Parts: m.Content.Parts, ToolCallID: m.ToolCallID}
+		switch m.Role {
+		case "developer":
+			msg.Role = llm.RoleSystem // The newer name of the system role.
+		case llm.RoleTool:
+			msg.Name = toolNames[m.ToolCallID]
+		}
+		if len(msg.Parts) > 0 && msg.Role != llm.RoleUser {
+			return llm.Request{}, fmt.Errorf("message %d: images and files are only supported in user messages", i)
+		}
+		for _, tc := range m.ToolCalls {
+			arguments := llm.ToolArguments(json.RawMessage(tc.Function.Arguments))
+			if !json.Valid(arguments) {
+				return llm.Request{}, fmt.Errorf("message %d: arguments of tool call '%s' are not valid JSON", i, tc.ID)
+			}
+			toolNames[tc.ID] = tc.Function.Name
+			msg.ToolCalls = append(msg.ToolCalls, llm.ToolCall{ID: tc.ID, Name: tc.Function.Name, Arguments: arguments})
+		}
+		req.Messages = append(req.Messages, msg)
+	}
+	for _, t := range r.Tools {
+		req.Tools = append(req.Tools, llm.Tool{Name: t.Function.Name, Description: t.Function.Description, Parameters: t.Function.Parameters})
+	}
+	if f := r.ResponseFormat; f != nil {
+		switch {
+		case f.Type == "json_schema" && f.JSONSchema != nil && len(f.JSONSchema.Schema) > 0:
+			req.Schema = f.JSONSchema.Schema
+		case f.Type == "json_object" || f.Type == "json_schema":
+			req.Schema = json.RawMessage(`{"type":"object"}`)
+		}
+	}
+	return req, req.Validate()
+}
+
+// applyGeneration overrides the profile's generation parameters with those of the request.
+func (r *chatRequest) applyGeneration(gen *config.Generation) {
+	if r.Temperature != nil {
+		gen.Temperature = r.Temperature
+	}
+	if r.TopP != nil {
+		gen.TopP = r.TopP
+	}
+	if r.MaxTokens != nil {
+		gen.MaxTokens = r.MaxTokens
+	}
+	if r.MaxCompletionTokens != nil {
+		gen.MaxTokens = r.MaxCompletionTokens
+	}
+	if len(r.Stop) > 0 {
+		gen.Stop = r.Stop
+	}
+	if r.Seed != nil {
+		gen.Seed = r.Seed
+	}
+	if r.N != nil && *r.N > 1 {
+		gen.CandidateCount = r.N
+	}
+}
+
+// chatCompletion is the body of a non-streaming response.
+type chatCompletion struct {
+	ID      string   `json:"id"`
+	Object  string   `json:"object"` // Always "chat.completion".
+	Created int64    `json:"created"`
+	Model   string   `json:"model"`
+	Choices []choice `json:"choices"`
+	Usage   *usage   `json:"usage,omitempty"`
+}
+
+// choice is a generated answer of a non-streaming response.
+type choice struct {
+	Index        int              `json:"index"`
+	Message      assistantMessage `json:"message"`
+	FinishReason string           `json:"finish_reason"`
+}
+
+// assistantMessage is the message of a choice, or the delta of a stream chunk.
+type assistantMessage struct {
+	Role      string     `json:"role,omitempty"`
+	Content   *string    `json:"content,omitempty"`
+	ToolCalls []toolCall `json:"tool_calls,omitempty"`
+}
+
+// usage reports the token usage in the Chat Completions format.
+type usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// chatCompletionChunk is an event of a streaming response.
+type chatCompletionChunk struct {
+	ID      string        `json:"id"`
+	Object  string        `json:"object"` // Always "chat.completion.chunk".
+	Created int64         `json:"created"`
+	Model   string        `json:"model"`
+	Choices []chunkChoice `json:"choices"`
+	Usage   *usage        `json:"usage,omitempty"`
+}
+
+// chunkChoice is the change to the answer carried by a stream chunk.
+type chunkChoice struct {
+	Index        int              `json:"index"`
+	Delta        assistantMessage `json:"delta"`
+	FinishReason *string          `json:"finish_reason"`
+}
+
+// modelList is the body of a /v1/models response.
+type modelList struct {
+	Object string  `json:"object"` // Always "list".
+	Data   []model `json:"data"`
+}
+
+// model describes a profile as a model that requests can be routed to.
+type model struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"` // Always "model".
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// errorBody is the body of an error response, and of an error event of a stream.
+type errorBody struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    string `json:"code,omitempty"`
+	} `json:"error"`
+}
+
+// newUsage converts the token usage, returning nil if the provider did not report it.
+func newUsage(u *llm.Usage) *usage {
+	if u == nil {
+		return nil
+	}
+	return &usage{PromptTokens: u.InputTokens, CompletionTokens: u.OutputTokens, TotalTokens: u.InputTokens + u.OutputTokens}
+}
+
+// newToolCalls converts the tool calls of a response.
+func newToolCalls(calls []llm.ToolCall, indexed bool) []toolCall {
+	var result []toolCall
+	for i, c := range calls {
+		tc := toolCall{ID: c.ID, Type: "function"}
+		if tc.ID == "" {
+			tc.ID = fmt.Sprintf("call_%d", i) // Some providers do not assign IDs, but clients need them to answer.
+		}
+		if indexed {
+			index := i
+			tc.Index = &index
+		}
+		tc.Function.Name = c.Name
+		tc.Function.Arguments = string(c.Arguments)
+		result = append(result, tc)
+	}
+	return result
+}
+
+// finishReason maps the provider's finish reason to those of the Chat Completions API.
+func finishReason(resp *llm.Response) string {
+	if len(resp.ToolCalls) > 0 {
+		return "tool_calls"
+	}
+	switch strings.ToLower(resp.FinishReason) {
+	case "length", "max_tokens", "max_tokens_reached":
+		return "length"
+	case "content_filter", "content_filtered", "safety", "guardrail_intervened", "prohibited_content", "blocklist", "spii":
+		return "content_filter"
+	default:
+		return "stop"
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/magifd2/llm-cli/internal/config"
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatRequest_ToLLMRequest(t *testing.T) {
+	body := `{
+		"model": "m",
+		"messages": [
+			{"role": "developer", "content": "Be brief."},
+			{"role": "user", "content": [
+				{"type": "text", "text": "What is this?"},
+				{"type": "image_url", "image_url": {"url": "data:image/png;base64,iVBORw=="}},
+				{"type": "file", "file": {"filename": "a.pdf", "file_data": "data:application/pdf;base64,JVBERg=="}}
+			]},
+			{"role": "assistant", "content": null, "tool_calls": [{"id": "c1", "type": "function", "function": {"name": "lookup", "arguments": ""}}]},
+			{"role": "tool", "tool_call_id": "c1", "content": "42"}
+		],
+		"tools": [{"type": "function", "function": {"name": "lookup", "parameters": {"type": "object"}}}],
+		"response_format": {"type": "json_object"},
+		"stop": "END"
+	}`
+	var r chatRequest
+	require.NoError(t, json.Unmarshal([]byte(body), &r))
+	req, err := r.toLLMRequest()
+	require.NoError(t, err)
+
+	require.Len(t, req.Messages, 4)
+	assert.Equal(t, llm.RoleSystem, req.Messages[0].Role)
+	assert.Equal(t, "What is this?", req.Messages[1].Content)
+	require.Len(t, req.Messages[1].Parts, 2)
+	assert.Equal(t, "image/png", req.Messages[1].Parts[0].MIMEType)
+	assert.Equal(t, "a.pdf", req.Messages[1].Parts[1].Name)
+	assert.Equal(t, []byte("%PDF"), req.Messages[1].Parts[1].Data)
+	assert.JSONEq(t, `{}`, string(req.Messages[2].ToolCalls[0].Arguments))
+	assert.Equal(t, "lookup", req.Messages[3].Name)
+	require.Len(t, req.Tools, 1)
+	assert.JSONEq(t, `{"type":"object"}`, string(req.Schema))
+	assert.Equal(t, stopSequences{"END"}, r.Stop)
+}
+
+func TestChatRequest_ToLLMRequest_Errors(t *testing.T) {
+	tests := map[string]string{
+		"remote image":       `{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"https://example.com/a.png"}}]}]}`,
+		"invalid arguments":  `{"messages":[{"role":"assistant","tool_calls":[{"id":"c1","function":{"name":"f","arguments":"{"}}]}]}`,
+		"image in assistant": `{"messages":[{"role":"assistant","content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,iVBORw=="}}]}]}`,
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			var r chatRequest
+			err := json.Unmarshal([]byte(body), &r)
+			if err == nil {
+				_, err = r.toLLMRequest()
+			}
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestChatRequest_ApplyGeneration(t *testing.T) {
+	var r chatRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"temperature":0.2,"max_tokens":10,"max_completion_tokens":20,"stop":["a","b"],"n":2}`), &r))
+	temperature := 0.9
+	gen := config.Generation{Temperature: &temperature}
+	r.applyGeneration(&gen)
+	assert.Equal(t, 0.2, *gen.Temperature)
+	assert.Equal(t, 20, *gen.MaxTokens)
+	assert.Equal(t, []string{"a", "b"}, gen.Stop)
+	assert.Equal(t, 2, *gen.CandidateCount)
+}
+
+func TestTruncateString(t *testing.T) {
+	assert.Equal(t, "ab", truncateString("abc", 2))
+	assert.Equal(t, "a", truncateString("aあ", 3))
+	assert.Equal(t, "aあ", truncateString("aあ", 4))
+}
@@ -0,0 +1,191 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/magifd2/llm-cli/internal/llm"
+)
+
+const (
+	dirName       = "sessions" // Name of the sessions directory within the config directory.
+	fileExtension = ".jsonl"   // Extension of session transcript files.
+)
+
+// validName restricts session names so that they cannot escape the sessions directory.
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Entry is a single turn recorded in a session transcript.
+type Entry struct {
+	Role    string     `json:"role"`              // The role of the message sender ("user" or "assistant").
+	Content string     `json:"content"`           // The content of the message.
+	Parts   []llm.Part `json:"parts,omitempty"`   // The images and documents sent with a user message, resent with the history.
+	Profile string     `json:"profile,omitempty"` // The profile that was used for the turn.
+	Time    time.Time  `json:"time"`              // When the turn was recorded.
+}
+
+// Info summarizes a stored session.
+type Info struct {
+	Name      string    // The session name.
+	Turns     int       // The number of entries in the transcript.
+	UpdatedAt time.Time // The last modification time of the transcript.
+}
+
+// Store manages named conversations on disk so that a conversation can be continued
+// across separate llm-cli invocations. Each session is a JSONL transcript, one entry per line.
+type Store struct {
+	Dir string // The directory that holds the session files.
+}
+
+// NewStore returns a Store that keeps sessions in the "sessions" directory under configDir.
+func NewStore(configDir string) *Store {
+	return &Store{Dir: filepath.Join(configDir, dirName)}
+}
+
+// path returns the transcript path for a session after validating its name.
+func (s *Store) path(name string) (string, error) {
+	if !validName.MatchString(name) {
+		return "", fmt.Errorf("invalid session name '%s': use letters, digits, '.', '_' or '-'", name)
+	}
+	return filepath.Join(s.Dir, name+fileExtension), nil
+}
+
+// Exists reports whether a session with the given name has been stored.
+func (s *Store) Exists(name string) (bool, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Load reads all entries of a session. A session that does not exist yet has no entries.
+func (s *Store) Load(name string) ([]Entry, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error opening session '%s': %w", name, err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("error decoding session '%s' at line %d: %w", name, lineNo, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading session '%s': %w", name, err)
+	}
+	return entries, nil
+}
+
+// Append adds entries to the end of a session, creating it if necessary.
+func (s *Store) Append(name string, entries ...Entry) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return fmt.Errorf("error creating sessions directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening session '%s': %w", name, err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("error writing session '%s': %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Remove deletes a session.
+func (s *Store) Remove(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("session '%s' not found", name)
+		}
+		return fmt.Errorf("error removing session '%s': %w", name, err)
+	}
+	return nil
+}
+
+// List returns all stored sessions sorted by name.
+func (s *Store) List() ([]Info, error) {
+	dirEntries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading sessions directory: %w", err)
+	}
+
+	var infos []Info
+	for _, de := range dirEntries {
+		if de.IsDir() || !strings.HasSuffix(de.Name(), fileExtension) {
+			continue
+		}
+		name := strings.TrimSuffix(de.Name(), fileExtension)
+		if !validName.MatchString(name) {
+			continue
+		}
+		fi, err := de.Info()
+		if err != nil {
+			return nil, fmt.Errorf("error reading session '%s': %w", name, err)
+		}
+		entries, err := s.Load(name)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, Info{Name: name, Turns: len(entries), UpdatedAt: fi.ModTime()})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+// Messages converts session entries into chat messages that can be resent to a provider.
+func Messages(entries []Entry) []llm.Message {
+	messages := make([]llm.Message, 0, len(entries))
+	for _, e := range entries {
+		messages = append(messages, llm.Message{Role: e.Role, Content: e.Content, Parts: e.Parts})
+	}
+	return messages
+}
@@ -0,0 +1,75 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_AppendLoadListRemove(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	// A session that does not exist yet has no entries.
+	entries, err := store.Load("review")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, store.Append("review",
+		Entry{Role: llm.RoleUser, Content: "q1", Profile: "default", Time: now},
+		Entry{Role: llm.RoleAssistant, Content: "a1", Profile: "default", Time: now},
+	))
+	require.NoError(t, store.Append("review",
+		Entry{Role: llm.RoleUser, Content: "q2", Profile: "default", Time: now},
+		Entry{Role: llm.RoleAssistant, Content: "a2", Profile: "default", Time: now},
+	))
+
+	entries, err = store.Load("review")
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	assert.Equal(t, "q2", entries[2].Content)
+	assert.Equal(t, now, entries[0].Time.UTC())
+
+	assert.Equal(t, []llm.Message{
+		{Role: llm.RoleUser, Content: "q1"},
+		{Role: llm.RoleAssistant, Content: "a1"},
+		{Role: llm.RoleUser, Content: "q2"},
+		{Role: llm.RoleAssistant, Content: "a2"},
+	}, Messages(entries))
+
+	// Session files must not be readable by other users.
+	fi, err := os.Stat(filepath.Join(store.Dir, "review.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	require.NoError(t, store.Append("another", Entry{Role: llm.RoleUser, Content: "x", Time: now}))
+	infos, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	assert.Equal(t, "another", infos[0].Name)
+	assert.Equal(t, "review", infos[1].Name)
+	assert.Equal(t, 4, infos[1].Turns)
+
+	require.NoError(t, store.Remove("review"))
+	exists, err := store.Exists("review")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	assert.Error(t, store.Remove("review"))
+}
+
+func TestStore_InvalidNames(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	for _, name := range []string{"", "../escape", "a/b", ".hidden", "with space"} {
+		t.Run(name, func(t *testing.T) {
+			_, err := store.Load(name)
+			assert.Error(t, err)
+			assert.Error(t, store.Append(name, Entry{Role: llm.RoleUser, Content: "x"}))
+		})
+	}
+}
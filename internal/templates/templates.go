@@ -0,0 +1,214 @@
+package templates
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	dirName       = "templates" // Name of the templates directory within the config directory.
+	fileExtension = ".yaml"     // Extension of template files.
+)
+
+// InputVar is the variable that holds the user prompt given on the command line or on stdin.
+const InputVar = "input"
+
+// validName restricts template names so that they cannot escape the templates directory.
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Template is a reusable prompt. The system and user parts are Go text/template sources
+// that are rendered with the template's default variables and the variables given by the caller.
+type Template struct {
+	Name        string            `yaml:"-"`           // The template name, taken from the file name.
+	Description string            `yaml:"description"` // What the template is for.
+	Profile     string            `yaml:"profile"`     // The profile recommended for the template, used if none is selected.
+	System      string            `yaml:"system"`      // The system prompt.
+	User        string            `yaml:"user"`        // The user prompt. If empty, the input is sent as the user prompt.
+	Vars        map[string]string `yaml:"vars"`        // Default values of the variables.
+}
+
+// Parse parses and checks the contents of a template file.
+func Parse(name string, data []byte) (*Template, error) {
+	var t Template
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("error parsing template '%s': %w", name, err)
+	}
+	t.Name = name
+	if t.System == "" && t.User == "" {
+		return nil, fmt.Errorf("template '%s' has neither a system nor a user part", name)
+	}
+	for part, source := range map[string]string{"system": t.System, "user": t.User} {
+		if _, err := newTextTemplate(part, source); err != nil {
+			return nil, fmt.Errorf("error parsing the %s part of template '%s': %w", part, name, err)
+		}
+	}
+	return &t, nil
+}
+
+// Render renders the system and user parts. The variables override the defaults of the template;
+// a variable that is used but not defined is an error. If the template has no user part, the input is returned instead.
+func (t *Template) Render(vars map[string]string) (system, user string, err error) {
+	data := make(map[string]string, len(t.Vars)+len(vars))
+	for name, value := range t.Vars {
+		data[name] = value
+	}
+	for name, value := range vars {
+		data[name] = value
+	}
+	if system, err = render("system", t.System, data); err != nil {
+		return "", "", fmt.Errorf("error rendering the system part of template '%s': %w", t.Name, err)
+	}
+	if t.User == "" {
+		return system, data[InputVar], nil
+	}
+	if user, err = render("user", t.User, data); err != nil {
+		return "", "", fmt.Errorf("error rendering the user part of template '%s': %w", t.Name, err)
+	}
+	return system, user, nil
+}
+
+// newTextTemplate parses a template part. Using an undefined variable is an error rather than "<no value>".
+func newTextTemplate(name, source string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(source)
+}
+
+// render executes a template part with the variables.
+func render(name, source string, data map[string]string) (string, error) {
+	if source == "" {
+		return "", nil
+	}
+	tmpl, err := newTextTemplate(name, source)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// Store manages the template library on disk. Each template is a YAML file named after the template.
+type Store struct {
+	Dir string // The directory that holds the template files.
+}
+
+// NewStore returns a Store that keeps templates in the "templates" directory under configDir.
+func NewStore(configDir string) *Store {
+	return &Store{Dir: filepath.Join(configDir, dirName)}
+}
+
+// path returns the file path of a template after validating its name.
+func (s *Store) path(name string) (string, error) {
+	if !validName.MatchString(name) {
+		return "", fmt.Errorf("invalid template name '%s': use letters, digits, '.', '_' or '-'", name)
+	}
+	return filepath.Join(s.Dir, name+fileExtension), nil
+}
+
+// ReadFile returns the contents of a template file.
+func (s *Store) ReadFile(name string) ([]byte, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("template '%s' not found", name)
+		}
+		return nil, fmt.Errorf("error reading template '%s': %w", name, err)
+	}
+	return data, nil
+}
+
+// Load reads and parses a template.
+func (s *Store) Load(name string) (*Template, error) {
+	data, err := s.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(name, data)
+}
+
+// Save checks the contents of a template file and writes it to the library.
+// An existing template is only replaced if overwrite is set.
+func (s *Store) Save(name string, data []byte, overwrite bool) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if _, err := Parse(name, data); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return fmt.Errorf("error creating templates directory: %w", err)
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+	file, err := os.OpenFile(path, flags, 0600)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("template '%s' already exists", name)
+		}
+		return fmt.Errorf("error writing template '%s': %w", name, err)
+	}
+	defer file.Close()
+	if _, err := file.Write(data); err != nil {
+		return fmt.Errorf("error writing template '%s': %w", name, err)
+	}
+	return nil
+}
+
+// Remove deletes a template.
+func (s *Store) Remove(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("template '%s' not found", name)
+		}
+		return fmt.Errorf("error removing template '%s': %w", name, err)
+	}
+	return nil
+}
+
+// List returns all templates of the library sorted by name.
+func (s *Store) List() ([]*Template, error) {
+	dirEntries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading templates directory: %w", err)
+	}
+
+	var list []*Template
+	for _, de := range dirEntries {
+		if de.IsDir() || !strings.HasSuffix(de.Name(), fileExtension) {
+			continue
+		}
+		name := strings.TrimSuffix(de.Name(), fileExtension)
+		if !validName.MatchString(name) {
+			continue
+		}
+		t, err := s.Load(name)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
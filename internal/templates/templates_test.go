@@ -0,0 +1,86 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reviewTemplate = `
+description: Review a diff
+profile: reviewer
+system: You review {{.lang}} code.
+user: |-
+  Focus on {{.focus}}.
+  {{.diff}}
+vars:
+  lang: go
+  focus: correctness
+`
+
+func TestTemplate_Render(t *testing.T) {
+	tmpl, err := Parse("review", []byte(reviewTemplate))
+	require.NoError(t, err)
+	assert.Equal(t, "reviewer", tmpl.Profile)
+
+	system, user, err := tmpl.Render(map[string]string{"lang": "rust", "diff": "+fn main() {}"})
+	require.NoError(t, err)
+	assert.Equal(t, "You review rust code.", system, "variables override the defaults")
+	assert.Equal(t, "Focus on correctness.\n+fn main() {}", user)
+
+	_, _, err = tmpl.Render(nil)
+	assert.ErrorContains(t, err, `map has no entry for key "diff"`, "undefined variables are an error")
+}
+
+func TestTemplate_RenderInput(t *testing.T) {
+	tmpl, err := Parse("terse", []byte("system: Answer in one sentence."))
+	require.NoError(t, err)
+
+	system, user, err := tmpl.Render(map[string]string{InputVar: "What is Go?"})
+	require.NoError(t, err)
+	assert.Equal(t, "Answer in one sentence.", system)
+	assert.Equal(t, "What is Go?", user, "without a user part, the input is the user prompt")
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"empty":        "description: nothing",
+		"syntax error": "user: '{{.diff'",
+		"invalid yaml": "user: [",
+	} {
+		_, err := Parse("bad", []byte(data))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestStore(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	list, err := store.List()
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	require.NoError(t, store.Save("review", []byte(reviewTemplate), false))
+	assert.ErrorContains(t, store.Save("review", []byte(reviewTemplate), false), "already exists")
+	require.NoError(t, store.Save("review", []byte("user: '{{.input}}'"), true))
+	assert.Error(t, store.Save("broken", []byte("user: '{{'"), false), "invalid templates are not saved")
+	assert.Error(t, store.Save("../escape", []byte(reviewTemplate), false))
+
+	tmpl, err := store.Load("review")
+	require.NoError(t, err)
+	assert.Equal(t, "{{.input}}", tmpl.User)
+
+	require.NoError(t, os.WriteFile(filepath.Join(store.Dir, "notes.txt"), []byte("ignored"), 0600))
+	list, err = store.List()
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "review", list[0].Name)
+
+	require.NoError(t, store.Remove("review"))
+	_, err = store.Load("review")
+	assert.ErrorContains(t, err, "template 'review' not found")
+	assert.ErrorContains(t, store.Remove("review"), "not found")
+}
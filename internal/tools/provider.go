@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/magifd2/llm-cli/internal/llm"
+)
+
+// Provider wraps another provider and runs the tools the model asks for, feeding their output
+// back to the model until it produces a final answer.
+type Provider struct {
+	llm.Provider           // The provider that generates the replies.
+	Set          *Set      // The tools the model may call.
+	MaxRounds    int       // The maximum number of tool-calling rounds before giving up.
+	Log          io.Writer // Receives a line for every tool call, if set.
+}
+
+// NewProvider returns a provider that lets the model of p call the tools in set.
+func NewProvider(p llm.Provider, set *Set, maxRounds int, log io.Writer) llm.Provider {
+	return &Provider{Provider: p, Set: set, MaxRounds: maxRounds, Log: log}
+}
+
+// Chat runs the conversation until the model answers without calling a tool.
+// The token usage of all rounds is added up.
+func (p *Provider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	return p.run(ctx, req, func(req llm.Request) (*llm.Response, string, error) {
+		resp, err := p.Provider.Chat(ctx, req)
+		if err != nil {
+			return nil, "", err
+		}
+		return resp, resp.Text, nil
+	})
+}
+
+// ChatStream runs the conversation like Chat, streaming the text of every round to the channel.
+// The text is also collected, so that the history of a tool-calling round keeps what the model said before its calls.
+func (p *Provider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	return p.run(ctx, req, func(req llm.Request) (*llm.Response, string, error) {
+		tee := make(chan string)
+		done := make(chan struct{})
+		var text strings.Builder
+		go func() {
+			defer close(done)
+			for token := range tee {
+				text.WriteString(token)
+				responseChan <- token
+			}
+		}()
+		resp, err := p.Provider.ChatStream(ctx, req, tee)
+		close(tee)
+		<-done
+		return resp, text.String(), err
+	})
+}
+
+// run sends the request and executes the requested tools until the model gives a final answer.
+// send returns the response of a round together with its text, which is empty in streamed responses.
+// A failing tool does not end the conversation: the error is reported to the model as the tool's output.
+func (p *Provider) run(ctx context.Context, req llm.Request, send func(llm.Request) (*llm.Response, string, error)) (*llm.Response, error) {
+	tools, err := p.Set.Tools()
+	if err != nil {
+		return nil, err
+	}
+	req.Tools = tools
+	messages := append([]llm.Message(nil), req.Messages...)
+
+	var usage *llm.Usage
+	for round := 0; ; round++ {
+		req.Messages = messages
+		resp, text, err := send(req)
+		if err != nil {
+			return nil, err
+		}
+		usage = usage.Add(resp.Usage)
+		if len(resp.ToolCalls) == 0 {
+			resp.Usage = usage
+			return resp, nil
+		}
+		if round >= p.MaxRounds {
+			return nil, fmt.Errorf("the model was still calling tools after %d rounds", p.MaxRounds)
+		}
+
+		messages = append(messages, llm.Message{Role: llm.RoleAssistant, Content: text, ToolCalls: resp.ToolCalls})
+		for _, call := range resp.ToolCalls {
+			if p.Log != nil {
+				fmt.Fprintf(p.Log, "Calling tool %s %s\n", call.Name, call.Arguments)
+			}
+			output, err := p.Set.Run(ctx, call)
+			if err != nil {
+				if p.Log != nil {
+					fmt.Fprintf(p.Log, "Warning: %v\n", err)
+				}
+				output = fmt.Sprintf("Error: %v", err)
+			}
+			messages = append(messages, llm.Message{Role: llm.RoleTool, ToolCallID: call.ID, Name: call.Name, Content: output})
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/magifd2/llm-cli/internal/llm"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultTimeout limits how long a tool command may run if the tools file does not set a timeout.
+const defaultTimeout = 60 * time.Second
+
+// validName matches the tool names accepted by all supported providers.
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Definition describes a tool in the tools file and the local command that implements it.
+type Definition struct {
+	Name        string         `yaml:"name"`        // The function name exposed to the model.
+	Description string         `yaml:"description"` // What the tool does.
+	Parameters  map[string]any `yaml:"parameters"`  // A JSON schema of the arguments object, written in YAML.
+	Command     []string       `yaml:"command"`     // The program and its arguments. Relative paths are resolved against the tools file.
+	Timeout     time.Duration  `yaml:"timeout"`     // How long the command may run (e.g., "30s").
+}
+
+// file is the top-level structure of a tools file.
+type file struct {
+	Tools []Definition `yaml:"tools"`
+}
+
+// Set is a collection of tools loaded from a tools file.
+type Set struct {
+	definitions []Definition
+	byName      map[string]Definition
+}
+
+// Load reads a tools file in YAML format.
+func Load(path string) (*Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading tools file: %w", err)
+	}
+	return Parse(data, filepath.Dir(path))
+}
+
+// Parse parses the contents of a tools file. Relative command paths are resolved against baseDir.
+func Parse(data []byte, baseDir string) (*Set, error) {
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("error parsing tools file: %w", err)
+	}
+	if len(f.Tools) == 0 {
+		return nil, fmt.Errorf("tools file does not define any tools")
+	}
+
+	set := &Set{byName: make(map[string]Definition)}
+	for i, def := range f.Tools {
+		if !validName.MatchString(def.Name) {
+			return nil, fmt.Errorf("tool %d has an invalid name '%s': use up to 64 letters, digits, '_' or '-'", i+1, def.Name)
+		}
+		if _, ok := set.byName[def.Name]; ok {
+			return nil, fmt.Errorf("tool '%s' is defined more than once", def.Name)
+		}
+		if len(def.Command) == 0 {
+			return nil, fmt.Errorf("tool '%s' has no command", def.Name)
+		}
+		if program := def.Command[0]; !filepath.IsAbs(program) && strings.ContainsRune(program, filepath.Separator) {
+			def.Command = append([]string{filepath.Join(baseDir, program)}, def.Command[1:]...)
+		}
+		if def.Timeout <= 0 {
+			def.Timeout = defaultTimeout
+		}
+		set.definitions = append(set.definitions, def)
+		set.byName[def.Name] = def
+	}
+	return set, nil
+}
+
+// Tools returns the declarations of the tools, in the order of the tools file.
+func (s *Set) Tools() ([]llm.Tool, error) {
+	var tools []llm.Tool
+	for _, def := range s.definitions {
+		tool := llm.Tool{Name: def.Name, Description: def.Description}
+		params := def.Parameters
+		if params == nil {
+			params = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding parameters of tool '%s': %w", def.Name, err)
+		}
+		tool.Parameters = data
+		tools = append(tools, tool)
+	}
+	return tools, nil
+}
+
+// Run executes the command of the requested tool and returns its standard output.
+// The arguments are passed as JSON on standard input and in the LLM_CLI_TOOL_ARGUMENTS environment variable;
+// LLM_CLI_TOOL_NAME holds the tool name.
+func (s *Set) Run(ctx context.Context, call llm.ToolCall) (string, error) {
+	def, ok := s.byName[call.Name]
+	if !ok {
+		return "", fmt.Errorf("unknown tool '%s'", call.Name)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, def.Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, def.Command[0], def.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(call.Arguments)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), "LLM_CLI_TOOL_NAME="+call.Name, "LLM_CLI_TOOL_ARGUMENTS="+string(call.Arguments))
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("tool '%s' timed out after %s", call.Name, def.Timeout)
+		}
+		return "", fmt.Errorf("tool '%s' failed: %w: %s", call.Name, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,167 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/magifd2/llm-cli/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTools = `
+tools:
+  - name: echo_args
+    description: Echo the arguments
+    parameters:
+      type: object
+      properties:
+        city: {type: string}
+      required: [city]
+    command: ["sh", "-c", "cat; printf ' %s' \"$LLM_CLI_TOOL_NAME\""]
+  - name: fail
+    command: ["sh", "-c", "echo boom >&2; exit 3"]
+    timeout: 5s
+  - name: local_script
+    command: ["./bin/tool.sh", "--flag"]
+`
+
+func TestParse(t *testing.T) {
+	set, err := Parse([]byte(testTools), "/opt/tools")
+	require.NoError(t, err)
+
+	tools, err := set.Tools()
+	require.NoError(t, err)
+	require.Len(t, tools, 3)
+	assert.Equal(t, "echo_args", tools[0].Name)
+	assert.JSONEq(t, `{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}`, string(tools[0].Parameters))
+	assert.JSONEq(t, `{"type":"object","properties":{}}`, string(tools[1].Parameters))
+
+	assert.Equal(t, 5*time.Second, set.byName["fail"].Timeout)
+	assert.Equal(t, defaultTimeout, set.byName["echo_args"].Timeout)
+	assert.Equal(t, []string{filepath.Join("/opt/tools", "bin/tool.sh"), "--flag"}, set.byName["local_script"].Command)
+	assert.Equal(t, "sh", set.byName["fail"].Command[0])
+
+	for name, data := range map[string]string{
+		"no tools":     "tools: []",
+		"invalid name": "tools: [{name: 'bad name', command: [true]}]",
+		"duplicate":    "tools: [{name: a, command: [true]}, {name: a, command: [true]}]",
+		"no command":   "tools: [{name: a}]",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(data), ".")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSet_Run(t *testing.T) {
+	set, err := Parse([]byte(testTools), ".")
+	require.NoError(t, err)
+
+	output, err := set.Run(context.Background(), llm.ToolCall{Name: "echo_args", Arguments: json.RawMessage(`{"city":"Tokyo"}`)})
+	require.NoError(t, err)
+	assert.Equal(t, `{"city":"Tokyo"} echo_args`, output)
+
+	_, err = set.Run(context.Background(), llm.ToolCall{Name: "fail", Arguments: json.RawMessage(`{}`)})
+	assert.ErrorContains(t, err, "boom")
+
+	_, err = set.Run(context.Background(), llm.ToolCall{Name: "missing"})
+	assert.ErrorContains(t, err, "unknown tool")
+}
+
+// scriptedProvider returns the given responses in order and records the requests it received.
+type scriptedProvider struct {
+	responses []*llm.Response
+	requests  []llm.Request
+}
+
+func (p *scriptedProvider) Chat(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	p.requests = append(p.requests, req)
+	return p.responses[len(p.requests)-1], nil
+}
+
+// ChatStream sends the text of the response to the channel and returns the response without it.
+func (p *scriptedProvider) ChatStream(ctx context.Context, req llm.Request, responseChan chan<- string) (*llm.Response, error) {
+	resp, err := p.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Text != "" {
+		responseChan <- resp.Text
+	}
+	streamed := *resp
+	streamed.Text = ""
+	return &streamed, nil
+}
+
+func TestProvider_RunsToolsUntilFinalAnswer(t *testing.T) {
+	set, err := Parse([]byte(testTools), ".")
+	require.NoError(t, err)
+
+	inner := &scriptedProvider{responses: []*llm.Response{
+		{ToolCalls: []llm.ToolCall{
+			{ID: "call_1", Name: "echo_args", Arguments: json.RawMessage(`{"city":"Oslo"}`)},
+			{ID: "call_2", Name: "fail", Arguments: json.RawMessage(`{}`)},
+		}, Usage: &llm.Usage{InputTokens: 10, OutputTokens: 4}},
+		{Text: "It is sunny in Oslo.", Usage: &llm.Usage{InputTokens: 30, OutputTokens: 6}},
+	}}
+
+	var log bytes.Buffer
+	resp, err := NewProvider(inner, set, 3, &log).Chat(context.Background(), llm.NewRequest("", "weather?"))
+	require.NoError(t, err)
+	assert.Equal(t, "It is sunny in Oslo.", resp.Text)
+	assert.Equal(t, &llm.Usage{InputTokens: 40, OutputTokens: 10}, resp.Usage)
+	assert.Contains(t, log.String(), "Calling tool echo_args")
+
+	require.Len(t, inner.requests, 2)
+	assert.Len(t, inner.requests[0].Tools, 3)
+	followUp := inner.requests[1].Messages
+	require.Len(t, followUp, 4)
+	assert.Equal(t, llm.RoleAssistant, followUp[1].Role)
+	assert.Equal(t, llm.Message{Role: llm.RoleTool, ToolCallID: "call_1", Name: "echo_args", Content: `{"city":"Oslo"} echo_args`}, followUp[2])
+	// A failing tool is reported to the model instead of ending the conversation.
+	assert.Contains(t, followUp[3].Content, "Error: tool 'fail' failed")
+}
+
+func TestProvider_ChatStream(t *testing.T) {
+	set, err := Parse([]byte(testTools), ".")
+	require.NoError(t, err)
+
+	inner := &scriptedProvider{responses: []*llm.Response{
+		{Text: "Let me check. ", ToolCalls: []llm.ToolCall{{ID: "call_1", Name: "echo_args", Arguments: json.RawMessage(`{"city":"Oslo"}`)}}},
+		{Text: "It is sunny in Oslo.", FinishReason: "stop"},
+	}}
+
+	responseChan := make(chan string, 10)
+	resp, err := NewProvider(inner, set, 3, nil).ChatStream(context.Background(), llm.NewRequest("", "weather?"), responseChan)
+	require.NoError(t, err)
+	close(responseChan)
+	var streamed string
+	for token := range responseChan {
+		streamed += token
+	}
+	assert.Equal(t, "Let me check. It is sunny in Oslo.", streamed)
+	assert.Empty(t, resp.Text)
+	assert.Equal(t, "stop", resp.FinishReason)
+
+	// The text streamed before the tool call is kept in the history, as in non-streamed runs.
+	require.Len(t, inner.requests, 2)
+	followUp := inner.requests[1].Messages
+	require.Len(t, followUp, 3)
+	assert.Equal(t, llm.Message{Role: llm.RoleAssistant, Content: "Let me check. ", ToolCalls: inner.responses[0].ToolCalls}, followUp[1])
+}
+
+func TestProvider_MaxRounds(t *testing.T) {
+	set, err := Parse([]byte(testTools), ".")
+	require.NoError(t, err)
+
+	call := &llm.Response{ToolCalls: []llm.ToolCall{{Name: "echo_args", Arguments: json.RawMessage(`{}`)}}}
+	inner := &scriptedProvider{responses: []*llm.Response{call, call, call}}
+	_, err = NewProvider(inner, set, 1, nil).Chat(context.Background(), llm.NewRequest("", "loop"))
+	assert.ErrorContains(t, err, "still calling tools after 1 rounds")
+}